@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bgzzz/kitchen/pkg/config"
+	ordrs "github.com/bgzzz/kitchen/pkg/orders"
+	"github.com/bgzzz/kitchen/pkg/rack"
+	"github.com/bgzzz/kitchen/pkg/routing"
+	shvs "github.com/bgzzz/kitchen/pkg/shelves"
+	"github.com/bgzzz/kitchen/pkg/source"
+	"github.com/bgzzz/kitchen/pkg/stats"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+)
+
+// kitchen is the kitchen of the multi-kitchen simulation
+type kitchen struct {
+	cfg     config.KitchenConfig
+	shelves []*shvs.Shelf
+	stats   *stats.Stats
+	sr      *rack.ShelfRack
+	// routed is the number of orders routed to the kitchen
+	routed int
+}
+
+// newKitchens returns kitchens of the config, shelves are in the
+// order of the kitchens
+func newKitchens(cfg *config.SimulationConfig,
+	shelves [][]*shvs.Shelf) []*kitchen {
+	kitchens := []*kitchen{}
+	for i, kc := range cfg.Kitchens {
+		kitchens = append(kitchens, &kitchen{
+			cfg:     kc,
+			shelves: shelves[i],
+		})
+	}
+	return kitchens
+}
+
+// loadKitchenShelves returns shelves of every kitchen of the config
+func loadKitchenShelves(cfg *config.SimulationConfig) ([][]*shvs.Shelf, error) {
+	shelves := [][]*shvs.Shelf{}
+	for _, kc := range cfg.Kitchens {
+		kitchenShelves, err := config.LoadKitchenShelves(cfg, kc)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("kitchen %s", kc.Name))
+		}
+		shelves = append(shelves, kitchenShelves)
+	}
+	return shelves, nil
+}
+
+// kitchenShelves returns shelves of every kitchen
+func kitchenShelves(kitchens []*kitchen) [][]*shvs.Shelf {
+	shelves := [][]*shvs.Shelf{}
+	for _, k := range kitchens {
+		shelves = append(shelves, k.shelves)
+	}
+	return shelves
+}
+
+// checkKitchenFlags returns error in case flags supported only by
+// the single kitchen simulation are set
+func checkKitchenFlags(c *cli.Context) error {
+	set := []string{}
+	for _, flag := range []string{flagTUI, flagWatch} {
+		if c.Bool(flag) {
+			set = append(set, "--"+flag)
+		}
+	}
+	for _, flag := range []string{flagReport, flagJSON, flagGRPC} {
+		if c.String(flag) != "" {
+			set = append(set, "--"+flag)
+		}
+	}
+
+	if len(set) != 0 {
+		return errors.New(fmt.Sprintf("%s can't be used with kitchens",
+			strings.Join(set, ", ")))
+	}
+	return nil
+}
+
+// load returns the share of the kitchen shelf capacity taken by the
+// orders, kitchen without capacity is fully loaded
+func (k *kitchen) load() float64 {
+	capacity, taken := 0, 0
+	for _, shelf := range k.sr.Snapshot().Shelves {
+		capacity += shelf.Capacity
+		taken += len(shelf.Orders)
+	}
+	if capacity <= 0 {
+		return 1
+	}
+	return float64(taken) / float64(capacity)
+}
+
+// runKitchens simulates the kitchens processing orders of the
+// source, every order is put on the rack of the kitchen chosen by
+// the routing policy. Simulation is over once every rack processed
+// its orders
+func (sim *simulation) runKitchens(src source.OrderSource) error {
+	cfg, _ := sim.live.get()
+
+	// number of orders of every rack is known only when the source
+	// is over
+	done := make(chan bool, len(sim.kitchens))
+	racks := []*rack.ShelfRack{}
+	routes := []routing.Kitchen{}
+	for _, k := range sim.kitchens {
+		k.stats = stats.NewStats(rack.UnknownOrders)
+		k.stats.Economics = stats.Economics{
+			ScaleRevenueByValue: cfg.Economics.ScaleRevenueByValue,
+			RefundThreshold:     cfg.Economics.RefundThreshold,
+		}
+		k.sr = rack.NewShelfRack(sim.log.WithField("kitchen", k.cfg.Name),
+			k.stats, k.shelves, rack.UnknownOrders, func() {
+				done <- true
+			})
+		k.sr.SetKitchen(k.cfg.Name)
+		if cfg.RackConfig.Strategy == config.StrategyOptimizer {
+			k.sr.UseOptimizer(cfg.RackConfig.OptimizerBudget)
+		}
+
+		racks = append(racks, k.sr)
+		routes = append(routes, routing.Kitchen{
+			Name:     k.cfg.Name,
+			Location: k.cfg.Location,
+			Load:     k.load,
+			Validate: config.OrderValidator(k.shelves),
+		})
+	}
+
+	policy := cfg.Routing.Policy
+	if policy == "" {
+		policy = routing.PolicyRoundRobin
+	}
+	router, err := routing.New(policy, routes)
+	if err != nil {
+		return err
+	}
+
+	out, err := sim.openOutputs(cfg, racks...)
+	if err != nil {
+		return err
+	}
+	stops := []func(){}
+	for _, sr := range racks {
+		sr.Init()
+		if cfg.RackConfig.RebalanceIntervalSeconds > 0 {
+			stops = append(stops, sr.StartRebalancing(time.Duration(
+				cfg.RackConfig.RebalanceIntervalSeconds*float64(time.Second))))
+		}
+	}
+
+	sim.start = time.Now()
+	go sim.produce(src, func(opts *ordrs.OrderOptions) (*rack.ShelfRack,
+		config.OrdersConfig, error) {
+		i, err := router.Route(opts)
+		if err != nil {
+			return nil, config.OrdersConfig{}, err
+		}
+		k := sim.kitchens[i]
+		k.routed++
+
+		cfg, _ := sim.live.get()
+		return k.sr, k.cfg.OrdersConfig(cfg.OrdersConfig), nil
+	}, racks, true)
+
+	for range sim.kitchens {
+		<-done
+	}
+	for _, stop := range stops {
+		stop()
+	}
+
+	// queued events are posted and written before exit
+	out.close()
+
+	// stats of every kitchen are logged by its rack
+	sim.log.Info(kitchensSummary(sim.kitchens))
+	return nil
+}
+
+// kitchensSummary returns the outcomes of every kitchen side by side
+// followed by the stats of all kitchens together
+func kitchensSummary(kitchens []*kitchen) string {
+	all := []*stats.Stats{}
+	output := "\n\tKitchens:"
+	for _, k := range kitchens {
+		all = append(all, k.stats)
+		summary := k.stats.Summary()
+		output = fmt.Sprintf("%s\n\t%s: routed %d, delivered %d, wasted %d, "+
+			"spoiled %d, avg value %f, profit $%.2f", output, k.cfg.Name,
+			k.routed, summary.Delivered, summary.Wasted, summary.Spoiled,
+			summary.AvgDelivered, summary.Profit)
+	}
+
+	return fmt.Sprintf("%s\n\tAll kitchens:%s", output,
+		strings.Replace(stats.Merge(all...).String(), "\n\t", "\n\t\t", -1))
+}
@@ -1,77 +1,267 @@
 package main
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
-	"math/rand"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/bgzzz/kitchen/pkg/config"
+	"github.com/bgzzz/kitchen/pkg/menu"
 	ordrs "github.com/bgzzz/kitchen/pkg/orders"
 	"github.com/bgzzz/kitchen/pkg/rack"
+	"github.com/bgzzz/kitchen/pkg/report"
+	"github.com/bgzzz/kitchen/pkg/sampler"
+	"github.com/bgzzz/kitchen/pkg/scenario"
+	"github.com/bgzzz/kitchen/pkg/service"
 	shvs "github.com/bgzzz/kitchen/pkg/shelves"
+	"github.com/bgzzz/kitchen/pkg/sink"
+	"github.com/bgzzz/kitchen/pkg/source"
 	"github.com/bgzzz/kitchen/pkg/stats"
+	"github.com/bgzzz/kitchen/pkg/tui"
+	"github.com/bgzzz/kitchen/pkg/webhook"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
 )
 
 const (
+	defaultConfigPath = "./kitchen.yaml"
+
 	flagConfig = "simulation-config"
 	flagDebug  = "debug"
+	flagWatch  = "watch"
+	flagTUI    = "tui"
+	flagReport = "html-report"
+	flagJSON   = "json-report"
+	flagGRPC   = "grpc-address"
 )
 
 func main() {
 
 	app := cli.App{
 		Action: func(c *cli.Context) error {
-			cfg, err := config.NewSimulationConfig(c.String(flagConfig))
+			cfgPath := configPath(c)
+			cfg, err := loadConfig(c)
 			if err != nil {
 				return err
 			}
 
-			ordOpts, err := config.FetchOrders(cfg.OrdersPath)
+			var shelves []*shvs.Shelf
+			var kitchens [][]*shvs.Shelf
+			if len(cfg.Kitchens) != 0 {
+				if err := checkKitchenFlags(c); err != nil {
+					return err
+				}
+				kitchens, err = loadKitchenShelves(cfg)
+			} else {
+				shelves, err = config.LoadShelves(cfg)
+			}
 			if err != nil {
 				return err
 			}
+			// check returns problems of the setup with the orders
+			check := func(ordOpts []*ordrs.OrderOptions) config.ValidationErrors {
+				if kitchens != nil {
+					return config.ValidateKitchens(cfgPath, cfg, kitchens, ordOpts)
+				}
+				return config.Validate(cfgPath, cfg, shelves, ordOpts)
+			}
 
-			if err := config.ValidateOrderOptions(ordOpts); err != nil {
-				return err
+			logger := logrus.New()
+			debug := c.Bool(flagDebug)
+
+			if debug {
+				logger.SetLevel(logrus.DebugLevel)
+			}
+
+			useTUI := c.Bool(flagTUI)
+			if useTUI {
+				// keys are read from stdin
+				if cfg.StreamOrders() && cfg.OrdersPath == config.StdinPath {
+					return errors.New("terminal UI can't be used with orders read from stdin")
+				}
+				// log output would break the screen
+				logger.SetOutput(ioutil.Discard)
 			}
 
-			shelves, err := config.FetchShelves(cfg.ShelvesFilePath)
+			log := logrus.NewEntry(logger)
+
+			sc, err := config.LoadScenario(cfg)
 			if err != nil {
 				return err
 			}
+			// scenario is not allowed with kitchens by the config check
+			if kitchens == nil {
+				if problems := config.ValidateScenario(cfg, shelves,
+					sc); len(problems) != 0 {
+					return problems
+				}
+			}
 
-			if err := config.ValidateShelves(shelves); err != nil {
-				return err
+			live := newLiveConfig(cfg, shelves)
+			sim := &simulation{
+				log:     log,
+				cfgPath: cfgPath,
+				load: func() (*config.SimulationConfig, error) {
+					return loadConfig(c)
+				},
+				live:       live,
+				watch:      c.Bool(flagWatch),
+				scenario:   sc,
+				tui:        useTUI,
+				htmlReport: c.String(flagReport),
+				jsonReport: c.String(flagJSON),
+			}
+			if kitchens != nil {
+				sim.kitchens = newKitchens(cfg, kitchens)
 			}
 
-			logger := logrus.New()
-			debug := c.Bool(flagDebug)
+			if address := c.String(flagGRPC); address != "" {
+				// submitted orders are checked one by one
+				if problems := check(nil); len(problems) != 0 {
+					return problems
+				}
 
-			if debug {
-				logger.SetLevel(logrus.DebugLevel)
+				return sim.serve(address)
 			}
 
-			log := logrus.NewEntry(logger)
+			if cfg.ConsumeOrders() {
+				// consumed orders are checked one by one
+				if problems := check(nil); len(problems) != 0 {
+					return problems
+				}
 
-			return run(log, cfg, shelves, ordOpts)
+				return sim.consume(cfg)
+			}
+
+			if cfg.MenuPath != "" {
+				items, err := config.FetchMenuWith(cfg.MenuPath, cfg.Fetch)
+				if err != nil {
+					return err
+				}
+
+				var problems config.ValidationErrors
+				if kitchens != nil {
+					problems = config.ValidateKitchensMenu(cfgPath, cfg,
+						kitchens, items)
+				} else {
+					problems = config.ValidateMenu(cfgPath, cfg, shelves, items)
+				}
+				if len(problems) != 0 {
+					return problems
+				}
+
+				return sim.run(menu.NewGenerator(items, &cfg.OrderMix,
+					cfg.OrdersConfig.OrdersPerSecond), cfg.OrderMix.Count)
+			}
+
+			if cfg.StreamOrders() {
+				// streamed orders are checked one by one while read
+				if problems := check(nil); len(problems) != 0 {
+					return problems
+				}
+
+				src, err := source.OpenNDJSON(cfg.OrdersPath, source.StreamOptions{
+					Follow: cfg.OrdersSource.Follow,
+					FollowIdle: time.Duration(cfg.OrdersSource.FollowIdleSeconds *
+						float64(time.Second)),
+					TimestampField: cfg.OrdersSource.TimestampField,
+					Schema:         config.OrdersSchema.Items,
+					Validate:       sim.validateOrder,
+				})
+				if err != nil {
+					return err
+				}
+
+				return sim.run(src, rack.UnknownOrders)
+			}
+
+			ordOpts, err := config.LoadOrders(cfg)
+			if err != nil {
+				return err
+			}
+
+			if problems := check(ordOpts); len(problems) != 0 {
+				return problems
+			}
+
+			return sim.run(source.NewListSource(ordOpts), len(ordOpts))
 
 		},
-		Flags: []cli.Flag{
+		Commands: []*cli.Command{
+			{
+				Name:   "validate",
+				Usage:  "Check simulation config, shelves and orders and report all problems",
+				Action: validate,
+			},
+			{
+				Name:      "schema",
+				Usage:     "Print JSON Schema of the simulation files",
+				ArgsUsage: "[simulation-config|shelves|orders|menu|scenario]",
+				Action:    printSchema,
+			},
+			{
+				Name:      "compare",
+				Usage:     "Compare JSON reports of two simulation setups",
+				ArgsUsage: "a.json b.json",
+				Action:    compare,
+			},
+			{
+				Name:  "config",
+				Usage: "Simulation config operations",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "print",
+						Usage:  "Print effective simulation config: defaults overridden by config file, env variables and flags",
+						Action: printConfig,
+					},
+				},
+			},
+		},
+		Flags: append([]cli.Flag{
 			&cli.StringFlag{
 				Name:        flagConfig,
-				Usage:       "Path to file containing simulation config. ex: ./kitchen.yaml",
-				DefaultText: "./kitchen.yaml",
+				Usage:       "Path to file containing simulation config, defaults are used when default file is absent. ex: ./kitchen.yaml",
+				DefaultText: defaultConfigPath,
 				EnvVars:     []string{"KITCHEN_SIMULATION_CONFIG_PATH"},
 			},
+			&cli.BoolFlag{
+				Name:    flagWatch,
+				Usage:   "Reload simulation config and shelves when their files change (SIGHUP always reloads them)",
+				EnvVars: []string{"KITCHEN_SIMULATION_WATCH"},
+			},
+			&cli.BoolFlag{
+				Name:    flagTUI,
+				Usage:   "Show the live rack in the terminal UI instead of the log",
+				EnvVars: []string{"KITCHEN_SIMULATION_TUI"},
+			},
+			&cli.StringFlag{
+				Name:    flagReport,
+				Usage:   "Write self-contained HTML report with charts of the run to the file. ex: ./report.html",
+				EnvVars: []string{"KITCHEN_SIMULATION_HTML_REPORT"},
+			},
+			&cli.StringFlag{
+				Name:    flagJSON,
+				Usage:   "Add summary of the run to the JSON report, runs of several seeds are collected in the same file. ex: ./report.json",
+				EnvVars: []string{"KITCHEN_SIMULATION_JSON_REPORT"},
+			},
+			&cli.StringFlag{
+				Name:    flagGRPC,
+				Usage:   "Serve the kitchen gRPC service on the address, orders are submitted by its clients instead of being read from the orders file or generated from the menu. ex: :50051",
+				EnvVars: []string{"KITCHEN_SIMULATION_GRPC_ADDRESS"},
+			},
 			&cli.BoolFlag{
 				Name:    flagDebug,
 				Usage:   "Debug logging",
 				EnvVars: []string{"KITCHEN_SIMULATION_DEBUG"},
 			},
-		},
+		}, overrideFlags()...),
 	}
 
 	if err := app.Run(os.Args); err != nil {
@@ -80,58 +270,604 @@ func main() {
 
 }
 
-func run(log *logrus.Entry, cfg *config.SimulationConfig,
-	shelves []*shvs.Shelf, ordOpts []*ordrs.OrderOptions) error {
+// overrideFlags returns flags overriding every simulation config
+// setting, flag name is the setting key. On and off settings are
+// bool flags, their env values are parsed as bool
+func overrideFlags() []cli.Flag {
+	flags := []cli.Flag{}
+	for _, field := range config.Fields() {
+		if field.Bool {
+			flags = append(flags, &cli.BoolFlag{
+				Name:    field.Key,
+				Usage:   field.Description,
+				EnvVars: []string{field.Env},
+			})
+			continue
+		}
+		flags = append(flags, &cli.StringFlag{
+			Name:    field.Key,
+			Usage:   field.Description,
+			EnvVars: []string{field.Env},
+		})
+	}
+	return flags
+}
+
+// configPath returns path of the simulation config file
+func configPath(c *cli.Context) string {
+	if path := c.String(flagConfig); path != "" {
+		return path
+	}
+	return defaultConfigPath
+}
+
+// loadConfig returns effective simulation config. Precedence of the
+// settings is flag > env > config file > default. Config file is
+// optional unless its path is set explicitly
+func loadConfig(c *cli.Context) (*config.SimulationConfig, error) {
+	overrides := map[string]string{}
+	for _, field := range config.Fields() {
+		// flag value takes precedence over env one
+		if !isSet(c, field.Key) {
+			continue
+		}
+		if field.Bool {
+			overrides[field.Key] = strconv.FormatBool(c.Bool(field.Key))
+			continue
+		}
+		overrides[field.Key] = c.String(field.Key)
+	}
+
+	return config.LoadSimulationConfig(configPath(c), isSet(c, flagConfig),
+		overrides)
+}
+
+// isSet returns true in case global flag is set by command line or
+// env. Nested commands do not see global flags set by env, so
+// all parent contexts are checked
+func isSet(c *cli.Context, name string) bool {
+	for _, ctx := range c.Lineage() {
+		if ctx.IsSet(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// printConfig prints effective simulation config in yaml
+func printConfig(c *cli.Context) error {
+	cfg, err := loadConfig(c)
+	if err != nil {
+		return err
+	}
+
+	b, err := yaml.Marshal(cfg)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal config")
+	}
+
+	fmt.Print(string(b))
+	return nil
+}
+
+// validate reports all problems of the simulation setup
+// return error in case setup is not valid
+func validate(c *cli.Context) error {
+	cfgPath := configPath(c)
+	cfg, err := loadConfig(c)
+	if err != nil {
+		return err
+	}
+
+	problems := config.ValidationErrors{}
+
+	var shelves []*shvs.Shelf
+	var kitchens [][]*shvs.Shelf
+	var shelvesErr error
+	shelvesFile := cfg.ShelvesFilePath
+	if len(cfg.Kitchens) != 0 {
+		kitchens, shelvesErr = loadKitchenShelves(cfg)
+		shelvesFile = cfgPath
+	} else {
+		shelves, shelvesErr = config.LoadShelves(cfg)
+	}
+	if shelvesErr != nil {
+		problems = append(problems, &config.ValidationError{
+			File:  shelvesFile,
+			Index: -1,
+			Err:   shelvesErr,
+		})
+	}
+
+	sc, scenarioErr := config.LoadScenario(cfg)
+	if scenarioErr != nil {
+		problems = append(problems, &config.ValidationError{
+			File:  cfg.ScenarioPath,
+			Index: -1,
+			Err:   scenarioErr,
+		})
+	}
+	// scenario is not allowed with kitchens by the config check
+	if shelvesErr == nil && scenarioErr == nil && kitchens == nil {
+		problems = append(problems,
+			config.ValidateScenario(cfg, shelves, sc)...)
+	}
+
+	if cfg.MenuPath != "" {
+		items, menuErr := config.FetchMenuWith(cfg.MenuPath, cfg.Fetch)
+		if menuErr != nil {
+			problems = append(problems, &config.ValidationError{
+				File:  cfg.MenuPath,
+				Index: -1,
+				Err:   menuErr,
+			})
+		}
+
+		if shelvesErr == nil && menuErr == nil && kitchens != nil {
+			problems = append(problems,
+				config.ValidateKitchensMenu(cfgPath, cfg, kitchens, items)...)
+		} else if shelvesErr == nil && menuErr == nil {
+			problems = append(problems,
+				config.ValidateMenu(cfgPath, cfg, shelves, items)...)
+		}
+
+		return printProblems(problems)
+	}
+
+	var ordOpts []*ordrs.OrderOptions
+	var ordersErr error
+	// orders from stdin or broker can be checked only while
+	// simulation runs
+	if (!cfg.StreamOrders() || cfg.OrdersPath != config.StdinPath) &&
+		!cfg.ConsumeOrders() {
+		ordOpts, ordersErr = config.LoadOrders(cfg)
+	}
+	if ordersErr != nil {
+		problems = append(problems, &config.ValidationError{
+			File:  cfg.OrdersPath,
+			Index: -1,
+			Err:   ordersErr,
+		})
+	}
+
+	// setup can be checked as a whole only in case all files are read
+	if shelvesErr == nil && ordersErr == nil && kitchens != nil {
+		problems = append(problems,
+			config.ValidateKitchens(cfgPath, cfg, kitchens, ordOpts)...)
+	} else if shelvesErr == nil && ordersErr == nil {
+		problems = append(problems,
+			config.Validate(cfgPath, cfg, shelves, ordOpts)...)
+	}
+
+	return printProblems(problems)
+}
+
+// printProblems prints problems of the simulation setup
+// return error in case there are any
+func printProblems(problems config.ValidationErrors) error {
+
+	if len(problems) == 0 {
+		fmt.Println("simulation setup is valid")
+		return nil
+	}
+
+	fmt.Println(problems.Error())
+	return errors.New(fmt.Sprintf("%d problems found", len(problems)))
+}
+
+// printSchema prints JSON Schema of the requested file kind,
+// schemas of all kinds are printed in case kind is not supplied
+func printSchema(c *cli.Context) error {
+	var v interface{} = config.Schemas()
+	if kind := c.Args().First(); kind != "" {
+		s, ok := config.Schemas()[kind]
+		if !ok {
+			return errors.New(fmt.Sprintf("unknown file kind %s", kind))
+		}
+		v = s
+	}
+
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal schema")
+	}
+
+	fmt.Println(string(b))
+	return nil
+}
+
+// compare prints differences of two JSON reports
+func compare(c *cli.Context) error {
+	if c.Args().Len() != 2 {
+		return errors.New("two JSON reports are expected: a.json b.json")
+	}
+
+	a, err := report.LoadJSONFile(c.Args().Get(0))
+	if err != nil {
+		return errors.Wrap(err, c.Args().Get(0))
+	}
+	b, err := report.LoadJSONFile(c.Args().Get(1))
+	if err != nil {
+		return errors.Wrap(err, c.Args().Get(1))
+	}
+
+	fmt.Print(report.Compare(a, b).String())
+	return nil
+}
+
+// simulation is the running kitchen simulation
+type simulation struct {
+	log     *logrus.Entry
+	cfgPath string
+	// load reads the config with its overrides
+	load func() (*config.SimulationConfig, error)
+	// live is the config that can be reloaded while simulation runs
+	live *liveConfig
+	// watch reloads config when its files change
+	watch bool
+	// scenario is the timed events applied while simulation runs
+	scenario *scenario.Scenario
+	// tui shows the live rack in the terminal UI
+	tui bool
+	// pace holds the producer while the terminal UI is paused, nil
+	// in case UI is not shown
+	pace *tui.UI
+	// htmlReport is the path of the HTML report written when
+	// simulation is over, empty disables it
+	htmlReport string
+	// jsonReport is the path of the JSON report the run summary is
+	// added to when simulation is over, empty disables it
+	jsonReport string
+	// service is the kitchen gRPC service orders are submitted to,
+	// nil in case orders are read from the source
+	service *service.Kitchen
+	// serviceAddress is the address service listens on
+	serviceAddress string
+	// kitchens are simulated instead of the single kitchen in case
+	// they are set
+	kitchens []*kitchen
+	// start is the time simulation started, scenario events and
+	// order arrivals are relative to it
+	start time.Time
+}
+
+// run simulates the kitchen processing orders of the source.
+// Expected is the number of orders of the source or
+// rack.UnknownOrders in case it is not known upfront
+func (sim *simulation) run(src source.OrderSource, expected int) error {
+	if len(sim.kitchens) != 0 {
+		return sim.runKitchens(src)
+	}
+
+	cfg, shelves := sim.live.get()
+
 	done := make(chan bool)
-	st := stats.NewStats(len(ordOpts))
-	sr := rack.NewShelfRack(log, st, shelves, len(ordOpts), func() {
+	st := stats.NewStats(expected)
+	st.Economics = stats.Economics{
+		ScaleRevenueByValue: cfg.Economics.ScaleRevenueByValue,
+		RefundThreshold:     cfg.Economics.RefundThreshold,
+	}
+	sr := rack.NewShelfRack(sim.log, st, shelves, expected, func() {
 		done <- true
 	})
+	if cfg.RackConfig.Strategy == config.StrategyOptimizer {
+		sr.UseOptimizer(cfg.RackConfig.OptimizerBudget)
+	}
+
+	var ui *tui.UI
+	if sim.tui {
+		ui = tui.New(os.Stdin, os.Stdout, sr.Snapshot)
+		sr.Subscribe(ui.Feed)
+	}
+	var recorder *report.Recorder
+	if sim.htmlReport != "" || sim.jsonReport != "" {
+		recorder = report.NewRecorder()
+		sr.Subscribe(recorder.Record)
+	}
+	out, err := sim.openOutputs(cfg, sr)
+	if err != nil {
+		return err
+	}
 	sr.Init()
+	if sim.service != nil {
+		if err := sim.service.Serve(sim.serviceAddress, sr); err != nil {
+			return err
+		}
+		defer sim.service.Stop()
+	}
+	if cfg.RackConfig.RebalanceIntervalSeconds > 0 {
+		stop := sr.StartRebalancing(time.Duration(cfg.RackConfig.RebalanceIntervalSeconds *
+			float64(time.Second)))
+		defer stop()
+	}
 
-	orderTicker := time.NewTicker(1 * time.Second)
+	var smp *sampler.Sampler
+	if cfg.Sampling.Path != "" {
+		var err error
+		if smp, err = sampler.Create(cfg.Sampling.Path, sr.Snapshot); err != nil {
+			return err
+		}
+	}
 
-	go func(ordOpts []*ordrs.OrderOptions, sr *rack.ShelfRack) {
-		for {
-			if len(ordOpts) == 0 {
-				break
-			}
-			<-orderTicker.C
-			for i := 0; i < cfg.OrdersConfig.OrdersPerSecond; i++ {
-				if len(ordOpts) == 0 {
-					break
-				}
+	var quit <-chan struct{}
+	if ui != nil {
+		if err := ui.Start(); err != nil {
+			return err
+		}
+		quit = ui.Quit()
+		sim.pace = ui
+	}
 
-				go func(orderOpts ordrs.OrderOptions) {
-					time.Sleep(time.Duration(rand.Float64()) * time.Second)
+	sim.start = time.Now()
+	if smp != nil {
+		smp.Start(sim.start, time.Duration(cfg.Sampling.IntervalSeconds*
+			float64(time.Second)))
+	}
+	stopWatch := sim.watchConfig(sr)
+	defer stopWatch()
+	go sim.play(sr)
+	go sim.produce(src, func(*ordrs.OrderOptions) (*rack.ShelfRack,
+		config.OrdersConfig, error) {
+		cfg, _ := sim.live.get()
+		return sr, cfg.OrdersConfig, nil
+	}, []*rack.ShelfRack{sr}, expected == rack.UnknownOrders)
 
-					order := ordrs.NewOrder(&orderOpts, &ordrs.Config{
-						CourierReadyMin: cfg.OrdersConfig.DeliveryMinSeconds,
-						CourierReadyMax: cfg.OrdersConfig.DeliveryMaxSeconds,
-					}, func(ord *ordrs.Order) {
-						sr.Interact(&rack.OrderEvent{
-							EventType: rack.OESpoiled,
-							Order:     ord,
-						})
-					}, func(ord *ordrs.Order) {
-						sr.Interact(&rack.OrderEvent{
-							EventType: rack.OEDelivered,
-							Order:     ord,
-						})
-					})
+	interrupted := false
+	select {
+	case <-done:
+		if ui != nil {
+			ui.Close()
+			// stats are logged by the rack otherwise, summary is
+			// taken by the rack event loop, so it is consistent
+			fmt.Printf("simulation is over\n\t%s\n",
+				tui.Summary(sr.Snapshot().Stats))
+		}
+	case <-quit:
+		interrupted = true
+		ui.Close()
+		fmt.Printf("simulation is interrupted\n\t%s\n",
+			tui.Summary(sr.Snapshot().Stats))
+	}
 
-					sr.Interact(&rack.OrderEvent{
-						EventType: rack.OECreated,
-						Order:     order,
-					})
-				}(*ordOpts[0])
+	// queued events are posted and written before exit
+	out.close()
 
-				ordOpts = ordOpts[1:]
+	if smp != nil {
+		if err := smp.Stop(); err != nil {
+			return err
+		}
+		sim.log.Infof("samples are written to %s", cfg.Sampling.Path)
+	}
+
+	if recorder == nil {
+		return nil
+	}
+	run := &report.Run{
+		Start:   sim.start,
+		End:     time.Now(),
+		Shelves: shelves,
+		Events:  recorder.Events(),
+		// rack keeps processing its events, so stats are taken
+		// by the rack event loop
+		Summary: sr.Snapshot().Stats,
+	}
+	if cfg.MenuPath != "" {
+		run.Seed = cfg.OrderMix.Seed
+	}
+	if !interrupted {
+		run.Details = st.String()
+	}
+
+	if sim.htmlReport != "" {
+		if err := report.WriteHTMLFile(sim.htmlReport, run); err != nil {
+			return err
+		}
+		sim.log.Infof("report is written to %s", sim.htmlReport)
+	}
+	if sim.jsonReport != "" {
+		if err := report.AppendJSONFile(sim.jsonReport,
+			report.Summarize(run)); err != nil {
+			return err
+		}
+		sim.log.Infof("run summary is added to %s", sim.jsonReport)
+	}
+	return nil
+}
+
+// validateOrder checks the single order read while simulation runs
+func (sim *simulation) validateOrder(opts *ordrs.OrderOptions) error {
+	if len(sim.kitchens) != 0 {
+		return config.KitchensOrderValidator(kitchenShelves(sim.kitchens))(opts)
+	}
+	// shelves can be changed by config reload
+	_, shelves := sim.live.get()
+	return config.OrderValidator(shelves)(opts)
+}
+
+// outputs are the webhooks and sinks rack events are sent to
+type outputs struct {
+	log          *logrus.Entry
+	closers      []io.Closer
+	unsubscribes []func()
+}
+
+// openOutputs opens webhooks and sinks of the config and subscribes
+// them to the events of the racks
+func (sim *simulation) openOutputs(cfg *config.SimulationConfig,
+	racks ...*rack.ShelfRack) (*outputs, error) {
+	out := &outputs{log: sim.log}
+	subscribe := func(fn func(rack.Event)) {
+		for _, sr := range racks {
+			out.unsubscribes = append(out.unsubscribes, sr.Subscribe(fn))
+		}
+	}
+
+	for _, wh := range cfg.Webhooks {
+		w := webhook.New(sim.log, webhookOptions(wh))
+		subscribe(w.Notify)
+		out.closers = append(out.closers, w)
+	}
+	for _, sc := range cfg.Sinks {
+		p, err := sink.Open(sim.log, sinkOptions(sc))
+		if err != nil {
+			out.close()
+			return nil, err
+		}
+		subscribe(p.Notify)
+		out.closers = append(out.closers, p)
+	}
+	return out, nil
+}
+
+// webhookOptions returns options of the webhook, not set values are
+// replaced by defaults
+func webhookOptions(wc config.WebhookConfig) webhook.Options {
+	wc = wc.WithDefaults()
+	return webhook.Options{
+		URL:     wc.URL,
+		Events:  wc.Events,
+		Secret:  wc.Secret,
+		Timeout: time.Duration(wc.TimeoutSeconds * float64(time.Second)),
+		Retries: *wc.Retries,
+		Backoff: time.Duration(wc.BackoffSeconds * float64(time.Second)),
+	}
+}
+
+// sinkOptions returns options of the sink, not set values are
+// replaced by defaults
+func sinkOptions(sc config.SinkConfig) sink.Options {
+	sc = sc.WithDefaults()
+	return sink.Options{
+		Type:          sc.Type,
+		Events:        sc.Events,
+		Path:          sc.Path,
+		MaxSize:       int64(*sc.MaxSizeMB * 1024 * 1024),
+		MaxFiles:      *sc.MaxFiles,
+		URL:           sc.URL,
+		Subject:       sc.Subject,
+		Timeout:       time.Duration(sc.TimeoutSeconds * float64(time.Second)),
+		Retries:       *sc.Retries,
+		Backoff:       time.Duration(sc.BackoffSeconds * float64(time.Second)),
+		BufferSize:    sc.BufferSize,
+		BatchSize:     sc.BatchSize,
+		FlushInterval: time.Duration(sc.FlushIntervalSeconds * float64(time.Second)),
+		Policy:        sc.Policy,
+	}
+}
+
+// close sends the queued events and closes the outputs. Racks can
+// still produce events (ex: interrupted simulation), outputs ignore
+// them once closed. Outputs are closed before they are unsubscribed,
+// so the stalled sink blocking the rack is released by its drain
+// timeout first
+func (out *outputs) close() {
+	for _, closer := range out.closers {
+		if err := closer.Close(); err != nil {
+			out.log.Warn(err)
+		}
+	}
+	for _, unsubscribe := range out.unsubscribes {
+		unsubscribe()
+	}
+}
+
+// router returns the rack the order is put on and the orders settings
+// of its kitchen
+type router func(opts *ordrs.OrderOptions) (*rack.ShelfRack,
+	config.OrdersConfig, error)
+
+// produce creates orders read from the source. Orders with arrival
+// offset are created at start + offset, the rest are created in
+// batches of orders-per-second every second. Orders settings are
+// taken from the current config, so they can be changed by reload
+// or scenario. Couriers of the orders created during the scenario
+// delay are late. Every order is put on the rack chosen by the
+// route, orders that can't be routed are skipped.
+// In case of streaming racks are notified when source is over
+func (sim *simulation) produce(src source.OrderSource, route router,
+	racks []*rack.ShelfRack, streaming bool) {
+	defer src.Close()
+
+	orderTicker := time.NewTicker(1 * time.Second)
+	defer orderTicker.Stop()
+
+	batch := 0
+	for {
+		ord, err := src.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var recordErr *source.RecordError
+			if errors.As(err, &recordErr) {
+				sim.log.Warnf("order is skipped: %s", err.Error())
+				continue
+			}
+			sim.log.Errorf("unable to read orders: %s", err.Error())
+			break
+		}
+
+		if ord.Timed {
+			time.Sleep(time.Until(sim.start.Add(ord.Offset)))
+		} else {
+			if batch == 0 {
+				<-orderTicker.C
+			}
+			cfg, _ := sim.live.get()
+			batch++
+			if batch >= cfg.OrdersConfig.OrdersPerSecond {
+				batch = 0
 			}
+		}
+		if sim.pace != nil {
+			sim.pace.Wait()
+		}
 
+		sr, ordersCfg, err := route(ord.Opts)
+		if err != nil {
+			sim.log.Warnf("order is skipped: %s", err.Error())
+			sim.ack(ord)
+			continue
 		}
-	}(ordOpts, sr)
+		order := ordrs.NewOrder(ord.Opts, &ordrs.Config{
+			CourierReadyMin: ordersCfg.DeliveryMinSeconds,
+			CourierReadyMax: ordersCfg.DeliveryMaxSeconds,
+			CourierDelay:    sim.scenario.CourierDelay(time.Since(sim.start)),
+		}, func(ord *ordrs.Order) {
+			sr.Interact(&rack.OrderEvent{
+				EventType: rack.OESpoiled,
+				Order:     ord,
+			})
+		}, func(ord *ordrs.Order) {
+			sr.Interact(&rack.OrderEvent{
+				EventType: rack.OEDelivered,
+				Order:     ord,
+			})
+		})
 
-	<-done
-	return nil
+		sr.Interact(&rack.OrderEvent{
+			EventType: rack.OECreated,
+			Order:     order,
+		})
+		sim.ack(ord)
+	}
+
+	if streaming {
+		for _, sr := range racks {
+			sr.Interact(&rack.OrderEvent{
+				EventType: rack.OEClosed,
+			})
+		}
+	}
+}
+
+// ack acknowledges the order is taken in case its source requires it
+func (sim *simulation) ack(ord *source.Order) {
+	if ord.Ack == nil {
+		return
+	}
+	if err := ord.Ack(); err != nil {
+		sim.log.Warnf("order %s is not acknowledged: %s",
+			ord.Opts.ID, err.Error())
+	}
 }
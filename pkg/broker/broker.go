@@ -0,0 +1,27 @@
+package broker
+
+// Message is the message consumed from the broker
+type Message interface {
+	// Data returns the payload of the message
+	Data() []byte
+	// Ack acknowledges message is processed, broker redelivers
+	// messages that are not acknowledged
+	Ack() error
+}
+
+// Consumer consumes messages of the broker subject (topic), it is
+// implemented by subscriptions of the broker clients
+type Consumer interface {
+	// Next returns the next message, it blocks until message
+	// arrives. io.EOF is returned once consumer is closed
+	Next() (Message, error)
+	// Close stops consuming, messages that are received but not
+	// acknowledged are redelivered by the broker
+	Close() error
+}
+
+// Publisher publishes messages to the broker subjects, it is
+// implemented by the broker clients
+type Publisher interface {
+	Publish(subject string, data []byte) error
+}
@@ -0,0 +1,444 @@
+package brokertest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults of the server delivery
+const (
+	defaultAckWait       = 30 * time.Second
+	defaultMaxAckPending = 1000
+)
+
+// ackPrefix is the prefix of the reply subject delivered messages
+// are acknowledged to
+const ackPrefix = "$ACK."
+
+// message is the published message of the subject
+type message struct {
+	seq     uint64
+	subject string
+	data    []byte
+	// deliveries is the number of times message was delivered
+	deliveries int
+}
+
+// delivery is the message delivered to the subscription and not
+// acknowledged yet
+type delivery struct {
+	msg      *message
+	sub      *subscription
+	deadline time.Time
+}
+
+// subscription is the subscription of the client to the subject
+type subscription struct {
+	client  *client
+	sid     string
+	subject string
+	// inFlight is the number of delivered messages that are not
+	// acknowledged yet
+	inFlight int
+}
+
+// Server is the in-process stand-in of the NATS server for tests.
+// Every subject is the durable work queue: message published to it is
+// delivered to one of its subscriptions (round-robin) with the reply
+// subject it is acknowledged to by publishing any payload. Messages
+// that are not acknowledged within AckWait or the ones of the closed
+// subscription are redelivered, so they are delivered at least once.
+// Subscription gets no more messages while it has MaxAckPending of
+// them unacknowledged. Subjects are matched exactly, wildcards and
+// queue groups are not supported
+type Server struct {
+	// AckWait and MaxAckPending are supposed to be set before clients
+	// connect
+	AckWait       time.Duration
+	MaxAckPending int
+
+	lis net.Listener
+
+	lock     sync.Mutex
+	seq      uint64
+	queues   map[string][]*message
+	inFlight map[uint64]*delivery
+	subs     []*subscription
+	next     int
+	clients  map[*client]struct{}
+	// redelivered is the number of redelivered messages
+	redelivered int
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewServer starts the server listening on the local port
+func NewServer() *Server {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("brokertest: unable to listen: %s", err.Error()))
+	}
+
+	s := &Server{
+		AckWait:       defaultAckWait,
+		MaxAckPending: defaultMaxAckPending,
+		lis:           lis,
+		queues:        map[string][]*message{},
+		inFlight:      map[uint64]*delivery{},
+		clients:       map[*client]struct{}{},
+		done:          make(chan struct{}),
+	}
+
+	s.wg.Add(2)
+	go s.accept()
+	go s.redeliver()
+	return s
+}
+
+// URL returns the address of the server, ex: nats://127.0.0.1:4222
+func (s *Server) URL() string {
+	return "nats://" + s.lis.Addr().String()
+}
+
+// Publish adds the message to the subject
+func (s *Server) Publish(subject string, data []byte) {
+	s.lock.Lock()
+	s.seq++
+	s.queues[subject] = append(s.queues[subject], &message{
+		seq:     s.seq,
+		subject: subject,
+		data:    append([]byte{}, data...),
+	})
+	s.lock.Unlock()
+
+	s.dispatch()
+}
+
+// Pending returns the number of messages of the subject that are not
+// acknowledged: queued and delivered ones
+func (s *Server) Pending(subject string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.queues[subject]) + s.delivered(subject)
+}
+
+// InFlight returns the number of delivered messages of the subject
+// that are not acknowledged
+func (s *Server) InFlight(subject string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.delivered(subject)
+}
+
+// delivered returns the number of in flight messages of the subject,
+// lock is supposed to be held
+func (s *Server) delivered(subject string) int {
+	n := 0
+	for _, d := range s.inFlight {
+		if d.msg.subject == subject {
+			n++
+		}
+	}
+	return n
+}
+
+// Redelivered returns the number of redelivered messages
+func (s *Server) Redelivered() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.redelivered
+}
+
+// Close stops the server and disconnects its clients
+func (s *Server) Close() {
+	close(s.done)
+	s.lis.Close()
+
+	s.lock.Lock()
+	for c := range s.clients {
+		c.conn.Close()
+	}
+	s.lock.Unlock()
+	s.wg.Wait()
+}
+
+// accept serves connecting clients
+func (s *Server) accept() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.lis.Accept()
+		if err != nil {
+			return
+		}
+
+		c := &client{
+			server: s,
+			conn:   conn,
+			writer: bufio.NewWriter(conn),
+		}
+		s.lock.Lock()
+		s.clients[c] = struct{}{}
+		s.lock.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			c.serve()
+			s.disconnect(c)
+		}()
+	}
+}
+
+// redeliver returns messages that are not acknowledged in time to
+// their queues
+func (s *Server) redeliver() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case now := <-ticker.C:
+			s.lock.Lock()
+			for seq, d := range s.inFlight {
+				if now.After(d.deadline) {
+					s.requeue(seq)
+				}
+			}
+			s.lock.Unlock()
+			s.dispatch()
+		}
+	}
+}
+
+// requeue puts the delivered message back to the head of its queue,
+// lock is supposed to be held
+func (s *Server) requeue(seq uint64) {
+	d := s.inFlight[seq]
+	delete(s.inFlight, seq)
+	d.sub.inFlight--
+	s.redelivered++
+	s.queues[d.msg.subject] = append([]*message{d.msg},
+		s.queues[d.msg.subject]...)
+}
+
+// dispatch delivers queued messages to the subscriptions having room
+// for them. Messages are written outside of the lock, so slow client
+// does not block the server
+func (s *Server) dispatch() {
+	type send struct {
+		client *client
+		line   string
+		data   []byte
+	}
+	sends := []send{}
+
+	s.lock.Lock()
+	for subject, queue := range s.queues {
+		for len(queue) != 0 {
+			sub := s.pick(subject)
+			if sub == nil {
+				break
+			}
+			msg := queue[0]
+			queue = queue[1:]
+
+			msg.deliveries++
+			sub.inFlight++
+			s.inFlight[msg.seq] = &delivery{
+				msg:      msg,
+				sub:      sub,
+				deadline: time.Now().Add(s.AckWait),
+			}
+			sends = append(sends, send{
+				client: sub.client,
+				line: fmt.Sprintf("MSG %s %s %s%d.%d %d\r\n", subject, sub.sid,
+					ackPrefix, msg.seq, msg.deliveries, len(msg.data)),
+				data: msg.data,
+			})
+		}
+		s.queues[subject] = queue
+	}
+	s.lock.Unlock()
+
+	for _, snd := range sends {
+		snd.client.write(snd.line + string(snd.data) + "\r\n")
+	}
+}
+
+// pick returns the next subscription of the subject (round-robin)
+// having room for the message, lock is supposed to be held
+func (s *Server) pick(subject string) *subscription {
+	for i := 0; i < len(s.subs); i++ {
+		sub := s.subs[(s.next+i)%len(s.subs)]
+		if sub.subject == subject && sub.inFlight < s.MaxAckPending {
+			s.next = (s.next + i + 1) % len(s.subs)
+			return sub
+		}
+	}
+	return nil
+}
+
+// ack removes acknowledged message, late acknowledgment of the
+// redelivered message removes it as well
+func (s *Server) ack(subject string) {
+	parts := strings.Split(strings.TrimPrefix(subject, ackPrefix), ".")
+	seq, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return
+	}
+
+	s.lock.Lock()
+	if d, ok := s.inFlight[seq]; ok {
+		delete(s.inFlight, seq)
+		d.sub.inFlight--
+	} else {
+		for subj, queue := range s.queues {
+			for i, msg := range queue {
+				if msg.seq == seq {
+					s.queues[subj] = append(queue[:i:i], queue[i+1:]...)
+					break
+				}
+			}
+		}
+	}
+	s.lock.Unlock()
+
+	s.dispatch()
+}
+
+// subscribe adds the subscription of the client
+func (s *Server) subscribe(c *client, subject, sid string) {
+	s.lock.Lock()
+	s.subs = append(s.subs, &subscription{
+		client:  c,
+		sid:     sid,
+		subject: subject,
+	})
+	s.lock.Unlock()
+
+	s.dispatch()
+}
+
+// unsubscribe removes subscriptions matching the filter, their
+// messages are redelivered
+func (s *Server) unsubscribe(match func(sub *subscription) bool) {
+	s.lock.Lock()
+	subs := []*subscription{}
+	for _, sub := range s.subs {
+		if !match(sub) {
+			subs = append(subs, sub)
+			continue
+		}
+		for seq, d := range s.inFlight {
+			if d.sub == sub {
+				s.requeue(seq)
+			}
+		}
+	}
+	s.subs = subs
+	s.next = 0
+	s.lock.Unlock()
+
+	s.dispatch()
+}
+
+// disconnect drops the client with its subscriptions
+func (s *Server) disconnect(c *client) {
+	s.lock.Lock()
+	delete(s.clients, c)
+	s.lock.Unlock()
+
+	s.unsubscribe(func(sub *subscription) bool {
+		return sub.client == c
+	})
+}
+
+// client is the connection of the client
+type client struct {
+	server *Server
+	conn   net.Conn
+
+	writeLock sync.Mutex
+	writer    *bufio.Writer
+}
+
+// write sends the protocol command to the client, errors are
+// detected by the reading side
+func (c *client) write(cmd string) {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	c.writer.WriteString(cmd)
+	c.writer.Flush()
+}
+
+// serve processes commands of the client until it disconnects
+func (c *client) serve() {
+	defer c.conn.Close()
+	c.write(`INFO {"server_id":"brokertest","version":"2.0.0","proto":1,"max_payload":1048576}` +
+		"\r\n")
+
+	reader := bufio.NewReader(c.conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			continue
+		}
+
+		switch strings.ToUpper(args[0]) {
+		case "CONNECT", "PONG":
+		case "PING":
+			c.write("PONG\r\n")
+		case "SUB":
+			// SUB <subject> [queue] <sid>
+			if len(args) != 3 && len(args) != 4 {
+				c.write("-ERR 'Invalid Subscription'\r\n")
+				return
+			}
+			c.server.subscribe(c, args[1], args[len(args)-1])
+		case "UNSUB":
+			if len(args) < 2 {
+				c.write("-ERR 'Invalid Unsubscribe'\r\n")
+				return
+			}
+			c.server.unsubscribe(func(sub *subscription) bool {
+				return sub.client == c && sub.sid == args[1]
+			})
+		case "PUB":
+			// PUB <subject> [reply] <size>
+			if len(args) != 3 && len(args) != 4 {
+				c.write("-ERR 'Invalid Publish'\r\n")
+				return
+			}
+			size, err := strconv.Atoi(args[len(args)-1])
+			if err != nil {
+				c.write("-ERR 'Invalid Publish'\r\n")
+				return
+			}
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, payload); err != nil {
+				return
+			}
+			if strings.HasPrefix(args[1], ackPrefix) {
+				c.server.ack(args[1])
+				continue
+			}
+			c.server.Publish(args[1], payload[:size])
+		default:
+			c.write("-ERR 'Unknown Protocol Operation'\r\n")
+			return
+		}
+	}
+}
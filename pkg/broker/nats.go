@@ -0,0 +1,375 @@
+package broker
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// dialTimeout is the timeout of connecting to the server
+const dialTimeout = 5 * time.Second
+
+// writeTimeout is the timeout of sending the command to the server,
+// stalled server does not block publishers
+const writeTimeout = 5 * time.Second
+
+// ackPayload is the payload acknowledging the message, it is the
+// JetStream acknowledgment
+var ackPayload = []byte("+ACK")
+
+// Conn is the connection to the NATS server. Only the core protocol
+// is used: messages are consumed by subscriptions and acknowledged
+// by publishing to their reply subject, so JetStream push consumers
+// (and the brokertest stand-in) deliver them at least once
+type Conn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+
+	writeLock sync.Mutex
+	writer    *bufio.Writer
+
+	lock    sync.Mutex
+	subs    map[string]*Subscription
+	nextSID int
+	err     error
+
+	done chan struct{}
+}
+
+// Dial connects to the NATS server, address is host:port or
+// nats://host:port
+func Dial(address string) (*Conn, error) {
+	address = strings.TrimPrefix(address, "nats://")
+	conn, err := net.DialTimeout("tcp", address, dialTimeout)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to connect to the broker")
+	}
+
+	c := &Conn{
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+		writer: bufio.NewWriter(conn),
+		subs:   map[string]*Subscription{},
+		done:   make(chan struct{}),
+	}
+	if err := c.handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	go c.readLoop()
+	return c, nil
+}
+
+// handshake reads server info and sends connect options, PING reply
+// confirms the connection is accepted
+func (c *Conn) handshake() error {
+	c.conn.SetDeadline(time.Now().Add(dialTimeout))
+	defer c.conn.SetDeadline(time.Time{})
+
+	line, err := c.readLine()
+	if err != nil {
+		return errors.Wrap(err, "unable to read broker info")
+	}
+	if !strings.HasPrefix(line, "INFO") {
+		return errors.New(fmt.Sprintf("unexpected broker greeting %q", line))
+	}
+
+	if err := c.write(`CONNECT {"verbose":false,"pedantic":false,"name":"kitchen","lang":"go","protocol":1}` +
+		"\r\nPING\r\n"); err != nil {
+		return err
+	}
+
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return errors.Wrap(err, "unable to connect to the broker")
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case strings.HasPrefix(line, "-ERR"):
+			return errors.New(fmt.Sprintf("broker refused connection: %s",
+				strings.TrimSpace(strings.TrimPrefix(line, "-ERR"))))
+		}
+	}
+}
+
+// Subscribe starts consuming messages of the subject. Subscriptions
+// of the same queue group share messages of the subject, queue can
+// be empty. Pending is the number of messages returned by Next that
+// are not acknowledged yet, Next blocks while it is reached. Server
+// is read regardless, received messages are queued by subscription
+func (c *Conn) Subscribe(subject, queue string, pending int) (*Subscription, error) {
+	if pending < 1 {
+		pending = 1
+	}
+
+	c.lock.Lock()
+	c.nextSID++
+	sub := &Subscription{
+		conn:  c,
+		sid:   strconv.Itoa(c.nextSID),
+		ready: make(chan struct{}, 1),
+		slots: make(chan struct{}, pending),
+		done:  make(chan struct{}),
+	}
+	c.subs[sub.sid] = sub
+	c.lock.Unlock()
+
+	cmd := fmt.Sprintf("SUB %s %s\r\n", subject, sub.sid)
+	if queue != "" {
+		cmd = fmt.Sprintf("SUB %s %s %s\r\n", subject, queue, sub.sid)
+	}
+	if err := c.write(cmd); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// Publish publishes the message to the subject
+func (c *Conn) Publish(subject string, data []byte) error {
+	return c.write(fmt.Sprintf("PUB %s %d\r\n%s\r\n", subject, len(data), data))
+}
+
+// Close closes the connection, subscriptions are closed with it
+func (c *Conn) Close() error {
+	err := c.conn.Close()
+	<-c.done
+	return err
+}
+
+// Err returns the reason connection is closed
+func (c *Conn) Err() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.err
+}
+
+// write sends protocol command to the server
+func (c *Conn) write(cmd string) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := c.writer.WriteString(cmd); err != nil {
+		return errors.Wrap(err, "unable to write to the broker")
+	}
+	if err := c.writer.Flush(); err != nil {
+		return errors.Wrap(err, "unable to write to the broker")
+	}
+	return nil
+}
+
+// readLine returns the protocol line without CRLF
+func (c *Conn) readLine() (string, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// readLoop processes messages of the server until connection is
+// closed. Messages are queued by their subscriptions without waiting
+// for them to be read, so slow subscription holds back neither the
+// other ones nor PING replies
+func (c *Conn) readLoop() {
+	err := c.read()
+
+	c.lock.Lock()
+	c.err = err
+	c.lock.Unlock()
+	close(c.done)
+}
+
+func (c *Conn) read() error {
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			if err == io.EOF {
+				return errors.New("broker closed connection")
+			}
+			return errors.Wrap(err, "unable to read from the broker")
+		}
+
+		switch {
+		case strings.HasPrefix(line, "MSG "):
+			if err := c.message(strings.Fields(line)[1:]); err != nil {
+				return err
+			}
+		case line == "PING":
+			if err := c.write("PONG\r\n"); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return errors.New(fmt.Sprintf("broker error: %s",
+				strings.TrimSpace(strings.TrimPrefix(line, "-ERR"))))
+		}
+	}
+}
+
+// message reads the payload of MSG <subject> <sid> [reply] <size>
+// and queues it by the subscription
+func (c *Conn) message(args []string) error {
+	if len(args) != 3 && len(args) != 4 {
+		return errors.New(fmt.Sprintf("malformed message %v", args))
+	}
+	size, err := strconv.Atoi(args[len(args)-1])
+	if err != nil {
+		return errors.Wrap(err, "malformed message size")
+	}
+
+	payload := make([]byte, size+2)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return errors.Wrap(err, "unable to read message")
+	}
+
+	msg := &natsMessage{
+		subject: args[0],
+		data:    payload[:size],
+	}
+	if len(args) == 4 {
+		msg.reply = args[2]
+	}
+
+	c.lock.Lock()
+	sub, ok := c.subs[args[1]]
+	c.lock.Unlock()
+	if !ok {
+		// subscription is closed
+		return nil
+	}
+	msg.sub = sub
+	sub.push(msg)
+	return nil
+}
+
+// Subscription consumes messages of the subject. Received messages
+// are queued until they are read by Next, the queue is bounded by the
+// server: JetStream stops delivering once max_ack_pending messages
+// are not acknowledged
+type Subscription struct {
+	conn *Conn
+	sid  string
+
+	lock  sync.Mutex
+	queue []*natsMessage
+	// ready signals the message is queued
+	ready chan struct{}
+	// slots holds the messages returned by Next that are not
+	// acknowledged yet
+	slots chan struct{}
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// push queues the received message
+func (s *Subscription) push(msg *natsMessage) {
+	s.lock.Lock()
+	s.queue = append(s.queue, msg)
+	s.lock.Unlock()
+
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+}
+
+// pop returns the first queued message, it returns nil in case queue
+// is empty
+func (s *Subscription) pop() *natsMessage {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if len(s.queue) == 0 {
+		return nil
+	}
+	msg := s.queue[0]
+	s.queue[0] = nil
+	s.queue = s.queue[1:]
+	return msg
+}
+
+// Next returns the next message of the subject, it waits for the
+// acknowledgment of the returned messages in case pending of them
+// are not acknowledged yet
+func (s *Subscription) Next() (Message, error) {
+	select {
+	case s.slots <- struct{}{}:
+	case <-s.done:
+		return nil, io.EOF
+	case <-s.conn.done:
+		return nil, s.conn.Err()
+	}
+
+	for {
+		if msg := s.pop(); msg != nil {
+			if msg.reply == "" {
+				// message is not acknowledged
+				<-s.slots
+			}
+			return msg, nil
+		}
+
+		select {
+		case <-s.ready:
+		case <-s.done:
+			<-s.slots
+			return nil, io.EOF
+		case <-s.conn.done:
+			<-s.slots
+			return nil, s.conn.Err()
+		}
+	}
+}
+
+// Close unsubscribes from the subject
+func (s *Subscription) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.conn.lock.Lock()
+		delete(s.conn.subs, s.sid)
+		s.conn.lock.Unlock()
+		err = s.conn.write(fmt.Sprintf("UNSUB %s\r\n", s.sid))
+	})
+	return err
+}
+
+// natsMessage is the message received from the NATS server
+type natsMessage struct {
+	sub     *Subscription
+	subject string
+	reply   string
+	data    []byte
+
+	ackOnce sync.Once
+}
+
+// Data returns the payload of the message
+func (m *natsMessage) Data() []byte {
+	return m.data
+}
+
+// Ack acknowledges the message, messages without reply subject are
+// delivered at most once, so they are not acknowledged. Message is
+// not pending any more once it is acknowledged, even in case of the
+// failure, as it is redelivered by the server then
+func (m *natsMessage) Ack() error {
+	if m.reply == "" {
+		return nil
+	}
+	var err error
+	m.ackOnce.Do(func() {
+		err = m.sub.conn.Publish(m.reply, ackPayload)
+		<-m.sub.slots
+	})
+	return err
+}
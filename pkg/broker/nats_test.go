@@ -0,0 +1,199 @@
+package broker
+
+import (
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/bgzzz/kitchen/pkg/broker/brokertest"
+	"github.com/stretchr/testify/assert"
+)
+
+// next returns the payload of the next message of the subscription
+func next(t *testing.T, sub *Subscription) Message {
+	msgs := make(chan Message, 1)
+	go func() {
+		msg, err := sub.Next()
+		assert.Equal(t, nil, err, "should be equal")
+		msgs <- msg
+	}()
+	select {
+	case msg := <-msgs:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("message is not received")
+	}
+	return nil
+}
+
+// eventually waits until the condition is true
+func eventually(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition is not met")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	srv := brokertest.NewServer()
+	defer srv.Close()
+
+	conn, err := Dial(srv.URL())
+	assert.Equal(t, nil, err, "should be equal")
+	defer conn.Close()
+
+	sub, err := conn.Subscribe("orders", "", 10)
+	assert.Equal(t, nil, err, "should be equal")
+
+	// messages are published by the client as well
+	srv.Publish("orders", []byte("1"))
+	assert.Equal(t, nil, conn.Publish("orders", []byte("2")), "should be equal")
+	srv.Publish("other", []byte("3"))
+
+	for _, expected := range []string{"1", "2"} {
+		msg := next(t, sub)
+		assert.Equal(t, expected, string(msg.Data()), "should be equal")
+		assert.Equal(t, nil, msg.Ack(), "should be equal")
+	}
+	eventually(t, func() bool { return srv.Pending("orders") == 0 })
+	assert.Equal(t, 1, srv.Pending("other"), "should be equal")
+
+	assert.Equal(t, nil, sub.Close(), "should be equal")
+	_, err = sub.Next()
+	assert.Equal(t, io.EOF, err, "should be equal")
+}
+
+func TestRedelivery(t *testing.T) {
+	srv := brokertest.NewServer()
+	srv.AckWait = 100 * time.Millisecond
+	defer srv.Close()
+
+	conn, err := Dial(srv.URL())
+	assert.Equal(t, nil, err, "should be equal")
+	defer conn.Close()
+	sub, err := conn.Subscribe("orders", "", 10)
+	assert.Equal(t, nil, err, "should be equal")
+
+	srv.Publish("orders", []byte("1"))
+	// message that is not acknowledged in time is delivered again
+	msg := next(t, sub)
+	assert.Equal(t, "1", string(msg.Data()), "should be equal")
+	msg = next(t, sub)
+	assert.Equal(t, "1", string(msg.Data()), "should be equal")
+	assert.Equal(t, nil, msg.Ack(), "should be equal")
+	eventually(t, func() bool { return srv.Pending("orders") == 0 })
+	assert.Equal(t, true, srv.Redelivered() >= 1, "should be equal")
+}
+
+func TestDisconnect(t *testing.T) {
+	srv := brokertest.NewServer()
+	defer srv.Close()
+
+	conn, err := Dial(srv.URL())
+	assert.Equal(t, nil, err, "should be equal")
+	sub, err := conn.Subscribe("orders", "", 10)
+	assert.Equal(t, nil, err, "should be equal")
+
+	// messages of the closed connection are delivered to the
+	// other subscriber
+	srv.Publish("orders", []byte("2"))
+	next(t, sub)
+	conn.Close()
+
+	other, err := Dial(srv.URL())
+	assert.Equal(t, nil, err, "should be equal")
+	defer other.Close()
+	sub, err = other.Subscribe("orders", "", 10)
+	assert.Equal(t, nil, err, "should be equal")
+	msg := next(t, sub)
+	assert.Equal(t, "2", string(msg.Data()), "should be equal")
+}
+
+func TestBackPressure(t *testing.T) {
+	srv := brokertest.NewServer()
+	srv.MaxAckPending = 3
+	defer srv.Close()
+
+	conn, err := Dial(srv.URL())
+	assert.Equal(t, nil, err, "should be equal")
+	defer conn.Close()
+	sub, err := conn.Subscribe("orders", "", 1)
+	assert.Equal(t, nil, err, "should be equal")
+
+	for i := 0; i < 10; i++ {
+		srv.Publish("orders", []byte(fmt.Sprint(i)))
+	}
+
+	// unacknowledged messages hold the rest back
+	eventually(t, func() bool { return srv.InFlight("orders") == 3 })
+	for i := 0; i < 10; i++ {
+		msg := next(t, sub)
+		assert.Equal(t, fmt.Sprint(i), string(msg.Data()), "should be equal")
+		assert.Equal(t, true, srv.InFlight("orders") <= 3, "should be equal")
+		assert.Equal(t, nil, msg.Ack(), "should be equal")
+	}
+	eventually(t, func() bool { return srv.Pending("orders") == 0 })
+}
+
+func TestSlowSubscription(t *testing.T) {
+	srv := brokertest.NewServer()
+	defer srv.Close()
+
+	conn, err := Dial(srv.URL())
+	assert.Equal(t, nil, err, "should be equal")
+	defer conn.Close()
+	slow, err := conn.Subscribe("slow", "", 1)
+	assert.Equal(t, nil, err, "should be equal")
+	fast, err := conn.Subscribe("fast", "", 1)
+	assert.Equal(t, nil, err, "should be equal")
+
+	for i := 0; i < 10; i++ {
+		srv.Publish("slow", []byte(fmt.Sprint(i)))
+	}
+	// messages of the slow subscription are received without being
+	// read, they don't hold the other subscription back
+	eventually(t, func() bool { return srv.InFlight("slow") == 10 })
+	srv.Publish("fast", []byte("1"))
+	msg := next(t, fast)
+	assert.Equal(t, "1", string(msg.Data()), "should be equal")
+	assert.Equal(t, nil, msg.Ack(), "should be equal")
+
+	// next message is not returned until the pending one is
+	// acknowledged
+	msg = next(t, slow)
+	assert.Equal(t, "0", string(msg.Data()), "should be equal")
+	msgs := make(chan Message, 1)
+	go func() {
+		msg, _ := slow.Next()
+		msgs <- msg
+	}()
+	select {
+	case <-msgs:
+		t.Fatal("message is returned before the pending one is acknowledged")
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.Equal(t, nil, msg.Ack(), "should be equal")
+	select {
+	case msg = <-msgs:
+		assert.Equal(t, "1", string(msg.Data()), "should be equal")
+	case <-time.After(5 * time.Second):
+		t.Fatal("message is not received")
+	}
+
+	assert.Equal(t, nil, slow.Close(), "should be equal")
+	_, err = slow.Next()
+	assert.Equal(t, io.EOF, err, "should be equal")
+}
+
+func TestDialRefused(t *testing.T) {
+	srv := brokertest.NewServer()
+	url := srv.URL()
+	srv.Close()
+
+	_, err := Dial(url)
+	assert.Equal(t, true, err != nil, "should be equal")
+}
@@ -1,13 +1,11 @@
 package config
 
 import (
-	"encoding/json"
-	"fmt"
 	"io/ioutil"
-	"net/http"
-	"strings"
 
+	"github.com/bgzzz/kitchen/pkg/menu"
 	"github.com/bgzzz/kitchen/pkg/orders"
+	"github.com/bgzzz/kitchen/pkg/scenario"
 	shvs "github.com/bgzzz/kitchen/pkg/shelves"
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v2"
@@ -16,16 +14,343 @@ import (
 // OrdersConfig general configuration of the orders
 // created in the simulation
 type OrdersConfig struct {
-	OrdersPerSecond    int     `yaml:"orders-per-second"`
-	DeliveryMinSeconds float64 `yaml:"delivery-min-seconds"`
-	DeliveryMaxSeconds float64 `yaml:"delivery-max-seconds"`
+	OrdersPerSecond    int     `yaml:"orders-per-second" description:"Number of orders created every second"`
+	DeliveryMinSeconds float64 `yaml:"delivery-min-seconds" description:"Min courier arrival time after order creation, seconds"`
+	DeliveryMaxSeconds float64 `yaml:"delivery-max-seconds" description:"Max courier arrival time after order creation, seconds"`
 }
 
+// EconomicsConfig defines how money outcomes of the
+// simulation are calculated
+type EconomicsConfig struct {
+	// ScaleRevenueByValue scales order price by its delivered value
+	ScaleRevenueByValue bool `yaml:"scale-revenue-by-value" description:"Scale order price by its delivered value"`
+	// RefundThreshold is the delivered value below which order
+	// is refunded
+	RefundThreshold float64 `yaml:"refund-threshold" description:"Delivered value below which order price is refunded, 0..1"`
+}
+
+// RackConfig general configuration of the shelf rack
+type RackConfig struct {
+	// RebalanceIntervalSeconds is the period of moving overflow
+	// orders to their optimal shelves, 0 disables periodic moving
+	RebalanceIntervalSeconds float64 `yaml:"rebalance-interval-seconds" description:"Period of moving overflow orders to their optimal shelves, seconds, 0 disables it"`
+	// Strategy is the dispatching strategy: "default" or "optimizer"
+	Strategy string `yaml:"strategy" description:"Dispatching strategy: default or optimizer"`
+	// OptimizerBudget is the max number of moves evaluated by the
+	// optimizer per rack event
+	OptimizerBudget int `yaml:"optimizer-budget" description:"Max number of moves evaluated by the optimizer per rack event"`
+	// RemovedShelfPolicy defines what happens on config reload to the
+	// orders of the removed shelves: "migrate" or "waste"
+	RemovedShelfPolicy string `yaml:"removed-shelf-policy" description:"What happens on config reload to the orders of removed or shrunk shelves: migrate (default) or waste"`
+}
+
+const (
+	// StrategyDefault places orders according to the rules only
+	StrategyDefault = "default"
+	// StrategyOptimizer in addition rearranges orders on every rack
+	// event to maximize expected delivered value
+	StrategyOptimizer = "optimizer"
+
+	// RemovedShelfMigrate moves orders of the removed shelves to
+	// the other shelves on config reload
+	RemovedShelfMigrate = "migrate"
+	// RemovedShelfWaste wastes orders of the removed shelves on
+	// config reload
+	RemovedShelfWaste = "waste"
+)
+
+// OrdersSourceConfig defines how orders are read during the
+// simulation
+type OrdersSourceConfig struct {
+	// Stream reads orders record by record from the NDJSON file
+	// (or stdin in case orders-path is "-") while simulation runs
+	// instead of loading the whole file upfront
+	Stream bool `yaml:"stream" description:"Read ndjson orders record by record while simulation runs, implied by orders-path - (stdin)"`
+	// Follow waits for the records appended to the orders file
+	Follow bool `yaml:"follow" description:"Wait for the records appended to the streamed orders file"`
+	// FollowIdleSeconds stops following the file in case nothing
+	// is appended to it during this period, 0 means never stop
+	FollowIdleSeconds float64 `yaml:"follow-idle-seconds" description:"Stop following the orders file after this period without new records, seconds, 0 means never"`
+	// TimestampField is the record field with the arrival time
+	// of the order (RFC3339 string or unix seconds)
+	TimestampField string `yaml:"timestamp-field" description:"Record field with the order arrival time (RFC3339 or unix seconds), orders-per-second is used for records without it"`
+	// Broker is the message broker orders are consumed from instead
+	// of the orders file
+	Broker BrokerConfig `yaml:"broker" description:"Message broker orders are consumed from instead of orders-path"`
+}
+
+// BrokerConfig defines the subject of the NATS server orders are
+// consumed from
+type BrokerConfig struct {
+	// URL is the address of the server, empty disables consuming
+	URL     string `yaml:"url" description:"NATS server orders are consumed from (nats://host:port), empty disables it"`
+	Subject string `yaml:"subject" description:"Subject orders are published to, every message is a single JSON order record"`
+	// Queue is the queue group of the simulators sharing orders of
+	// the subject
+	Queue string `yaml:"queue" description:"Queue group of the simulators sharing orders of the subject, empty means no group"`
+	// MaxPending is the number of consumed orders that are not put
+	// on the rack yet, orders are not consumed further while it is
+	// reached
+	MaxPending int `yaml:"max-pending" description:"Number of consumed orders that are not put on the rack yet, orders are not consumed further while it is reached"`
+}
+
+// SamplingConfig defines time-series sampling of the rack while
+// simulation runs
+type SamplingConfig struct {
+	// Path is the CSV file samples are written to, empty disables
+	// sampling
+	Path string `yaml:"path" description:"CSV file rack samples are written to, empty disables sampling"`
+	// IntervalSeconds is the period of sampling
+	IntervalSeconds float64 `yaml:"interval-seconds" description:"Period of sampling rack occupancy, at-risk orders and mean order value, seconds"`
+}
+
+// WebhookConfig defines the endpoint rack events are posted to
+type WebhookConfig struct {
+	URL string `yaml:"url" required:"true" description:"http(s) link events are posted to"`
+	// Events are the names of the posted events, all supported
+	// events are posted in case it is empty
+	Events []string `yaml:"events,omitempty" description:"Posted events: delivered, spoiled, wasted, moved, cancelled; all of them when not set"`
+	// Secret is the key of the HMAC-SHA256 signature of the payloads
+	Secret         string  `yaml:"secret,omitempty" description:"Key of the HMAC-SHA256 payload signature sent in X-Kitchen-Signature header, payloads are not signed when not set"`
+	TimeoutSeconds float64 `yaml:"timeout-seconds,omitempty" description:"Timeout of a single post, seconds"`
+	// Retries is the pointer, so explicit 0 disables retrying
+	Retries        *int    `yaml:"retries,omitempty" description:"Number of retries of failed post, 0 disables retrying"`
+	BackoffSeconds float64 `yaml:"backoff-seconds,omitempty" description:"Delay before the first retry, doubled for every next one, seconds"`
+}
+
+// WebhookEvents are the names of the events webhooks post
+var WebhookEvents = []string{SinkEventCancelled, SinkEventDelivered,
+	SinkEventMoved, SinkEventSpoiled, SinkEventWasted}
+
+// WithDefaults returns config with default values set instead
+// of not set ones
+func (wc WebhookConfig) WithDefaults() WebhookConfig {
+	if wc.TimeoutSeconds == 0 {
+		wc.TimeoutSeconds = defaultWebhookTimeoutSeconds
+	}
+	if wc.Retries == nil {
+		retries := defaultWebhookRetries
+		wc.Retries = &retries
+	}
+	if wc.BackoffSeconds == 0 {
+		wc.BackoffSeconds = defaultWebhookBackoffSeconds
+	}
+	return wc
+}
+
+const (
+	defaultWebhookTimeoutSeconds = 5
+	defaultWebhookRetries        = 3
+	defaultWebhookBackoffSeconds = 0.5
+)
+
+// SinkConfig defines the sink rack events are written to
+type SinkConfig struct {
+	Type string `yaml:"type" required:"true" description:"Sink type: file (NDJSON), http (batch POST) or broker (NATS subject)"`
+	// Events are the names of the written events, all supported
+	// events are written in case it is empty
+	Events []string `yaml:"events,omitempty" description:"Written events: placed, moved, delivered, spoiled, wasted, cancelled; all of them when not set"`
+	// Path, MaxSizeMB and MaxFiles are the settings of the file sink,
+	// MaxSizeMB and MaxFiles are the pointers, so explicit 0 is kept
+	Path      string   `yaml:"path,omitempty" description:"NDJSON file of the file sink"`
+	MaxSizeMB *float64 `yaml:"max-size-mb,omitempty" description:"Size of the file sink file it is rotated at, megabytes, 0 disables rotation"`
+	MaxFiles  *int     `yaml:"max-files,omitempty" description:"Number of the rotated files of the file sink that are kept"`
+	// URL is the link of the http sink or the server of the broker
+	// sink
+	URL     string `yaml:"url,omitempty" description:"http(s) link of the http sink, NATS server (nats://host:port) of the broker sink"`
+	Subject string `yaml:"subject,omitempty" description:"Subject of the broker sink"`
+	// TimeoutSeconds, Retries and BackoffSeconds are the settings of
+	// the http sink, Retries is the pointer, so explicit 0 is kept
+	TimeoutSeconds float64 `yaml:"timeout-seconds,omitempty" description:"Timeout of a single post of the http sink, seconds"`
+	Retries        *int    `yaml:"retries,omitempty" description:"Number of retries of failed post of the http sink, 0 disables retrying"`
+	BackoffSeconds float64 `yaml:"backoff-seconds,omitempty" description:"Delay before the first retry of the http sink, doubled for every next one, seconds"`
+	// BufferSize, BatchSize, FlushIntervalSeconds and Policy define
+	// delivery of the events to the sink
+	BufferSize           int     `yaml:"buffer-size,omitempty" description:"Number of events waiting to be written"`
+	BatchSize            int     `yaml:"batch-size,omitempty" description:"Max number of events written at once"`
+	FlushIntervalSeconds float64 `yaml:"flush-interval-seconds,omitempty" description:"Max time event waits for the batch to be filled, seconds"`
+	Policy               string  `yaml:"policy,omitempty" description:"Events that do not fit the buffer: drop (the new event), drop-oldest or block (rack waits for room)"`
+}
+
+const (
+	// SinkFile appends events to the NDJSON file
+	SinkFile = "file"
+	// SinkHTTP posts batches of events to the http(s) link
+	SinkHTTP = "http"
+	// SinkBroker publishes events to the NATS subject
+	SinkBroker = "broker"
+
+	// SinkPolicyDrop drops the event that does not fit the buffer
+	SinkPolicyDrop = "drop"
+	// SinkPolicyDropOldest drops the oldest buffered event
+	SinkPolicyDropOldest = "drop-oldest"
+	// SinkPolicyBlock holds the rack until there is room in the buffer
+	SinkPolicyBlock = "block"
+
+	// Names of the events written to the sinks
+	SinkEventPlaced    = "placed"
+	SinkEventMoved     = "moved"
+	SinkEventDelivered = "delivered"
+	SinkEventSpoiled   = "spoiled"
+	SinkEventWasted    = "wasted"
+	SinkEventCancelled = "cancelled"
+)
+
+// SinkTypes are the supported sink types
+var SinkTypes = []string{SinkFile, SinkHTTP, SinkBroker}
+
+// SinkPolicies are the supported policies of the events that do not
+// fit the sink buffer
+var SinkPolicies = []string{SinkPolicyDrop, SinkPolicyDropOldest,
+	SinkPolicyBlock}
+
+// SinkEvents are the names of the events sinks write
+var SinkEvents = []string{SinkEventCancelled, SinkEventDelivered,
+	SinkEventMoved, SinkEventPlaced, SinkEventSpoiled, SinkEventWasted}
+
+// WithDefaults returns config with default values set instead
+// of not set ones
+func (sc SinkConfig) WithDefaults() SinkConfig {
+	if sc.MaxSizeMB == nil {
+		maxSizeMB := float64(defaultSinkMaxSizeMB)
+		sc.MaxSizeMB = &maxSizeMB
+	}
+	if sc.MaxFiles == nil {
+		maxFiles := defaultSinkMaxFiles
+		sc.MaxFiles = &maxFiles
+	}
+	if sc.TimeoutSeconds == 0 {
+		sc.TimeoutSeconds = defaultWebhookTimeoutSeconds
+	}
+	if sc.Retries == nil {
+		retries := defaultWebhookRetries
+		sc.Retries = &retries
+	}
+	if sc.BackoffSeconds == 0 {
+		sc.BackoffSeconds = defaultWebhookBackoffSeconds
+	}
+	if sc.BufferSize == 0 {
+		sc.BufferSize = defaultSinkBufferSize
+	}
+	if sc.BatchSize == 0 {
+		sc.BatchSize = defaultSinkBatchSize
+	}
+	if sc.FlushIntervalSeconds == 0 {
+		sc.FlushIntervalSeconds = defaultSinkFlushIntervalSeconds
+	}
+	if sc.Policy == "" {
+		sc.Policy = SinkPolicyDrop
+	}
+	return sc
+}
+
+const (
+	defaultSinkMaxSizeMB            = 100
+	defaultSinkMaxFiles             = 5
+	defaultSinkBufferSize           = 1024
+	defaultSinkBatchSize            = 100
+	defaultSinkFlushIntervalSeconds = 1
+)
+
 // SimulationConfig general simulation configuration
 type SimulationConfig struct {
-	ShelvesFilePath string       `yaml:"shelves-path"`
-	OrdersPath      string       `yaml:"orders-path"`
-	OrdersConfig    OrdersConfig `yaml:"orders-config"`
+	ShelvesFilePath string `yaml:"shelves-path" description:"Path to the shelves file (json, yaml, ndjson or csv)"`
+	OrdersPath      string `yaml:"orders-path" description:"Path or http(s) link to the orders file (json, yaml, ndjson or csv)"`
+	// Shelves defined inline take precedence over the shelves file
+	Shelves []*shvs.Shelf `yaml:"shelves,omitempty" description:"Shelves defined inline, shelves-path is not used when set"`
+	// Orders defined inline take precedence over the orders file
+	Orders       []*orders.OrderOptions `yaml:"orders,omitempty" description:"Orders defined inline, orders-path is not used when set"`
+	OrdersConfig OrdersConfig           `yaml:"orders-config" description:"Orders creation settings"`
+	RackConfig   RackConfig             `yaml:"rack-config" description:"Shelf rack settings"`
+	Economics    EconomicsConfig        `yaml:"economics" description:"Money outcomes calculation settings"`
+	Fetch        FetchConfig            `yaml:"fetch" description:"Settings of fetching shelves and orders by http(s) link"`
+	OrdersSource OrdersSourceConfig     `yaml:"orders-source" description:"Settings of reading orders while simulation runs"`
+	// MenuPath is the menu catalog orders are generated from
+	// according to OrderMix instead of reading them
+	MenuPath string   `yaml:"menu-path,omitempty" description:"Path or http(s) link to the menu file (json, yaml, ndjson or csv), orders are generated from the menu according to order-mix instead of reading orders-path when set"`
+	OrderMix menu.Mix `yaml:"order-mix" description:"Menu items customers order, used with menu-path"`
+	// ScenarioPath is the file of the timed operational events
+	// applied while simulation runs
+	ScenarioPath string         `yaml:"scenario-path,omitempty" description:"Path or http(s) link to the scenario file (json or yaml) with timed events applied while simulation runs"`
+	Sampling     SamplingConfig `yaml:"sampling" description:"Time-series sampling of the rack"`
+	// Webhooks are notified about order outcomes and moves while
+	// simulation runs
+	Webhooks []WebhookConfig `yaml:"webhooks,omitempty" description:"Endpoints order outcomes and moves are posted to while simulation runs"`
+	// Sinks get all rack events while simulation runs
+	Sinks []SinkConfig `yaml:"sinks,omitempty" description:"Sinks rack events are written to while simulation runs: NDJSON files, http endpoints or broker subjects"`
+	// Kitchens are simulated instead of the single kitchen of the
+	// shelves above, orders are assigned to them by the routing
+	Kitchens []KitchenConfig `yaml:"kitchens,omitempty" description:"Kitchens orders are routed to, single kitchen with the shelves above is simulated when not set"`
+	Routing  RoutingConfig   `yaml:"routing" description:"Assignment of the orders to the kitchens"`
+}
+
+// KitchenConfig defines the kitchen of the multi-kitchen simulation
+type KitchenConfig struct {
+	Name string `yaml:"name" required:"true" description:"Unique kitchen name"`
+	// Shelves defined inline take precedence over the shelves file,
+	// shelves of the simulation are used when neither is set
+	ShelvesFilePath string        `yaml:"shelves-path,omitempty" description:"Path or http(s) link to the shelves file of the kitchen, shelves of the simulation are used when neither it nor shelves are set"`
+	Shelves         []*shvs.Shelf `yaml:"shelves,omitempty" description:"Shelves of the kitchen defined inline, shelves-path is not used when set"`
+	// Location is required by the nearest routing policy
+	Location *orders.Location `yaml:"location,omitempty" description:"Location of the kitchen, required by the nearest routing policy"`
+	// DeliveryMinSeconds and DeliveryMaxSeconds override the courier
+	// settings of orders-config for the orders of the kitchen
+	DeliveryMinSeconds *float64 `yaml:"delivery-min-seconds,omitempty" description:"Min courier arrival time after order creation, seconds, the one of orders-config when not set"`
+	DeliveryMaxSeconds *float64 `yaml:"delivery-max-seconds,omitempty" description:"Max courier arrival time after order creation, seconds, the one of orders-config when not set"`
+}
+
+// OrdersConfig returns the orders settings of the kitchen: the
+// supplied ones with the courier settings of the kitchen
+func (kc KitchenConfig) OrdersConfig(base OrdersConfig) OrdersConfig {
+	if kc.DeliveryMinSeconds != nil {
+		base.DeliveryMinSeconds = *kc.DeliveryMinSeconds
+	}
+	if kc.DeliveryMaxSeconds != nil {
+		base.DeliveryMaxSeconds = *kc.DeliveryMaxSeconds
+	}
+	return base
+}
+
+// RoutingConfig defines how orders are assigned to the kitchens
+type RoutingConfig struct {
+	Policy string `yaml:"policy" description:"Routing policy: round-robin, least-loaded (by shelf occupancy) or nearest (to the order location, least loaded for orders without it)"`
+}
+
+const (
+	// RoutingRoundRobin assigns orders to the kitchens in turn
+	RoutingRoundRobin = "round-robin"
+	// RoutingLeastLoaded assigns order to the kitchen with the
+	// lowest shelf occupancy
+	RoutingLeastLoaded = "least-loaded"
+	// RoutingNearest assigns order to the kitchen nearest to its
+	// location
+	RoutingNearest = "nearest"
+)
+
+// RoutingPolicies are the supported routing policies
+var RoutingPolicies = []string{RoutingRoundRobin, RoutingLeastLoaded,
+	RoutingNearest}
+
+// LiveSettings are the settings that take effect when changed while
+// simulation runs, the rest are used on start only
+var LiveSettings = []string{
+	"orders-config.orders-per-second",
+	"orders-config.delivery-min-seconds",
+	"orders-config.delivery-max-seconds",
+	"rack-config.removed-shelf-policy",
+}
+
+// StreamOrders returns true in case orders are read record by record
+// while simulation runs
+func (sc *SimulationConfig) StreamOrders() bool {
+	return len(sc.Orders) == 0 && sc.MenuPath == "" && !sc.ConsumeOrders() &&
+		(sc.OrdersSource.Stream || sc.OrdersPath == StdinPath)
+}
+
+// ConsumeOrders returns true in case orders are consumed from the
+// message broker
+func (sc *SimulationConfig) ConsumeOrders() bool {
+	return sc.OrdersSource.Broker.URL != ""
 }
 
 // NewSimulationConfig reads configuration file and parses it
@@ -43,140 +368,144 @@ func NewSimulationConfig(configFilePath string) (*SimulationConfig, error) {
 		return nil, errors.Wrap(err, "unable to parse yaml")
 	}
 
+	if err := validateYAML(SimulationConfigSchema, b); err != nil {
+		return nil, err
+	}
+
 	return &sc, nil
 }
 
+// StdinPath is the orders path referring to the standard input
+const StdinPath = "-"
+
 // FetchOrders downloads order definition from a file/link parse it
-// and return in list
+// and return in list. See FetchOrdersWith for the details
+func FetchOrders(ordersFilePath string) ([]*orders.OrderOptions, error) {
+	return FetchOrdersWith(ordersFilePath, defaultFetchConfig())
+}
+
+// FetchOrdersWith downloads order definition from a local path,
+// file:// or http(s):// link, parse it and return in list. File format
+// is detected by the content type of the link or by the file extension
+// (see formatOf)
 // return errors in case of reading/parsing problems
-func FetchOrders(ordersFilePath string) (opts []*orders.OrderOptions, err error) {
+func FetchOrdersWith(ordersFilePath string, fetchCfg FetchConfig) (
+	opts []*orders.OrderOptions, err error) {
 	opts = []*orders.OrderOptions{}
-	err = nil
-	if strings.Contains(ordersFilePath, "http") {
-		res, e := http.Get(ordersFilePath)
-		if e != nil {
-			err = errors.Wrap(e, "unable to fetch orders")
-			return
-		}
-
-		body, e := ioutil.ReadAll(res.Body)
-		if e != nil {
-			err = errors.Wrap(e, "unable to read http responce body")
-			return
-		}
-
-		if e := json.Unmarshal(body, &opts); e != nil {
-			err = errors.Wrap(e, "unable to parse orders json")
-			return
-		}
-
-		return
-	}
-
-	b, e := ioutil.ReadFile(ordersFilePath)
+	b, contentType, e := fetch(ordersFilePath, fetchCfg)
 	if e != nil {
-		err = errors.Wrap(e, "unable to read orders file")
+		err = errors.Wrap(e, "unable to read orders")
 		return
 	}
 
-	if e := json.Unmarshal(b, &opts); e != nil {
-		err = errors.Wrap(e, "unable to parse json")
+	if e := decode(b, formatOf(ordersFilePath, contentType),
+		OrdersSchema, &opts); e != nil {
+		err = errors.Wrap(e, "unable to parse orders")
 		return
 	}
 
 	return
 }
 
-// FetchShelves reads shelves definition
+// FetchShelves reads shelves definition. See FetchShelvesWith
+// for the details
+func FetchShelves(shelvesPath string) ([]*shvs.Shelf, error) {
+	return FetchShelvesWith(shelvesPath, defaultFetchConfig())
+}
+
+// FetchShelvesWith reads shelves definition from a local path,
+// file:// or http(s):// link. File format is detected the same way
+// it is done for orders
 // return errors in case of file reading structure parsing problems
-func FetchShelves(shelvesPath string) (shelves []*shvs.Shelf, err error) {
-	err = nil
+func FetchShelvesWith(shelvesPath string, fetchCfg FetchConfig) (
+	shelves []*shvs.Shelf, err error) {
 	shelves = []*shvs.Shelf{}
-	b, e := ioutil.ReadFile(shelvesPath)
+	b, contentType, e := fetch(shelvesPath, fetchCfg)
 	if e != nil {
-		err = errors.Wrap(e, "unable to read shelves file")
+		err = errors.Wrap(e, "unable to read shelves")
 		return
 	}
 
-	if e := json.Unmarshal(b, &shelves); e != nil {
-		err = errors.Wrap(e, "unable to parse shelves json")
+	if e := decode(b, formatOf(shelvesPath, contentType),
+		ShelvesSchema, &shelves); e != nil {
+		err = errors.Wrap(e, "unable to parse shelves")
 		return
 	}
 
 	return
 }
 
-func validateShelf(shelf *shvs.Shelf) error {
-	if shelf.Name == "" {
-		return errors.New("name of shelf can't be empty")
-	}
-	if shelf.Capacity < 0 {
-		return errors.New(fmt.Sprintf("shelf %s: capacity has to be set >= 0",
-			shelf.Name))
-	}
-	if shelf.ShelfDecayModifier < 0 {
-		return errors.New(fmt.Sprintf("shelf %s: shelf decay modifier has to be integer >= 0",
-			shelf.Name))
+// FetchMenuWith reads menu items from a local path, file:// or
+// http(s):// link. File format is detected the same way it is done
+// for orders
+// return errors in case of reading/parsing problems
+func FetchMenuWith(menuPath string, fetchCfg FetchConfig) (
+	items []*menu.Item, err error) {
+	items = []*menu.Item{}
+	b, contentType, e := fetch(menuPath, fetchCfg)
+	if e != nil {
+		err = errors.Wrap(e, "unable to read menu")
+		return
 	}
-	return nil
-}
 
-func validateOrderOptions(opts *orders.OrderOptions) error {
-	if opts.ID == "" {
-		return errors.New("ID of order can't be empty")
-	}
-	if opts.Name == "" {
-		return errors.New("Name of order can't be empty")
-	}
-	if opts.ShelfLife <= 0 {
-		return errors.New(fmt.Sprintf("order %s: shelf life <= 0", opts.ID))
-	}
-	if opts.DecayRate < 0 {
-		return errors.New(fmt.Sprintf("order %s: decay rate < 0", opts.ID))
+	if e := decode(b, formatOf(menuPath, contentType),
+		MenuSchema, &items); e != nil {
+		err = errors.Wrap(e, "unable to parse menu")
+		return
 	}
-	return nil
+
+	return
 }
 
-// ValidateShelves validates supplied shelves structures
-// return non nil error in case of invalid shelflist
-func ValidateShelves(shelves []*shvs.Shelf) error {
+// LoadScenario reads scenario of the simulation, scenario without
+// events is returned in case scenario file is not set
+// return errors in case of reading/parsing problems
+func LoadScenario(cfg *SimulationConfig) (*scenario.Scenario, error) {
+	sc := &scenario.Scenario{}
+	if cfg.ScenarioPath == "" {
+		return sc, nil
+	}
 
-	shelvesTempMap := map[string]struct{}{}
-	shelvesNameMap := map[string]struct{}{}
+	b, contentType, err := fetch(cfg.ScenarioPath, cfg.Fetch)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read scenario")
+	}
 
-	for _, shelf := range shelves {
-		if _, ok := shelvesTempMap[shelf.Temp]; ok {
-			return errors.New(fmt.Sprintf("shelf %s: shelf with this temp was already defined",
-				shelf.Name))
-		}
-		if _, ok := shelvesNameMap[shelf.Name]; ok {
-			return errors.New(fmt.Sprintf("shelf %s: shelf with this name was already defined",
-				shelf.Name))
-		}
-		if err := validateShelf(shelf); err != nil {
-			return errors.Wrap(err, "shelf definition is not valid")
-		}
-		shelvesTempMap[shelf.Temp] = struct{}{}
-		shelvesNameMap[shelf.Name] = struct{}{}
+	if err := decode(b, formatOf(cfg.ScenarioPath, contentType),
+		ScenarioSchema, sc); err != nil {
+		return nil, errors.Wrap(err, "unable to parse scenario")
 	}
 
-	return nil
+	return sc, nil
 }
 
-// Validateorders.OrderOptions validates order option list
-// return non nil error in case of non valid order options list
-func ValidateOrderOptions(orderOptions []*orders.OrderOptions) error {
-	orderIDMap := map[string]struct{}{}
+// LoadShelves returns shelves defined inline in the simulation config
+// or read from the shelves file otherwise
+func LoadShelves(cfg *SimulationConfig) ([]*shvs.Shelf, error) {
+	if len(cfg.Shelves) != 0 {
+		return cfg.Shelves, nil
+	}
+	return FetchShelvesWith(cfg.ShelvesFilePath, cfg.Fetch)
+}
 
-	for _, opts := range orderOptions {
-		if _, ok := orderIDMap[opts.ID]; ok {
-			return errors.New(fmt.Sprintf("order with id %s was previously defined",
-				opts.ID))
-		}
-		if err := validateOrderOptions(opts); err != nil {
-			return errors.Wrap(err, "order defintion is not valid")
-		}
+// LoadKitchenShelves returns shelves of the kitchen defined inline,
+// fetched from the kitchen shelves file or the simulation shelves
+// otherwise
+func LoadKitchenShelves(cfg *SimulationConfig, kc KitchenConfig) ([]*shvs.Shelf, error) {
+	if len(kc.Shelves) != 0 {
+		return kc.Shelves, nil
+	}
+	if kc.ShelvesFilePath != "" {
+		return FetchShelvesWith(kc.ShelvesFilePath, cfg.Fetch)
 	}
+	return LoadShelves(cfg)
+}
 
-	return nil
+// LoadOrders returns orders defined inline in the simulation config
+// or fetched from the orders file/link otherwise
+func LoadOrders(cfg *SimulationConfig) ([]*orders.OrderOptions, error) {
+	if len(cfg.Orders) != 0 {
+		return cfg.Orders, nil
+	}
+	return FetchOrdersWith(cfg.OrdersPath, cfg.Fetch)
 }
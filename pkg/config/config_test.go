@@ -11,8 +11,13 @@ import (
 	"testing"
 	"time"
 
+	"github.com/bgzzz/kitchen/pkg/menu"
 	ordrs "github.com/bgzzz/kitchen/pkg/orders"
+	"github.com/bgzzz/kitchen/pkg/routing"
+	"github.com/bgzzz/kitchen/pkg/scenario"
 	shvs "github.com/bgzzz/kitchen/pkg/shelves"
+	"github.com/bgzzz/kitchen/pkg/sink"
+	"github.com/bgzzz/kitchen/pkg/webhook"
 	"github.com/phayes/freeport"
 	"github.com/stretchr/testify/assert"
 )
@@ -46,9 +51,44 @@ func TestSimulationConfig(t *testing.T) {
 			},
 			err: nil,
 		},
+		{
+			fPath: "./../../fixtures/simulation-config-inline.yaml",
+			expectedCfg: &SimulationConfig{
+				Shelves: []*shvs.Shelf{
+					{
+						Name:               "hot shelf",
+						Temp:               "hot",
+						Capacity:           10,
+						ShelfDecayModifier: 1,
+					},
+					{
+						Name:               "overflow shelf",
+						Temp:               "any",
+						Capacity:           15,
+						ShelfDecayModifier: 2,
+					},
+				},
+				Orders: []*ordrs.OrderOptions{
+					{
+						ID:        "0ff534a7-a7c4-48ad-b6ec-7632e36af950",
+						Name:      "Cheese Pizza",
+						Temp:      "hot",
+						ShelfLife: 300,
+						DecayRate: 0.45,
+					},
+				},
+				OrdersConfig: OrdersConfig{
+					OrdersPerSecond:    2,
+					DeliveryMinSeconds: 4,
+					DeliveryMaxSeconds: 7,
+				},
+			},
+			err: nil,
+		},
 	}
 
 	for i, test := range tests {
+		test := test
 		t.Run(fmt.Sprintf("simulation_config_%d", i),
 			func(t *testing.T) {
 				t.Parallel()
@@ -58,7 +98,7 @@ func TestSimulationConfig(t *testing.T) {
 				} else if err != nil {
 					t.Errorf("error %v, must not be handled here", err)
 				}
-				assert.Equal(t, true, reflect.DeepEqual(*test.expectedCfg, *cfg))
+				assert.Equal(t, test.expectedCfg, cfg)
 			})
 	}
 
@@ -96,6 +136,48 @@ func TestFetchOrders(t *testing.T) {
 			err: nil,
 		},
 
+		{
+			fPath: "./../../fixtures/orders.yaml",
+			expectedOpts: []*ordrs.OrderOptions{
+				{
+					ID:        "0ff534a7-a7c4-48ad-b6ec-7632e36af950",
+					Name:      "Cheese Pizza",
+					Temp:      "hot",
+					ShelfLife: 300,
+					DecayRate: 0.45,
+				},
+			},
+			err: nil,
+		},
+
+		{
+			fPath: "./../../fixtures/orders.ndjson",
+			expectedOpts: []*ordrs.OrderOptions{
+				{
+					ID:        "0ff534a7-a7c4-48ad-b6ec-7632e36af950",
+					Name:      "Cheese Pizza",
+					Temp:      "hot",
+					ShelfLife: 300,
+					DecayRate: 0.45,
+				},
+			},
+			err: nil,
+		},
+
+		{
+			fPath: "./../../fixtures/orders.csv",
+			expectedOpts: []*ordrs.OrderOptions{
+				{
+					ID:        "0ff534a7-a7c4-48ad-b6ec-7632e36af950",
+					Name:      "Cheese Pizza",
+					Temp:      "hot",
+					ShelfLife: 300,
+					DecayRate: 0.45,
+				},
+			},
+			err: nil,
+		},
+
 		{
 			fPath: "https://some-url",
 			expectedOpts: []*ordrs.OrderOptions{
@@ -169,6 +251,8 @@ func TestFetchOrders(t *testing.T) {
 					t.Errorf("error %v, must not be handled here", err)
 				}
 
+				assert.Equal(t, len(test.expectedOpts), len(ordrs),
+					"should be equal")
 				for i, ordr := range ordrs {
 					assert.Equal(t, true,
 						reflect.DeepEqual(*test.expectedOpts[i], *ordr))
@@ -192,7 +276,12 @@ func TestFetchShelves(t *testing.T) {
 		{
 			fPath:   "./../../fixtures/wrong.yaml",
 			shelves: []*shvs.Shelf{},
-			err:     errors.New("unable to parse shelves json"),
+			err:     errors.New("unable to parse shelves"),
+		},
+		{
+			fPath:   "./../../fixtures/shelves-unknown-field.json",
+			shelves: []*shvs.Shelf{},
+			err:     errors.New(`unknown field "shelfdecaymodifier"`),
 		},
 		{
 			fPath: "./../../fixtures/shelves.json",
@@ -339,6 +428,127 @@ func TestValidateOrders(t *testing.T) {
 			},
 			isError: true,
 		},
+		{
+			orders: []*ordrs.OrderOptions{
+				{
+					ID:        "aasdasdf",
+					Name:      "asdasdasd",
+					Temp:      "asdasdasd",
+					ShelfLife: 1,
+					DecayRate: 0.2,
+					Priority:  -1,
+				},
+			},
+			isError: true,
+		},
+		{
+			orders: []*ordrs.OrderOptions{
+				{
+					ID:   "multi",
+					Name: "multi",
+					Items: []*ordrs.ItemOptions{
+						{
+							Name:      "burger",
+							Temp:      "hot",
+							ShelfLife: 1,
+							DecayRate: 0.2,
+						},
+						{
+							Name:      "ice cream",
+							Temp:      "frozen",
+							ShelfLife: 0,
+							DecayRate: 0.2,
+						},
+					},
+				},
+			},
+			isError: true,
+		},
+		{
+			orders: []*ordrs.OrderOptions{
+				{
+					ID:   "multi",
+					Name: "multi",
+					Items: []*ordrs.ItemOptions{
+						{
+							Name:      "burger",
+							Temp:      "hot",
+							ShelfLife: 1,
+							DecayRate: 0.2,
+						},
+						{
+							Name:      "ice cream",
+							Temp:      "frozen",
+							ShelfLife: 1,
+							DecayRate: 0.2,
+						},
+					},
+				},
+			},
+			isError: false,
+		},
+		{
+			orders: []*ordrs.OrderOptions{
+				{
+					ID:            "timed",
+					Name:          "timed",
+					Temp:          "hot",
+					ShelfLife:     1,
+					ArrivalOffset: floatPtr(-1),
+				},
+			},
+			isError: true,
+		},
+		{
+			orders: []*ordrs.OrderOptions{
+				{
+					ID:          "timed",
+					Name:        "timed",
+					Temp:        "hot",
+					ShelfLife:   1,
+					ArrivalTime: "yesterday",
+				},
+			},
+			isError: true,
+		},
+		{
+			orders: []*ordrs.OrderOptions{
+				{
+					ID:            "timed",
+					Name:          "timed",
+					Temp:          "hot",
+					ShelfLife:     1,
+					ArrivalOffset: floatPtr(1),
+					ArrivalTime:   "2020-01-01T00:00:00Z",
+				},
+			},
+			isError: true,
+		},
+		{
+			orders: []*ordrs.OrderOptions{
+				{
+					ID:            "timed",
+					Name:          "timed",
+					Temp:          "hot",
+					ShelfLife:     1,
+					CourierOffset: floatPtr(-1),
+				},
+			},
+			isError: true,
+		},
+		{
+			orders: []*ordrs.OrderOptions{
+				{
+					ID:            "timed",
+					Name:          "timed",
+					Temp:          "hot",
+					ShelfLife:     1,
+					ArrivalTime:   "2020-01-01T00:00:00Z",
+					CourierOffset: floatPtr(2.5),
+				},
+			},
+			isError: false,
+		},
 		{
 			orders: []*ordrs.OrderOptions{
 				{
@@ -361,6 +571,7 @@ func TestValidateOrders(t *testing.T) {
 	}
 
 	for i, test := range tests {
+		test := test
 		t.Run(fmt.Sprintf("validate_orders_%d", i),
 			func(t *testing.T) {
 				t.Parallel()
@@ -374,3 +585,542 @@ func TestValidateOrders(t *testing.T) {
 }
 
 // validate shelves
+func TestValidateShelves(t *testing.T) {
+	tests := []struct {
+		shelves  []*shvs.Shelf
+		problems int
+	}{
+		{
+			shelves: []*shvs.Shelf{
+				{
+					Name:     "hot",
+					Temp:     "hot",
+					Capacity: 1,
+				},
+				{
+					Name:     "hot",
+					Temp:     "hot",
+					Capacity: -1,
+				},
+			},
+			problems: 3,
+		},
+		{
+			shelves: []*shvs.Shelf{
+				{
+					Name:     "hot",
+					Temp:     "hot",
+					Capacity: 1,
+				},
+				{
+					Name:     "overflow",
+					Temp:     shvs.OverflowShelfTemp,
+					Capacity: 1,
+				},
+			},
+			problems: 0,
+		},
+	}
+
+	for i, test := range tests {
+		test := test
+		t.Run(fmt.Sprintf("validate_shelves_%d", i),
+			func(t *testing.T) {
+				t.Parallel()
+				err := ValidateShelves(test.shelves)
+				if test.problems == 0 {
+					assert.Nil(t, err, "should be valid")
+					return
+				}
+				assert.Equal(t, test.problems, len(err.(ValidationErrors)),
+					"should be equal")
+			})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := &SimulationConfig{
+		ShelvesFilePath: "shelves.json",
+		OrdersPath:      "orders.json",
+		OrdersConfig: OrdersConfig{
+			OrdersPerSecond:    1,
+			DeliveryMinSeconds: 5,
+			DeliveryMaxSeconds: 2,
+		},
+		OrdersSource: OrdersSourceConfig{
+			Broker: BrokerConfig{URL: "localhost:4222"},
+		},
+		Sampling: SamplingConfig{
+			Path: "samples.csv",
+		},
+		Webhooks: []WebhookConfig{
+			{URL: "http://localhost:8080/orders", Events: []string{"delivered"}},
+			{URL: "localhost:8080", Events: []string{"created"}, Retries: intPtr(-1)},
+		},
+		Sinks: []SinkConfig{
+			{Type: "file", Path: "events.ndjson"},
+			{Type: "file", Events: []string{"created"}},
+			{Type: "http", URL: "localhost:8080", Policy: "wait"},
+			{Type: "broker", URL: "nats://localhost:4222", BatchSize: -1},
+			{Type: "kafka"},
+		},
+	}
+
+	shelves := []*shvs.Shelf{
+		{
+			Name:     "hot",
+			Temp:     "hot",
+			Capacity: 0,
+		},
+	}
+
+	orders := []*ordrs.OrderOptions{
+		{
+			ID:        "pizza",
+			Name:      "pizza",
+			Temp:      "hot",
+			ShelfLife: 1,
+		},
+		{
+			ID:        "pizza",
+			Name:      "ice cream",
+			Temp:      "frozen",
+			ShelfLife: 1,
+		},
+	}
+
+	problems := Validate("kitchen.yaml", cfg, shelves, orders)
+
+	expected := []string{
+		"kitchen.yaml: delivery-min-seconds 5 is greater than delivery-max-seconds 2",
+		`kitchen.yaml: broker url "localhost:4222" has to be nats://host:port`,
+		"kitchen.yaml: broker subject is not set",
+		"kitchen.yaml: broker max-pending has to be > 0",
+		"kitchen.yaml: sampling interval-seconds has to be > 0",
+		`kitchen.yaml: webhook 1: url "localhost:8080" has to be http(s) link`,
+		"kitchen.yaml: webhook 1: unknown event created, supported: cancelled, delivered, moved, spoiled, wasted",
+		"kitchen.yaml: webhook 1: timeout-seconds, retries and backoff-seconds have to be >= 0",
+		"kitchen.yaml: sink 1: path of the file sink is not set",
+		"kitchen.yaml: sink 1: unknown event created, supported: cancelled, delivered, moved, placed, spoiled, wasted",
+		`kitchen.yaml: sink 2: url "localhost:8080" has to be http(s) link`,
+		"kitchen.yaml: sink 2: unknown policy wait, supported: drop, drop-oldest, block",
+		"kitchen.yaml: sink 3: subject of the broker sink is not set",
+		"kitchen.yaml: sink 3: sizes, counts and durations have to be >= 0",
+		"kitchen.yaml: sink 4: unknown type kafka, supported: file, http, broker",
+		"orders.json[1]: order with id pizza was previously defined",
+		"shelves.json: overflow shelf (temp any) is not defined",
+		"orders.json[0]: order pizza: shelf of temp hot and overflow shelf have no capacity, order can never be delivered",
+		"orders.json[1]: order pizza: there is no shelf for temp frozen",
+	}
+
+	assert.Equal(t, len(expected), len(problems), problems.Error())
+	for i, problem := range problems {
+		assert.Equal(t, expected[i], problem.Error(), "should be equal")
+	}
+}
+
+func TestOrderValidator(t *testing.T) {
+	validator := OrderValidator([]*shvs.Shelf{
+		{
+			Name:     "hot",
+			Temp:     "hot",
+			Capacity: 1,
+		},
+		{
+			Name:     "overflow",
+			Temp:     shvs.OverflowShelfTemp,
+			Capacity: 1,
+		},
+	})
+
+	tests := []struct {
+		opts *ordrs.OrderOptions
+		err  string
+	}{
+		{
+			opts: &ordrs.OrderOptions{
+				ID:        "pizza",
+				Name:      "pizza",
+				Temp:      "hot",
+				ShelfLife: 1,
+			},
+		},
+		{
+			opts: &ordrs.OrderOptions{
+				ID:   "pizza",
+				Name: "pizza",
+				Temp: "hot",
+			},
+			err: "order defintion is not valid: order pizza: shelf life <= 0",
+		},
+		{
+			opts: &ordrs.OrderOptions{
+				ID:        "ice cream",
+				Name:      "ice cream",
+				Temp:      "frozen",
+				ShelfLife: 1,
+			},
+			err: "order ice cream: there is no shelf for temp frozen",
+		},
+	}
+
+	for _, test := range tests {
+		err := validator(test.opts)
+		if test.err == "" {
+			assert.Equal(t, nil, err, "should be equal")
+			continue
+		}
+		assert.Equal(t, test.err, err.Error(), "should be equal")
+	}
+}
+
+func TestValidateKitchens(t *testing.T) {
+	cfg := &SimulationConfig{
+		ShelvesFilePath: "shelves.json",
+		OrdersPath:      "orders.json",
+		OrdersConfig: OrdersConfig{
+			OrdersPerSecond:    1,
+			DeliveryMinSeconds: 2,
+			DeliveryMaxSeconds: 6,
+		},
+		Kitchens: []KitchenConfig{
+			{Name: "north", Location: &ordrs.Location{Lat: 52.4, Lon: 4.9}},
+			{Name: "south", ShelvesFilePath: "south.json",
+				DeliveryMinSeconds: floatPtr(8)},
+			{Name: "north", Shelves: []*shvs.Shelf{
+				{Name: "hot", Temp: "hot", Capacity: 1},
+			}},
+		},
+		Routing:      RoutingConfig{Policy: "nearest"},
+		ScenarioPath: "scenario.yaml",
+	}
+
+	overflow := &shvs.Shelf{Name: "overflow", Temp: shvs.OverflowShelfTemp,
+		Capacity: 1}
+	shelves := [][]*shvs.Shelf{
+		{{Name: "hot", Temp: "hot", Capacity: 1}, overflow},
+		{{Name: "cold", Temp: "cold", Capacity: 1}, overflow},
+		cfg.Kitchens[2].Shelves,
+	}
+
+	orders := []*ordrs.OrderOptions{
+		{ID: "pizza", Name: "pizza", Temp: "hot", ShelfLife: 1},
+		{ID: "salad", Name: "salad", Temp: "cold", ShelfLife: 1},
+		{ID: "ice cream", Name: "ice cream", Temp: "frozen", ShelfLife: 1},
+	}
+
+	problems := ValidateKitchens("kitchen.yaml", cfg, shelves, orders)
+
+	expected := []string{
+		"kitchen.yaml: kitchen 1: delivery-min-seconds 8 is greater than delivery-max-seconds 6",
+		"kitchen.yaml: kitchen 1: location is required by the nearest routing policy",
+		"kitchen.yaml: kitchen 2: kitchen north was previously defined",
+		"kitchen.yaml: kitchen 2: location is required by the nearest routing policy",
+		"kitchen.yaml: scenario-path can't be used with kitchens",
+		"kitchen.yaml kitchen north shelves: overflow shelf (temp any) is not defined",
+		"orders.json[2]: order ice cream can't be put on the shelves of any kitchen",
+	}
+
+	assert.Equal(t, len(expected), len(problems), problems.Error())
+	for i, problem := range problems {
+		assert.Equal(t, expected[i], problem.Error(), "should be equal")
+	}
+
+	validator := KitchensOrderValidator(shelves[:2])
+	assert.Equal(t, nil, validator(orders[1]), "should be equal")
+	assert.Equal(t, "order can't be put on the shelves of any kitchen: order ice cream: there is no shelf for temp frozen",
+		validator(orders[2]).Error(), "should be equal")
+}
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestWebhookDefaults(t *testing.T) {
+	assert.Equal(t, defaultWebhookRetries,
+		*WebhookConfig{}.WithDefaults().Retries, "should be equal")
+	assert.Equal(t, 0, *WebhookConfig{Retries: intPtr(0)}.WithDefaults().Retries,
+		"explicit 0 disables retrying")
+}
+
+func TestSinkDefaults(t *testing.T) {
+	sc := SinkConfig{Type: "file"}.WithDefaults()
+	assert.Equal(t, float64(defaultSinkMaxSizeMB), *sc.MaxSizeMB, "should be equal")
+	assert.Equal(t, defaultSinkMaxFiles, *sc.MaxFiles, "should be equal")
+	assert.Equal(t, defaultWebhookRetries, *sc.Retries, "should be equal")
+	assert.Equal(t, SinkPolicyDrop, sc.Policy, "should be equal")
+
+	sc = SinkConfig{Type: "file", MaxSizeMB: floatPtr(0), MaxFiles: intPtr(0),
+		Retries: intPtr(0)}.WithDefaults()
+	assert.Equal(t, float64(0), *sc.MaxSizeMB, "explicit 0 disables rotation")
+	assert.Equal(t, 0, *sc.MaxFiles, "should be equal")
+	assert.Equal(t, 0, *sc.Retries, "explicit 0 disables retrying")
+}
+
+// names of the config are the ones of the packages the settings are
+// passed to
+func TestSupportedNames(t *testing.T) {
+	assert.Equal(t, routing.Policies(), RoutingPolicies, "should be equal")
+	assert.Equal(t, webhook.Events(), WebhookEvents, "should be equal")
+	assert.Equal(t, sink.Events(), SinkEvents, "should be equal")
+	assert.Equal(t, sink.Types(), SinkTypes, "should be equal")
+	assert.Equal(t, sink.Policies(), SinkPolicies, "should be equal")
+}
+
+func TestLoadSimulationConfig(t *testing.T) {
+	withDefaults := func(modify func(cfg *SimulationConfig)) *SimulationConfig {
+		cfg := DefaultSimulationConfig()
+		modify(cfg)
+		return cfg
+	}
+
+	tests := []struct {
+		fPath       string
+		required    bool
+		overrides   map[string]string
+		expectedCfg *SimulationConfig
+		err         string
+	}{
+		// defaults are used for absent optional file
+		{
+			fPath:       "./no-existing-path",
+			expectedCfg: DefaultSimulationConfig(),
+		},
+		{
+			fPath:    "./no-existing-path",
+			required: true,
+			err:      "unable to read config file",
+		},
+		// file values are put on top of the defaults
+		{
+			fPath: "./../../fixtures/simulation-config.yaml",
+			expectedCfg: withDefaults(func(cfg *SimulationConfig) {
+				cfg.OrdersConfig.DeliveryMinSeconds = 4
+				cfg.OrdersConfig.DeliveryMaxSeconds = 7
+			}),
+		},
+		// overrides are put on top of the file values
+		{
+			fPath: "./../../fixtures/simulation-config.yaml",
+			overrides: map[string]string{
+				"orders-path":                        "-",
+				"orders-config.delivery-max-seconds": "9.5",
+				"orders-config.orders-per-second":    "10",
+				"economics.scale-revenue-by-value":   "true",
+			},
+			expectedCfg: withDefaults(func(cfg *SimulationConfig) {
+				cfg.OrdersPath = "-"
+				cfg.OrdersConfig.OrdersPerSecond = 10
+				cfg.OrdersConfig.DeliveryMinSeconds = 4
+				cfg.OrdersConfig.DeliveryMaxSeconds = 9.5
+				cfg.Economics.ScaleRevenueByValue = true
+			}),
+		},
+		{
+			fPath: "./no-existing-path",
+			overrides: map[string]string{
+				"orders-config.orders-per-second": "many",
+			},
+			err: "invalid value \"many\" of orders-config.orders-per-second",
+		},
+		{
+			fPath: "./no-existing-path",
+			overrides: map[string]string{
+				"orders-config.orders": "1",
+			},
+			err: "unknown setting orders-config.orders",
+		},
+	}
+
+	for _, test := range tests {
+		cfg, err := LoadSimulationConfig(test.fPath, test.required, test.overrides)
+		if test.err != "" {
+			assert.Equal(t, true, err != nil && strings.HasPrefix(err.Error(), test.err),
+				fmt.Sprintf("error %v should start with %s", err, test.err))
+			continue
+		}
+		assert.Equal(t, nil, err, "should be equal")
+		assert.Equal(t, test.expectedCfg, cfg, "should be equal")
+	}
+}
+
+func TestFields(t *testing.T) {
+	fields := map[string]string{}
+	bools := map[string]bool{}
+	for _, field := range Fields() {
+		fields[field.Key] = field.Env
+		bools[field.Key] = field.Bool
+	}
+
+	assert.Equal(t, "KITCHEN_ORDERS_CONFIG_ORDERS_PER_SECOND",
+		fields["orders-config.orders-per-second"], "should be equal")
+	assert.Equal(t, "KITCHEN_SHELVES_PATH", fields["shelves-path"], "should be equal")
+	_, ok := fields["orders"]
+	assert.Equal(t, false, ok, "inline orders can't be overridden")
+	assert.Equal(t, true, bools["orders-source.follow"], "should be equal")
+	assert.Equal(t, false, bools["orders-config.orders-per-second"],
+		"should be equal")
+
+	// every field can be set
+	for key := range fields {
+		cfg := DefaultSimulationConfig()
+		err := Set(cfg, key, "1")
+		assert.Equal(t, nil, err, key)
+	}
+}
+
+func TestFetchMenu(t *testing.T) {
+	items, err := FetchMenuWith("./../../fixtures/menu.json", FetchConfig{})
+	assert.Equal(t, nil, err, "should be equal")
+	assert.Equal(t, 3, len(items), "should be equal")
+	assert.Equal(t, 2, len(items[2].Items), "should be equal")
+
+	_, err = FetchMenuWith("./../../fixtures/orders.json", FetchConfig{})
+	assert.Equal(t, true, err != nil &&
+		strings.HasPrefix(err.Error(), "unable to parse menu"),
+		"orders are not menu items")
+
+	cfg, err := NewSimulationConfig("./../../fixtures/simulation-config-menu.yaml")
+	assert.Equal(t, nil, err, "should be equal")
+	assert.Equal(t, 10, cfg.OrderMix.Count, "should be equal")
+	assert.Equal(t, 2, len(cfg.OrderMix.Windows), "should be equal")
+	assert.Equal(t, "Acai Bowl", cfg.OrderMix.Windows[1].Weights[0].Item,
+		"should be equal")
+}
+
+func TestValidateMenu(t *testing.T) {
+	cfg := &SimulationConfig{
+		ShelvesFilePath: "shelves.json",
+		MenuPath:        "menu.json",
+		OrdersConfig: OrdersConfig{
+			OrdersPerSecond: 1,
+		},
+		OrderMix: menu.Mix{
+			Count: 0,
+			Windows: []*menu.Window{
+				{
+					StartSeconds: 10,
+					EndSeconds:   5,
+					Weights: []*menu.Weight{
+						{Item: "pizza", Weight: 1},
+						{Item: "soup", Weight: -1},
+					},
+				},
+			},
+		},
+	}
+
+	shelves := []*shvs.Shelf{
+		{Name: "hot", Temp: "hot", Capacity: 1},
+		{Name: "overflow", Temp: shvs.OverflowShelfTemp, Capacity: 1},
+	}
+
+	items := []*menu.Item{
+		{Name: "pizza", Temp: "hot", ShelfLife: 1},
+		{Name: "pizza", Temp: "hot", ShelfLife: 1},
+		{Name: "ice cream", Temp: "frozen", ShelfLife: 0},
+	}
+
+	problems := ValidateMenu("kitchen.yaml", cfg, shelves, items)
+
+	expected := []string{
+		"menu.json[1]: menu item pizza was previously defined",
+		"menu.json[2]: menu item defintion is not valid: order ice cream: shelf life <= 0",
+		"kitchen.yaml: order-mix count has to be > 0",
+		"kitchen.yaml: order-mix window 0: end-seconds has to be greater than start-seconds",
+		"kitchen.yaml: order-mix window 0: unknown menu item soup",
+		"kitchen.yaml: order-mix window 0: weight of soup has to be >= 0",
+		"kitchen.yaml: order-mix window 0: total weight has to be > 0",
+		"menu.json[2]: order ice cream: there is no shelf for temp frozen",
+	}
+
+	assert.Equal(t, len(expected), len(problems), problems.Error())
+	for i, problem := range problems {
+		assert.Equal(t, expected[i], problem.Error(), "should be equal")
+	}
+}
+
+func TestLoadScenario(t *testing.T) {
+	sc, err := LoadScenario(&SimulationConfig{})
+	assert.Equal(t, nil, err, "should be equal")
+	assert.Equal(t, 0, len(sc.Events), "scenario is optional")
+
+	sc, err = LoadScenario(&SimulationConfig{
+		ScenarioPath: "./../../fixtures/scenario.yaml",
+	})
+	assert.Equal(t, nil, err, "should be equal")
+	assert.Equal(t, 3, len(sc.Events), "should be equal")
+	assert.Equal(t, "8", sc.Events[0].Value, "should be equal")
+	assert.Equal(t, 5, *sc.Events[1].Capacity, "should be equal")
+	assert.Equal(t, 900.0, sc.Events[2].UntilSeconds, "should be equal")
+
+	_, err = LoadScenario(&SimulationConfig{
+		ScenarioPath: "./../../fixtures/orders.yaml",
+	})
+	assert.Equal(t, true, err != nil &&
+		strings.HasPrefix(err.Error(), "unable to parse scenario"),
+		"orders are not scenario")
+}
+
+func TestValidateScenario(t *testing.T) {
+	cfg := &SimulationConfig{
+		ScenarioPath: "scenario.yaml",
+		OrdersConfig: OrdersConfig{
+			OrdersPerSecond:    1,
+			DeliveryMinSeconds: 2,
+			DeliveryMaxSeconds: 6,
+		},
+	}
+
+	shelves := []*shvs.Shelf{
+		{Name: "hot", Temp: "hot", Capacity: 1},
+		{Name: "overflow", Temp: shvs.OverflowShelfTemp, Capacity: 1},
+	}
+
+	capacity := 5
+	negative := -1
+	sc := &scenario.Scenario{
+		Events: []*scenario.Event{
+			{AtSeconds: 10, Setting: "orders-config.delivery-min-seconds", Value: "4"},
+			// applied after the previous one, so min is greater than max
+			{AtSeconds: 20, Setting: "orders-config.delivery-max-seconds", Value: "3"},
+			{AtSeconds: 0, Setting: "rack-config.strategy", Value: "optimizer"},
+			{AtSeconds: 0, Setting: "orders-config.orders-per-second", Value: "many"},
+			{AtSeconds: 0, Shelf: "hot", Capacity: &capacity},
+			{AtSeconds: 0, Shelf: "cold", Capacity: &capacity},
+			{AtSeconds: 0, Shelf: "hot", Capacity: &negative},
+			{AtSeconds: 0, Shelf: "hot"},
+			{AtSeconds: 30, UntilSeconds: 20, CourierDelaySeconds: 5},
+			{AtSeconds: -1, Shelf: "hot", Capacity: &capacity, CourierDelaySeconds: 5},
+			{AtSeconds: 0, UntilSeconds: 20, Setting: "orders-config.orders-per-second", Value: "2"},
+			{AtSeconds: 40, CourierDelaySeconds: -2},
+		},
+	}
+
+	problems := ValidateScenario(cfg, shelves, sc)
+
+	// events are checked in the order of their time
+	expected := []string{
+		"scenario.yaml[9]: at-seconds has to be >= 0",
+		"scenario.yaml[9]: event has to define exactly one of setting, shelf capacity or courier-delay-seconds",
+		"scenario.yaml[2]: setting rack-config.strategy can't be changed while simulation runs, supported: " +
+			strings.Join(LiveSettings, ", "),
+		"scenario.yaml[3]: invalid value \"many\" of orders-config.orders-per-second: strconv.ParseInt: parsing \"many\": invalid syntax",
+		"scenario.yaml[5]: unknown shelf cold",
+		"scenario.yaml[6]: shelf hot: capacity has to be set >= 0",
+		"scenario.yaml[7]: capacity of shelf hot has to be set",
+		"scenario.yaml[10]: until-seconds is used with courier-delay-seconds only",
+		"scenario.yaml[1]: config is not valid after the event: delivery-min-seconds 4 is greater than delivery-max-seconds 3",
+		"scenario.yaml[8]: until-seconds has to be greater than at-seconds",
+		"scenario.yaml[11]: courier-delay-seconds has to be >= 0",
+	}
+
+	assert.Equal(t, len(expected), len(problems), problems.Error())
+	for i, problem := range problems {
+		assert.Equal(t, expected[i], problem.Error(), "should be equal")
+	}
+}
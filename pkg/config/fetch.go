@@ -0,0 +1,170 @@
+package config
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// EnvSourceToken is the env variable containing bearer token
+	// sent with the http(s) requests for shelves and orders
+	EnvSourceToken = "KITCHEN_SOURCE_TOKEN"
+	// EnvSourceUsername is the env variable containing basic auth
+	// username sent with the http(s) requests for shelves and orders
+	EnvSourceUsername = "KITCHEN_SOURCE_USERNAME"
+	// EnvSourcePassword is the env variable containing basic auth
+	// password sent with the http(s) requests for shelves and orders
+	EnvSourcePassword = "KITCHEN_SOURCE_PASSWORD"
+
+	defaultFetchTimeoutSeconds = 10
+	defaultFetchRetries        = 2
+	defaultFetchBackoffSeconds = 1
+
+	// errorBodySnippet is the max length of the response body
+	// shown in the error
+	errorBodySnippet = 200
+)
+
+// FetchConfig defines how shelves and orders are fetched by the link.
+// Defaults are set by DefaultSimulationConfig, so explicit 0 is kept:
+// 0 timeout disables the timeout, 0 retries disables retrying
+type FetchConfig struct {
+	TimeoutSeconds float64 `yaml:"timeout-seconds" description:"Timeout of a single http(s) request, seconds"`
+	Retries        int     `yaml:"retries" description:"Number of retries of failed http(s) request"`
+	BackoffSeconds float64 `yaml:"backoff-seconds" description:"Delay before the first retry, doubled for every next one, seconds"`
+}
+
+// defaultFetchConfig returns fetch settings used in case they are
+// not set by the config file or overrides
+func defaultFetchConfig() FetchConfig {
+	return FetchConfig{
+		TimeoutSeconds: defaultFetchTimeoutSeconds,
+		Retries:        defaultFetchRetries,
+		BackoffSeconds: defaultFetchBackoffSeconds,
+	}
+}
+
+// fetch reads the document located by the local path, file:// or
+// http(s):// link. It returns content of the document and its type
+// (empty for the files). Gzip compressed content is decompressed
+func fetch(location string, cfg FetchConfig) ([]byte, string, error) {
+	u, err := url.Parse(location)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "file") {
+		// everything that is not a link is a local path
+		b, err := ioutil.ReadFile(location)
+		if err != nil {
+			return nil, "", errors.Wrap(err, "unable to read file")
+		}
+		b, err = gunzip(b)
+		return b, "", err
+	}
+
+	if u.Scheme == "file" {
+		b, err := ioutil.ReadFile(u.Path)
+		if err != nil {
+			return nil, "", errors.Wrap(err, "unable to read file")
+		}
+		b, err = gunzip(b)
+		return b, "", err
+	}
+
+	client := &http.Client{
+		Timeout: time.Duration(cfg.TimeoutSeconds * float64(time.Second)),
+	}
+
+	backoff := time.Duration(cfg.BackoffSeconds * float64(time.Second))
+	for attempt := 0; ; attempt++ {
+		b, contentType, retriable, err := fetchHTTP(client, location)
+		if err == nil {
+			return b, contentType, nil
+		}
+
+		if !retriable {
+			return nil, "", err
+		}
+		if attempt >= cfg.Retries {
+			return nil, "", errors.Wrap(err,
+				fmt.Sprintf("unable to fetch after %d attempts", attempt+1))
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// fetchHTTP does single http(s) request of the document. In case of
+// failure it returns whether request may succeed later
+func fetchHTTP(client *http.Client, location string) (b []byte, contentType string,
+	retriable bool, err error) {
+	req, err := http.NewRequest(http.MethodGet, location, nil)
+	if err != nil {
+		return nil, "", false, errors.Wrap(err, "unable to create request")
+	}
+
+	if token := os.Getenv(EnvSourceToken); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else if username := os.Getenv(EnvSourceUsername); username != "" {
+		req.SetBasicAuth(username, os.Getenv(EnvSourcePassword))
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, "", true, errors.Wrap(err, "unable to fetch")
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, "", true, errors.Wrap(err, "unable to read http responce body")
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		snippet := strings.TrimSpace(string(body))
+		if len(snippet) > errorBodySnippet {
+			snippet = snippet[:errorBodySnippet] + "..."
+		}
+		// server errors and rate limiting may be temporary
+		retriable = res.StatusCode >= 500 ||
+			res.StatusCode == http.StatusTooManyRequests
+		return nil, "", retriable, errors.New(fmt.Sprintf(
+			"unexpected response status %s: %s", res.Status, snippet))
+	}
+
+	contentType = res.Header.Get("Content-Type")
+	if strings.HasPrefix(contentType, "text/html") {
+		return nil, "", false, errors.New(fmt.Sprintf(
+			"unexpected content type %s", contentType))
+	}
+
+	body, err = gunzip(body)
+	return body, contentType, false, err
+}
+
+// gunzip decompresses content in case it is gzip compressed
+func gunzip(b []byte) ([]byte, error) {
+	if !bytes.HasPrefix(b, []byte{0x1f, 0x8b}) {
+		return b, nil
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to decompress gzip")
+	}
+	defer r.Close()
+
+	b, err = ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to decompress gzip")
+	}
+
+	return b, nil
+}
@@ -0,0 +1,156 @@
+package config
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testOrders = `[{"id": "1", "name": "Cheese Pizza", "temp": "hot", "shelfLife": 300, "decayRate": 0.45}]`
+
+func TestFetchRemote(t *testing.T) {
+	var gzipped bytes.Buffer
+	w := gzip.NewWriter(&gzipped)
+	if _, err := w.Write([]byte(testOrders)); err != nil {
+		t.Fatalf("unable to compress orders: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unable to compress orders: %v", err)
+	}
+
+	tests := []struct {
+		handler  func(attempt int, rw http.ResponseWriter, req *http.Request)
+		attempts int
+		err      string
+	}{
+		// retried after server error
+		{
+			handler: func(attempt int, rw http.ResponseWriter, req *http.Request) {
+				if attempt == 0 {
+					rw.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				fmt.Fprint(rw, testOrders)
+			},
+			attempts: 2,
+		},
+		// html error page is reported with status
+		{
+			handler: func(attempt int, rw http.ResponseWriter, req *http.Request) {
+				rw.Header().Set("Content-Type", "text/html")
+				rw.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(rw, "<html>not found</html>")
+			},
+			attempts: 1,
+			err:      "unexpected response status 404 Not Found: <html>not found</html>",
+		},
+		// html page with success status is not parsed
+		{
+			handler: func(attempt int, rw http.ResponseWriter, req *http.Request) {
+				rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+				fmt.Fprint(rw, "<html>login</html>")
+			},
+			attempts: 1,
+			err:      "unexpected content type text/html",
+		},
+		// gzip compressed body
+		{
+			handler: func(attempt int, rw http.ResponseWriter, req *http.Request) {
+				rw.Header().Set("Content-Type", "application/gzip")
+				if _, err := rw.Write(gzipped.Bytes()); err != nil {
+					t.Errorf("unable to write rsp data: %v", err)
+				}
+			},
+			attempts: 1,
+		},
+		// bearer token from env
+		{
+			handler: func(attempt int, rw http.ResponseWriter, req *http.Request) {
+				if req.Header.Get("Authorization") != "Bearer secret" {
+					rw.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				fmt.Fprint(rw, testOrders)
+			},
+			attempts: 1,
+		},
+	}
+
+	os.Setenv(EnvSourceToken, "secret")
+	defer os.Unsetenv(EnvSourceToken)
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("fetch_remote_%d", i),
+			func(t *testing.T) {
+				attempts := 0
+				server := httptest.NewServer(http.HandlerFunc(
+					func(rw http.ResponseWriter, req *http.Request) {
+						test.handler(attempts, rw, req)
+						attempts++
+					}))
+				defer server.Close()
+
+				opts, err := FetchOrdersWith(server.URL+"/orders.json", FetchConfig{
+					TimeoutSeconds: 1,
+					Retries:        2,
+					BackoffSeconds: 0.01,
+				})
+				assert.Equal(t, test.attempts, attempts, "should be equal")
+				if test.err != "" {
+					assert.Contains(t, fmt.Sprintf("%v", err), test.err)
+					return
+				}
+				assert.Nil(t, err, "should not fail")
+				assert.Equal(t, 1, len(opts), "should be equal")
+			})
+	}
+}
+
+func TestFetchWithoutRetries(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(
+		func(rw http.ResponseWriter, req *http.Request) {
+			attempts++
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		}))
+	defer server.Close()
+
+	// explicit 0 disables retrying
+	cfg, err := LoadSimulationConfig("not-found.yaml", false, map[string]string{
+		"fetch.retries": "0",
+	})
+	assert.Nil(t, err, "should be nil")
+	assert.Equal(t, 0, cfg.Fetch.Retries, "should be equal")
+
+	_, err = FetchOrdersWith(server.URL+"/orders.json", cfg.Fetch)
+	assert.NotNil(t, err, "should fail")
+	assert.Equal(t, 1, attempts, "should be equal")
+}
+
+func TestFetchLocal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kitchen")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// local path containing "http" is not a link
+	path := filepath.Join(dir, "http-orders.json")
+	if err := ioutil.WriteFile(path, []byte(testOrders), 0600); err != nil {
+		t.Fatalf("unable to write orders: %v", err)
+	}
+
+	for _, location := range []string{path, "file://" + path} {
+		opts, err := FetchOrders(location)
+		assert.Nil(t, err, "should not fail for %s", location)
+		assert.Equal(t, 1, len(opts), "should be equal")
+	}
+}
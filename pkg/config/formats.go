@@ -0,0 +1,176 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/bgzzz/kitchen/pkg/schema"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// Supported formats of the shelves and orders files
+const (
+	FormatJSON   = "json"
+	FormatYAML   = "yaml"
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+)
+
+// formatOf returns format of the file based on its content type
+// (in case it is known) or extension. JSON is the default format
+func formatOf(path, contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/json":
+		return FormatJSON
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		return FormatYAML
+	case "application/x-ndjson", "application/ndjson", "application/jsonl",
+		"application/x-jsonlines":
+		return FormatNDJSON
+	case "text/csv":
+		return FormatCSV
+	}
+
+	// query part of the link is not the part of the extension
+	if i := strings.IndexAny(path, "?#"); i >= 0 {
+		path = path[:i]
+	}
+	// compressed file has the format of the inner extension
+	path = strings.TrimSuffix(path, ".gz")
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".ndjson", ".jsonl":
+		return FormatNDJSON
+	case ".csv":
+		return FormatCSV
+	}
+
+	return FormatJSON
+}
+
+// decode parses list document of the supplied format, checks it
+// against the schema and puts it into v
+func decode(b []byte, format string, s *schema.Schema, v interface{}) error {
+	var doc interface{}
+	var err error
+
+	switch format {
+	case FormatYAML:
+		err = yaml.Unmarshal(b, &doc)
+		doc = normalizeYAML(doc)
+	case FormatNDJSON:
+		doc, err = decodeNDJSON(b)
+	case FormatCSV:
+		doc, err = decodeCSV(b, s.Items)
+	default:
+		err = json.Unmarshal(b, &doc)
+	}
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("unable to parse %s", format))
+	}
+
+	if err := schema.Validate(s, doc); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("%s does not match schema", s.Title))
+	}
+
+	// document is already checked, so it is put into structures
+	// via JSON as the common representation
+	nb, err := json.Marshal(doc)
+	if err != nil {
+		return errors.Wrap(err, "unable to convert document")
+	}
+
+	return json.Unmarshal(nb, v)
+}
+
+// decodeNDJSON parses newline delimited JSON objects into the list,
+// empty lines are skipped
+func decodeNDJSON(b []byte) ([]interface{}, error) {
+	doc := []interface{}{}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var record interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("line %d", line))
+		}
+		doc = append(doc, record)
+	}
+
+	return doc, scanner.Err()
+}
+
+// decodeCSV parses CSV with the header row into the list of objects.
+// Cells are converted according to the type of the schema property
+// named as the column, empty cells are omitted. Nested lists
+// (ex: items of multi-item order) are not supported by CSV
+func decodeCSV(b []byte, item *schema.Schema) ([]interface{}, error) {
+	records, err := csv.NewReader(bytes.NewReader(b)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	doc := []interface{}{}
+	if len(records) == 0 {
+		return doc, nil
+	}
+
+	header := records[0]
+	for i, record := range records[1:] {
+		obj := map[string]interface{}{}
+		for j, cell := range record {
+			cell = strings.TrimSpace(cell)
+			if cell == "" {
+				continue
+			}
+			column := strings.TrimSpace(header[j])
+			value, err := csvValue(cell, item.Properties[column])
+			if err != nil {
+				return nil, errors.Wrap(err,
+					fmt.Sprintf("row %d, column %s", i+1, column))
+			}
+			obj[column] = value
+		}
+		doc = append(doc, obj)
+	}
+
+	return doc, nil
+}
+
+// csvValue converts CSV cell to the value of the property type,
+// unknown columns are kept as strings to be reported by the schema
+func csvValue(cell string, property *schema.Schema) (interface{}, error) {
+	if property == nil {
+		return cell, nil
+	}
+
+	switch property.Type {
+	case "integer":
+		return strconv.ParseInt(cell, 10, 64)
+	case "number":
+		return strconv.ParseFloat(cell, 64)
+	case "boolean":
+		return strconv.ParseBool(cell)
+	case "array", "object":
+		return nil, errors.New(fmt.Sprintf("%s values are not supported in csv",
+			property.Type))
+	}
+
+	return cell, nil
+}
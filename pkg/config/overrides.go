@@ -0,0 +1,184 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// EnvPrefix is the prefix of the environment variables overriding
+// simulation config settings
+const EnvPrefix = "KITCHEN_"
+
+// Field is the simulation config setting that can be overridden
+type Field struct {
+	// Key is the path of yaml keys joined by dots,
+	// ex: orders-config.orders-per-second
+	Key string
+	// Env is the environment variable overriding the setting,
+	// ex: KITCHEN_ORDERS_CONFIG_ORDERS_PER_SECOND
+	Env         string
+	Description string
+	// Bool is true in case the setting is on or off, it is
+	// overridden by the flag without value
+	Bool bool
+}
+
+// DefaultSimulationConfig returns settings used in case they are
+// not set by the config file or overrides
+func DefaultSimulationConfig() *SimulationConfig {
+	return &SimulationConfig{
+		ShelvesFilePath: "./shelves.json",
+		OrdersPath:      "./orders.json",
+		OrdersConfig: OrdersConfig{
+			OrdersPerSecond:    2,
+			DeliveryMinSeconds: 2,
+			DeliveryMaxSeconds: 6,
+		},
+		RackConfig: RackConfig{
+			Strategy:           StrategyDefault,
+			RemovedShelfPolicy: RemovedShelfMigrate,
+		},
+		Fetch: defaultFetchConfig(),
+		OrdersSource: OrdersSourceConfig{
+			Broker: BrokerConfig{
+				MaxPending: 64,
+			},
+		},
+		Sampling: SamplingConfig{
+			IntervalSeconds: 1,
+		},
+		Routing: RoutingConfig{
+			Policy: RoutingRoundRobin,
+		},
+	}
+}
+
+// LoadSimulationConfig returns effective simulation config: defaults
+// overridden by the config file overridden by the supplied values
+// (keyed as Field.Key). Missing config file is not an error in case
+// it is not required
+func LoadSimulationConfig(configFilePath string, required bool,
+	overrides map[string]string) (*SimulationConfig, error) {
+	cfg := DefaultSimulationConfig()
+
+	b, err := ioutil.ReadFile(configFilePath)
+	if err != nil && (required || !os.IsNotExist(err)) {
+		return nil, errors.Wrap(err, "unable to read config file")
+	}
+
+	if err == nil {
+		// file values are put on top of the defaults
+		if err := yaml.Unmarshal(b, cfg); err != nil {
+			return nil, errors.Wrap(err, "unable to parse yaml")
+		}
+
+		if err := validateYAML(SimulationConfigSchema, b); err != nil {
+			return nil, err
+		}
+	}
+
+	for key, value := range overrides {
+		if err := Set(cfg, key, value); err != nil {
+			return nil, err
+		}
+	}
+
+	return cfg, nil
+}
+
+// Fields returns all settings of the simulation config that can
+// be overridden. Inline shelves and orders are not among them
+func Fields() []Field {
+	return fields(reflect.TypeOf(SimulationConfig{}), "")
+}
+
+func fields(t reflect.Type, prefix string) []Field {
+	fs := []Field{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := prefix + yamlName(field)
+
+		switch field.Type.Kind() {
+		case reflect.Struct:
+			fs = append(fs, fields(field.Type, key+".")...)
+		case reflect.Slice:
+			continue
+		default:
+			fs = append(fs, Field{
+				Key:         key,
+				Env:         envName(key),
+				Description: field.Tag.Get("description"),
+				Bool:        field.Type.Kind() == reflect.Bool,
+			})
+		}
+	}
+	return fs
+}
+
+// Set sets the setting of the config by its key, value is parsed
+// according to the type of the setting
+func Set(cfg *SimulationConfig, key, value string) error {
+	v := reflect.ValueOf(cfg).Elem()
+	for _, name := range strings.Split(key, ".") {
+		if v.Kind() != reflect.Struct {
+			return errors.New(fmt.Sprintf("unknown setting %s", key))
+		}
+		found := false
+		for i := 0; i < v.NumField(); i++ {
+			if yamlName(v.Type().Field(i)) == name {
+				v = v.Field(i)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.New(fmt.Sprintf("unknown setting %s", key))
+		}
+	}
+
+	var err error
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Int, reflect.Int64:
+		var i int64
+		i, err = strconv.ParseInt(value, 10, 64)
+		v.SetInt(i)
+	case reflect.Float64:
+		var f float64
+		f, err = strconv.ParseFloat(value, 64)
+		v.SetFloat(f)
+	case reflect.Bool:
+		var b bool
+		b, err = strconv.ParseBool(value)
+		v.SetBool(b)
+	default:
+		return errors.New(fmt.Sprintf("setting %s can't be overridden", key))
+	}
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("invalid value %q of %s", value, key))
+	}
+
+	return nil
+}
+
+// yamlName returns yaml key of the structure field
+func yamlName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+	if name == "" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
+
+// envName returns environment variable name of the setting key
+func envName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/bgzzz/kitchen/pkg/menu"
+	"github.com/bgzzz/kitchen/pkg/orders"
+	"github.com/bgzzz/kitchen/pkg/scenario"
+	"github.com/bgzzz/kitchen/pkg/schema"
+	shvs "github.com/bgzzz/kitchen/pkg/shelves"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// Schemas of the simulation files generated from the structures
+// they are parsed into
+var (
+	SimulationConfigSchema = schema.Generate("simulation config",
+		SimulationConfig{}, "yaml")
+	ShelvesSchema = schema.Generate("shelves",
+		[]*shvs.Shelf{}, "json")
+	OrdersSchema = schema.Generate("orders",
+		[]*orders.OrderOptions{}, "json")
+	MenuSchema = schema.Generate("menu",
+		[]*menu.Item{}, "json")
+	ScenarioSchema = schema.Generate("scenario",
+		scenario.Scenario{}, "yaml")
+)
+
+// Schemas returns schemas of the simulation files by their kind
+func Schemas() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"simulation-config": SimulationConfigSchema,
+		"shelves":           ShelvesSchema,
+		"orders":            OrdersSchema,
+		"menu":              MenuSchema,
+		"scenario":          ScenarioSchema,
+	}
+}
+
+// validateYAML checks YAML document against the schema
+func validateYAML(s *schema.Schema, b []byte) error {
+	var doc interface{}
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return errors.Wrap(err, "unable to parse yaml")
+	}
+
+	// empty document is the empty object
+	if doc == nil {
+		doc = map[interface{}]interface{}{}
+	}
+
+	if err := schema.Validate(s, normalizeYAML(doc)); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("%s does not match schema", s.Title))
+	}
+
+	return nil
+}
+
+// normalizeYAML converts decoded YAML document to the form
+// JSON documents are decoded to
+func normalizeYAML(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := map[string]interface{}{}
+		for key, value := range t {
+			m[fmt.Sprintf("%v", key)] = normalizeYAML(value)
+		}
+		return m
+	case []interface{}:
+		for i, value := range t {
+			t[i] = normalizeYAML(value)
+		}
+		return t
+	}
+	return v
+}
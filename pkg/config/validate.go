@@ -0,0 +1,799 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bgzzz/kitchen/pkg/menu"
+	"github.com/bgzzz/kitchen/pkg/orders"
+	"github.com/bgzzz/kitchen/pkg/scenario"
+	shvs "github.com/bgzzz/kitchen/pkg/shelves"
+	"github.com/pkg/errors"
+)
+
+// ValidationError is the problem found in the simulation setup
+type ValidationError struct {
+	// File is the file problem is found in, empty for the problems
+	// of the whole setup
+	File string
+	// Index is the index of the shelf/order in the file,
+	// -1 in case problem does not concern the list entry
+	Index int
+	Err   error
+}
+
+// Error returns problem description with its context
+func (ve *ValidationError) Error() string {
+	if ve.File == "" {
+		return ve.Err.Error()
+	}
+	if ve.Index < 0 {
+		return fmt.Sprintf("%s: %s", ve.File, ve.Err.Error())
+	}
+	return fmt.Sprintf("%s[%d]: %s", ve.File, ve.Index, ve.Err.Error())
+}
+
+// ValidationErrors is the list of problems found in the simulation setup
+type ValidationErrors []*ValidationError
+
+// Error returns all problems one per line
+func (ves ValidationErrors) Error() string {
+	problems := []string{}
+	for _, ve := range ves {
+		problems = append(problems, ve.Error())
+	}
+	return strings.Join(problems, "\n")
+}
+
+// inFile sets the file context of the problems
+func (ves ValidationErrors) inFile(file string) ValidationErrors {
+	for _, ve := range ves {
+		ve.File = file
+	}
+	return ves
+}
+
+// orNil returns nil in case there are no problems. It is needed
+// to not return non nil error interface holding empty list
+func (ves ValidationErrors) orNil() error {
+	if len(ves) == 0 {
+		return nil
+	}
+	return ves
+}
+
+func validateShelf(shelf *shvs.Shelf) error {
+	if shelf.Name == "" {
+		return errors.New("name of shelf can't be empty")
+	}
+	if shelf.Capacity < 0 {
+		return errors.New(fmt.Sprintf("shelf %s: capacity has to be set >= 0",
+			shelf.Name))
+	}
+	if shelf.ShelfDecayModifier < 0 {
+		return errors.New(fmt.Sprintf("shelf %s: shelf decay modifier has to be integer >= 0",
+			shelf.Name))
+	}
+	return nil
+}
+
+func validateOrderOptions(opts *orders.OrderOptions) error {
+	if opts.ID == "" {
+		return errors.New("ID of order can't be empty")
+	}
+	if opts.Name == "" {
+		return errors.New("Name of order can't be empty")
+	}
+	if opts.Priority < 0 {
+		return errors.New(fmt.Sprintf("order %s: priority < 0", opts.ID))
+	}
+	if opts.Price < 0 {
+		return errors.New(fmt.Sprintf("order %s: price < 0", opts.ID))
+	}
+	if opts.Cost < 0 {
+		return errors.New(fmt.Sprintf("order %s: cost < 0", opts.ID))
+	}
+	if opts.ArrivalOffset != nil && *opts.ArrivalOffset < 0 {
+		return errors.New(fmt.Sprintf("order %s: arrival offset < 0", opts.ID))
+	}
+	if opts.ArrivalTime != "" {
+		if opts.ArrivalOffset != nil {
+			return errors.New(fmt.Sprintf("order %s: only one of arrival offset and arrival time can be set",
+				opts.ID))
+		}
+		if _, err := time.Parse(time.RFC3339Nano, opts.ArrivalTime); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("order %s: arrival time is not RFC3339", opts.ID))
+		}
+	}
+	if opts.CourierOffset != nil && *opts.CourierOffset < 0 {
+		return errors.New(fmt.Sprintf("order %s: courier offset < 0", opts.ID))
+	}
+	if len(opts.Items) != 0 {
+		for i, item := range opts.Items {
+			if err := validateItemOptions(item); err != nil {
+				return errors.Wrap(err, fmt.Sprintf("order %s: item %d", opts.ID, i))
+			}
+		}
+		return nil
+	}
+	if opts.ShelfLife <= 0 {
+		return errors.New(fmt.Sprintf("order %s: shelf life <= 0", opts.ID))
+	}
+	if opts.DecayRate < 0 {
+		return errors.New(fmt.Sprintf("order %s: decay rate < 0", opts.ID))
+	}
+	return nil
+}
+
+func validateItemOptions(item *orders.ItemOptions) error {
+	if item.Name == "" {
+		return errors.New("Name of item can't be empty")
+	}
+	if item.ShelfLife <= 0 {
+		return errors.New("shelf life <= 0")
+	}
+	if item.DecayRate < 0 {
+		return errors.New("decay rate < 0")
+	}
+	return nil
+}
+
+// validateShelves returns all problems of the shelf list
+func validateShelves(shelves []*shvs.Shelf) ValidationErrors {
+	problems := ValidationErrors{}
+
+	shelvesTempMap := map[string]struct{}{}
+	shelvesNameMap := map[string]struct{}{}
+
+	for i, shelf := range shelves {
+		if _, ok := shelvesTempMap[shelf.Temp]; ok {
+			problems = append(problems, &ValidationError{
+				Index: i,
+				Err: errors.New(fmt.Sprintf("shelf %s: shelf with this temp was already defined",
+					shelf.Name)),
+			})
+		}
+		if _, ok := shelvesNameMap[shelf.Name]; ok {
+			problems = append(problems, &ValidationError{
+				Index: i,
+				Err: errors.New(fmt.Sprintf("shelf %s: shelf with this name was already defined",
+					shelf.Name)),
+			})
+		}
+		if err := validateShelf(shelf); err != nil {
+			problems = append(problems, &ValidationError{
+				Index: i,
+				Err:   errors.Wrap(err, "shelf definition is not valid"),
+			})
+		}
+		shelvesTempMap[shelf.Temp] = struct{}{}
+		shelvesNameMap[shelf.Name] = struct{}{}
+	}
+
+	return problems
+}
+
+// validateOrderOptionsList returns all problems of the order list
+func validateOrderOptionsList(orderOptions []*orders.OrderOptions) ValidationErrors {
+	problems := ValidationErrors{}
+
+	orderIDMap := map[string]struct{}{}
+
+	for i, opts := range orderOptions {
+		if _, ok := orderIDMap[opts.ID]; ok {
+			problems = append(problems, &ValidationError{
+				Index: i,
+				Err: errors.New(fmt.Sprintf("order with id %s was previously defined",
+					opts.ID)),
+			})
+		}
+		if err := validateOrderOptions(opts); err != nil {
+			problems = append(problems, &ValidationError{
+				Index: i,
+				Err:   errors.Wrap(err, "order defintion is not valid"),
+			})
+		}
+		orderIDMap[opts.ID] = struct{}{}
+	}
+
+	return problems
+}
+
+// ValidateShelves validates supplied shelves structures
+// return non nil error (ValidationErrors) containing all problems
+// in case of invalid shelflist
+func ValidateShelves(shelves []*shvs.Shelf) error {
+	return validateShelves(shelves).orNil()
+}
+
+// ValidateOrderOptions validates order option list
+// return non nil error (ValidationErrors) containing all problems
+// in case of non valid order options list
+func ValidateOrderOptions(orderOptions []*orders.OrderOptions) error {
+	return validateOrderOptionsList(orderOptions).orNil()
+}
+
+// OrderValidator returns the check of the single order read while
+// simulation runs on the supplied shelves
+func OrderValidator(shelves []*shvs.Shelf) func(*orders.OrderOptions) error {
+	return func(opts *orders.OrderOptions) error {
+		if err := validateOrderOptions(opts); err != nil {
+			return errors.Wrap(err, "order defintion is not valid")
+		}
+		_, problems := validateSetup(shelves, []*orders.OrderOptions{opts})
+		if len(problems) != 0 {
+			return problems[0].Err
+		}
+		return nil
+	}
+}
+
+// validateSimulationConfig returns all problems of the simulation config
+func validateSimulationConfig(cfg *SimulationConfig) ValidationErrors {
+	problems := ValidationErrors{}
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, &ValidationError{
+			Index: -1,
+			Err:   errors.New(fmt.Sprintf(format, args...)),
+		})
+	}
+
+	ordersCfg := cfg.OrdersConfig
+	if ordersCfg.OrdersPerSecond <= 0 {
+		add("orders-per-second has to be > 0")
+	}
+	if ordersCfg.DeliveryMinSeconds < 0 {
+		add("delivery-min-seconds has to be >= 0")
+	}
+	if ordersCfg.DeliveryMinSeconds > ordersCfg.DeliveryMaxSeconds {
+		add("delivery-min-seconds %v is greater than delivery-max-seconds %v",
+			ordersCfg.DeliveryMinSeconds, ordersCfg.DeliveryMaxSeconds)
+	}
+
+	rackCfg := cfg.RackConfig
+	if rackCfg.RebalanceIntervalSeconds < 0 {
+		add("rebalance-interval-seconds has to be >= 0")
+	}
+	if rackCfg.Strategy != "" && rackCfg.Strategy != StrategyDefault &&
+		rackCfg.Strategy != StrategyOptimizer {
+		add("unknown strategy %s, supported: %s, %s", rackCfg.Strategy,
+			StrategyDefault, StrategyOptimizer)
+	}
+	if rackCfg.OptimizerBudget < 0 {
+		add("optimizer-budget has to be >= 0")
+	}
+	if rackCfg.RemovedShelfPolicy != "" &&
+		rackCfg.RemovedShelfPolicy != RemovedShelfMigrate &&
+		rackCfg.RemovedShelfPolicy != RemovedShelfWaste {
+		add("unknown removed-shelf-policy %s, supported: %s, %s",
+			rackCfg.RemovedShelfPolicy, RemovedShelfMigrate,
+			RemovedShelfWaste)
+	}
+
+	if cfg.Fetch.TimeoutSeconds < 0 || cfg.Fetch.Retries < 0 ||
+		cfg.Fetch.BackoffSeconds < 0 {
+		add("fetch settings have to be >= 0")
+	}
+
+	if cfg.OrdersSource.FollowIdleSeconds < 0 {
+		add("follow-idle-seconds has to be >= 0")
+	}
+
+	if broker := cfg.OrdersSource.Broker; broker.URL != "" {
+		u, err := url.Parse(broker.URL)
+		if err != nil || u.Scheme != "nats" || u.Host == "" {
+			add("broker url %q has to be nats://host:port", broker.URL)
+		}
+		if broker.Subject == "" {
+			add("broker subject is not set")
+		}
+		if broker.MaxPending <= 0 {
+			add("broker max-pending has to be > 0")
+		}
+	}
+
+	policies := map[string]bool{}
+	for _, policy := range RoutingPolicies {
+		policies[policy] = true
+	}
+	if cfg.Routing.Policy != "" && !policies[cfg.Routing.Policy] {
+		add("unknown routing policy %s, supported: %s", cfg.Routing.Policy,
+			strings.Join(RoutingPolicies, ", "))
+	}
+	kitchens := map[string]bool{}
+	for i, kc := range cfg.Kitchens {
+		if kitchens[kc.Name] {
+			add("kitchen %d: kitchen %s was previously defined", i, kc.Name)
+		}
+		kitchens[kc.Name] = true
+		if kc.Name == "" {
+			add("kitchen %d: name is not set", i)
+		}
+
+		kitchenOrders := kc.OrdersConfig(ordersCfg)
+		if kitchenOrders.DeliveryMinSeconds < 0 {
+			add("kitchen %d: delivery-min-seconds has to be >= 0", i)
+		}
+		if kitchenOrders.DeliveryMinSeconds > kitchenOrders.DeliveryMaxSeconds {
+			add("kitchen %d: delivery-min-seconds %v is greater than delivery-max-seconds %v",
+				i, kitchenOrders.DeliveryMinSeconds, kitchenOrders.DeliveryMaxSeconds)
+		}
+		if cfg.Routing.Policy == RoutingNearest && kc.Location == nil {
+			add("kitchen %d: location is required by the nearest routing policy", i)
+		}
+	}
+	// scenario and sampling concern the single rack
+	if len(cfg.Kitchens) != 0 && cfg.ScenarioPath != "" {
+		add("scenario-path can't be used with kitchens")
+	}
+	if len(cfg.Kitchens) != 0 && cfg.Sampling.Path != "" {
+		add("sampling path can't be used with kitchens")
+	}
+
+	if cfg.Economics.RefundThreshold < 0 || cfg.Economics.RefundThreshold > 1 {
+		add("refund-threshold has to be within [0, 1]")
+	}
+
+	if cfg.Sampling.Path != "" && cfg.Sampling.IntervalSeconds <= 0 {
+		add("sampling interval-seconds has to be > 0")
+	}
+
+	events := map[string]bool{}
+	for _, event := range WebhookEvents {
+		events[event] = true
+	}
+	for i, wh := range cfg.Webhooks {
+		u, err := url.Parse(wh.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("webhook %d: url %q has to be http(s) link", i, wh.URL)
+		}
+		for _, event := range wh.Events {
+			if !events[event] {
+				add("webhook %d: unknown event %s, supported: %s", i, event,
+					strings.Join(WebhookEvents, ", "))
+			}
+		}
+		if wh.TimeoutSeconds < 0 || (wh.Retries != nil && *wh.Retries < 0) ||
+			wh.BackoffSeconds < 0 {
+			add("webhook %d: timeout-seconds, retries and backoff-seconds have to be >= 0", i)
+		}
+	}
+
+	sinkEvents := map[string]bool{}
+	for _, event := range SinkEvents {
+		sinkEvents[event] = true
+	}
+	for i, sc := range cfg.Sinks {
+		switch sc.Type {
+		case SinkFile:
+			if sc.Path == "" {
+				add("sink %d: path of the file sink is not set", i)
+			}
+		case SinkHTTP:
+			u, err := url.Parse(sc.URL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				add("sink %d: url %q has to be http(s) link", i, sc.URL)
+			}
+		case SinkBroker:
+			u, err := url.Parse(sc.URL)
+			if err != nil || u.Scheme != "nats" || u.Host == "" {
+				add("sink %d: url %q has to be nats://host:port", i, sc.URL)
+			}
+			if sc.Subject == "" {
+				add("sink %d: subject of the broker sink is not set", i)
+			}
+		default:
+			add("sink %d: unknown type %s, supported: %s", i, sc.Type,
+				strings.Join(SinkTypes, ", "))
+		}
+		for _, event := range sc.Events {
+			if !sinkEvents[event] {
+				add("sink %d: unknown event %s, supported: %s", i, event,
+					strings.Join(SinkEvents, ", "))
+			}
+		}
+		if sc.Policy != "" && sc.Policy != SinkPolicyDrop &&
+			sc.Policy != SinkPolicyDropOldest && sc.Policy != SinkPolicyBlock {
+			add("sink %d: unknown policy %s, supported: %s", i, sc.Policy,
+				strings.Join(SinkPolicies, ", "))
+		}
+		if (sc.MaxSizeMB != nil && *sc.MaxSizeMB < 0) ||
+			(sc.MaxFiles != nil && *sc.MaxFiles < 0) || sc.TimeoutSeconds < 0 ||
+			(sc.Retries != nil && *sc.Retries < 0) || sc.BackoffSeconds < 0 ||
+			sc.BufferSize < 0 || sc.BatchSize < 0 || sc.FlushIntervalSeconds < 0 {
+			add("sink %d: sizes, counts and durations have to be >= 0", i)
+		}
+	}
+
+	return problems
+}
+
+// validateSetup returns problems of shelves and orders considered
+// together: orders have to be possible to put on the rack
+func validateSetup(shelves []*shvs.Shelf, orderOptions []*orders.OrderOptions) (
+	shelfProblems ValidationErrors, orderProblems ValidationErrors) {
+
+	capacity := map[string]int{}
+	for _, shelf := range shelves {
+		capacity[shelf.Temp] = shelf.Capacity
+	}
+
+	overflowCapacity, ok := capacity[shvs.OverflowShelfTemp]
+	if !ok {
+		shelfProblems = append(shelfProblems, &ValidationError{
+			Index: -1,
+			Err: errors.New(fmt.Sprintf("overflow shelf (temp %s) is not defined",
+				shvs.OverflowShelfTemp)),
+		})
+	}
+
+	for i, opts := range orderOptions {
+		temps := []string{opts.Temp}
+		if len(opts.Items) != 0 {
+			temps = []string{}
+			for _, item := range opts.Items {
+				temps = append(temps, item.Temp)
+			}
+		}
+
+		for _, temp := range temps {
+			shelfCapacity, ok := capacity[temp]
+			if !ok {
+				orderProblems = append(orderProblems, &ValidationError{
+					Index: i,
+					Err: errors.New(fmt.Sprintf("order %s: there is no shelf for temp %s",
+						opts.ID, temp)),
+				})
+				continue
+			}
+			if shelfCapacity+overflowCapacity <= 0 {
+				orderProblems = append(orderProblems, &ValidationError{
+					Index: i,
+					Err: errors.New(fmt.Sprintf("order %s: shelf of temp %s and overflow shelf have no capacity, order can never be delivered",
+						opts.ID, temp)),
+				})
+			}
+		}
+	}
+
+	return
+}
+
+// Validate checks simulation config, shelves and orders together and
+// returns all found problems with the file/index context
+func Validate(cfgPath string, cfg *SimulationConfig, shelves []*shvs.Shelf,
+	orderOptions []*orders.OrderOptions) ValidationErrors {
+
+	shelvesFile := shelvesFileOf(cfgPath, cfg)
+	ordersFile := ordersFileOf(cfgPath, cfg)
+
+	problems := validateSimulationConfig(cfg).inFile(cfgPath)
+	problems = append(problems,
+		validateShelves(shelves).inFile(shelvesFile)...)
+	problems = append(problems,
+		validateOrderOptionsList(orderOptions).inFile(ordersFile)...)
+
+	shelfProblems, orderProblems := validateSetup(shelves, orderOptions)
+	problems = append(problems, shelfProblems.inFile(shelvesFile)...)
+	problems = append(problems, orderProblems.inFile(ordersFile)...)
+
+	return problems
+}
+
+// shelvesFileOf returns the file the simulation shelves are defined in
+func shelvesFileOf(cfgPath string, cfg *SimulationConfig) string {
+	if len(cfg.Shelves) != 0 {
+		return fmt.Sprintf("%s shelves", cfgPath)
+	}
+	return cfg.ShelvesFilePath
+}
+
+// ordersFileOf returns the file the orders are defined in
+func ordersFileOf(cfgPath string, cfg *SimulationConfig) string {
+	if len(cfg.Orders) != 0 {
+		return fmt.Sprintf("%s orders", cfgPath)
+	}
+	return cfg.OrdersPath
+}
+
+// ValidateKitchens checks simulation config, shelves of the kitchens
+// and orders together and returns all found problems with the
+// file/index context. Shelves are in the order of the kitchens, every
+// order has to fit the shelves of at least one kitchen
+func ValidateKitchens(cfgPath string, cfg *SimulationConfig,
+	shelves [][]*shvs.Shelf, orderOptions []*orders.OrderOptions) ValidationErrors {
+
+	ordersFile := ordersFileOf(cfgPath, cfg)
+
+	problems := validateSimulationConfig(cfg).inFile(cfgPath)
+	for i, kc := range cfg.Kitchens {
+		shelvesFile := kc.ShelvesFilePath
+		if len(kc.Shelves) != 0 {
+			shelvesFile = fmt.Sprintf("%s kitchen %s shelves", cfgPath, kc.Name)
+		} else if shelvesFile == "" {
+			shelvesFile = shelvesFileOf(cfgPath, cfg)
+		}
+
+		problems = append(problems,
+			validateShelves(shelves[i]).inFile(shelvesFile)...)
+		shelfProblems, _ := validateSetup(shelves[i], nil)
+		problems = append(problems, shelfProblems.inFile(shelvesFile)...)
+	}
+	problems = append(problems,
+		validateOrderOptionsList(orderOptions).inFile(ordersFile)...)
+	problems = append(problems,
+		validateRoutable(shelves, orderOptions).inFile(ordersFile)...)
+
+	return problems
+}
+
+// ValidateKitchensMenu checks simulation config, shelves of the
+// kitchens, menu and order mix together and returns all found
+// problems with the file/index context
+func ValidateKitchensMenu(cfgPath string, cfg *SimulationConfig,
+	shelves [][]*shvs.Shelf, items []*menu.Item) ValidationErrors {
+
+	problems := ValidateKitchens(cfgPath, cfg, shelves, nil)
+	problems = append(problems, validateMenu(items).inFile(cfg.MenuPath)...)
+	problems = append(problems, validateMix(&cfg.OrderMix, items).inFile(cfgPath)...)
+
+	menuOrders := []*orders.OrderOptions{}
+	for _, item := range items {
+		menuOrders = append(menuOrders, item.Order(item.Name))
+	}
+	problems = append(problems,
+		validateRoutable(shelves, menuOrders).inFile(cfg.MenuPath)...)
+
+	return problems
+}
+
+// validateRoutable returns problems of the orders that can't be put
+// on the shelves of any kitchen
+func validateRoutable(shelves [][]*shvs.Shelf,
+	orderOptions []*orders.OrderOptions) ValidationErrors {
+	problems := ValidationErrors{}
+	if len(shelves) == 0 {
+		return problems
+	}
+
+	// number of kitchens rejecting the order by its index
+	rejected := map[int]int{}
+	for _, kitchenShelves := range shelves {
+		_, orderProblems := validateSetup(kitchenShelves, orderOptions)
+		rejectedBy := map[int]bool{}
+		for _, problem := range orderProblems {
+			rejectedBy[problem.Index] = true
+		}
+		for i := range rejectedBy {
+			rejected[i]++
+		}
+	}
+
+	for i, opts := range orderOptions {
+		if rejected[i] == len(shelves) {
+			problems = append(problems, &ValidationError{
+				Index: i,
+				Err: errors.New(fmt.Sprintf("order %s can't be put on the shelves of any kitchen",
+					opts.ID)),
+			})
+		}
+	}
+	return problems
+}
+
+// KitchensOrderValidator returns the check of the single order read
+// while simulation of the kitchens runs, order has to fit the shelves
+// of at least one kitchen
+func KitchensOrderValidator(shelves [][]*shvs.Shelf) func(*orders.OrderOptions) error {
+	return func(opts *orders.OrderOptions) error {
+		var err error
+		for _, kitchenShelves := range shelves {
+			if err = OrderValidator(kitchenShelves)(opts); err == nil {
+				return nil
+			}
+		}
+		return errors.Wrap(err, "order can't be put on the shelves of any kitchen")
+	}
+}
+
+// validateMenu returns all problems of the menu items
+func validateMenu(items []*menu.Item) ValidationErrors {
+	problems := ValidationErrors{}
+
+	names := map[string]struct{}{}
+	for i, item := range items {
+		if _, ok := names[item.Name]; ok {
+			problems = append(problems, &ValidationError{
+				Index: i,
+				Err: errors.New(fmt.Sprintf("menu item %s was previously defined",
+					item.Name)),
+			})
+		}
+		// menu item is checked as the order named by the item
+		if err := validateOrderOptions(item.Order(item.Name)); err != nil {
+			problems = append(problems, &ValidationError{
+				Index: i,
+				Err:   errors.Wrap(err, "menu item defintion is not valid"),
+			})
+		}
+		names[item.Name] = struct{}{}
+	}
+
+	return problems
+}
+
+// validateMix returns all problems of the order mix
+func validateMix(mix *menu.Mix, items []*menu.Item) ValidationErrors {
+	problems := ValidationErrors{}
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, &ValidationError{
+			Index: -1,
+			Err:   errors.New(fmt.Sprintf(format, args...)),
+		})
+	}
+
+	names := map[string]struct{}{}
+	for _, item := range items {
+		names[item.Name] = struct{}{}
+	}
+
+	if mix.Count <= 0 {
+		add("order-mix count has to be > 0")
+	}
+	if len(mix.Windows) == 0 {
+		add("order-mix has to define at least one window")
+	}
+
+	for i, w := range mix.Windows {
+		if w.StartSeconds < 0 {
+			add("order-mix window %d: start-seconds has to be >= 0", i)
+		}
+		if w.EndSeconds != 0 && w.EndSeconds <= w.StartSeconds {
+			add("order-mix window %d: end-seconds has to be greater than start-seconds", i)
+		}
+
+		total := 0.0
+		for _, weight := range w.Weights {
+			if _, ok := names[weight.Item]; !ok {
+				add("order-mix window %d: unknown menu item %s", i, weight.Item)
+			}
+			if weight.Weight < 0 {
+				add("order-mix window %d: weight of %s has to be >= 0", i, weight.Item)
+			}
+			total += weight.Weight
+		}
+		if total <= 0 {
+			add("order-mix window %d: total weight has to be > 0", i)
+		}
+	}
+
+	return problems
+}
+
+// ValidateMenu checks simulation config, shelves, menu and order mix
+// together and returns all found problems with the file/index context
+func ValidateMenu(cfgPath string, cfg *SimulationConfig, shelves []*shvs.Shelf,
+	items []*menu.Item) ValidationErrors {
+
+	problems := Validate(cfgPath, cfg, shelves, nil)
+	problems = append(problems, validateMenu(items).inFile(cfg.MenuPath)...)
+	problems = append(problems, validateMix(&cfg.OrderMix, items).inFile(cfgPath)...)
+
+	menuOrders := []*orders.OrderOptions{}
+	for _, item := range items {
+		menuOrders = append(menuOrders, item.Order(item.Name))
+	}
+	_, itemProblems := validateSetup(shelves, menuOrders)
+	problems = append(problems, itemProblems.inFile(cfg.MenuPath)...)
+
+	return problems
+}
+
+// validateScenario returns all problems of the scenario events.
+// Setting changes are applied to the copy of the config in the order
+// of their time, config has to stay valid after every change
+func validateScenario(sc *scenario.Scenario, cfg *SimulationConfig,
+	shelves []*shvs.Shelf) ValidationErrors {
+	problems := ValidationErrors{}
+	add := func(i int, format string, args ...interface{}) {
+		problems = append(problems, &ValidationError{
+			Index: i,
+			Err:   errors.New(fmt.Sprintf(format, args...)),
+		})
+	}
+
+	live := map[string]struct{}{}
+	for _, key := range LiveSettings {
+		live[key] = struct{}{}
+	}
+	shelfByTemp := map[string]*shvs.Shelf{}
+	for _, shelf := range shelves {
+		shelfByTemp[shelf.Temp] = shelf
+	}
+
+	order := make([]int, len(sc.Events))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return sc.Events[order[i]].AtSeconds < sc.Events[order[j]].AtSeconds
+	})
+
+	changed := *cfg
+	for _, i := range order {
+		e := sc.Events[i]
+		if e.AtSeconds < 0 {
+			add(i, "at-seconds has to be >= 0")
+		}
+
+		actions := 0
+		if e.Setting != "" {
+			actions++
+		}
+		if e.Shelf != "" || e.Capacity != nil {
+			actions++
+		}
+		if e.IsDelay() {
+			actions++
+		}
+		if actions != 1 {
+			add(i, "event has to define exactly one of setting, shelf capacity or courier-delay-seconds")
+			continue
+		}
+
+		if !e.IsDelay() && e.UntilSeconds != 0 {
+			add(i, "until-seconds is used with courier-delay-seconds only")
+		}
+
+		switch {
+		case e.Setting != "":
+			if _, ok := live[e.Setting]; !ok {
+				add(i, "setting %s can't be changed while simulation runs, supported: %s",
+					e.Setting, strings.Join(LiveSettings, ", "))
+				continue
+			}
+			if err := Set(&changed, e.Setting, e.Value); err != nil {
+				problems = append(problems, &ValidationError{Index: i, Err: err})
+				continue
+			}
+			for _, problem := range validateSimulationConfig(&changed) {
+				add(i, "config is not valid after the event: %s", problem.Err.Error())
+			}
+		case e.IsDelay():
+			if e.CourierDelaySeconds < 0 {
+				add(i, "courier-delay-seconds has to be >= 0")
+			}
+			if e.UntilSeconds != 0 && e.UntilSeconds <= e.AtSeconds {
+				add(i, "until-seconds has to be greater than at-seconds")
+			}
+		default:
+			shelf, ok := shelfByTemp[e.Shelf]
+			if !ok {
+				add(i, "unknown shelf %s", e.Shelf)
+				continue
+			}
+			if e.Capacity == nil {
+				add(i, "capacity of shelf %s has to be set", e.Shelf)
+				continue
+			}
+			resized := *shelf
+			resized.Capacity = *e.Capacity
+			if err := validateShelf(&resized); err != nil {
+				problems = append(problems, &ValidationError{Index: i, Err: err})
+			}
+		}
+	}
+
+	return problems
+}
+
+// ValidateScenario checks scenario events against the simulation
+// config and shelves and returns all found problems with the
+// file/index context
+func ValidateScenario(cfg *SimulationConfig, shelves []*shvs.Shelf,
+	sc *scenario.Scenario) ValidationErrors {
+	return validateScenario(sc, cfg, shelves).inFile(cfg.ScenarioPath)
+}
@@ -0,0 +1,249 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package kitchenpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion7
+
+// KitchenClient is the client API for Kitchen service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type KitchenClient interface {
+	// SubmitOrder queues the order, it is put on the rack as soon as
+	// the simulation reads it. Call waits for room in the full queue
+	// until its deadline
+	SubmitOrder(ctx context.Context, in *SubmitOrderRequest, opts ...grpc.CallOption) (*SubmitOrderResponse, error)
+	// CancelOrder takes the order off the rack before its courier
+	// arrives
+	CancelOrder(ctx context.Context, in *CancelOrderRequest, opts ...grpc.CallOption) (*CancelOrderResponse, error)
+	// GetRack returns shelves with their orders and stats of the
+	// processed orders
+	GetRack(ctx context.Context, in *GetRackRequest, opts ...grpc.CallOption) (*Rack, error)
+	// WatchEvents streams rack events as they happen
+	WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (Kitchen_WatchEventsClient, error)
+}
+
+type kitchenClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewKitchenClient(cc grpc.ClientConnInterface) KitchenClient {
+	return &kitchenClient{cc}
+}
+
+func (c *kitchenClient) SubmitOrder(ctx context.Context, in *SubmitOrderRequest, opts ...grpc.CallOption) (*SubmitOrderResponse, error) {
+	out := new(SubmitOrderResponse)
+	err := c.cc.Invoke(ctx, "/kitchen.v1.Kitchen/SubmitOrder", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kitchenClient) CancelOrder(ctx context.Context, in *CancelOrderRequest, opts ...grpc.CallOption) (*CancelOrderResponse, error) {
+	out := new(CancelOrderResponse)
+	err := c.cc.Invoke(ctx, "/kitchen.v1.Kitchen/CancelOrder", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kitchenClient) GetRack(ctx context.Context, in *GetRackRequest, opts ...grpc.CallOption) (*Rack, error) {
+	out := new(Rack)
+	err := c.cc.Invoke(ctx, "/kitchen.v1.Kitchen/GetRack", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kitchenClient) WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (Kitchen_WatchEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Kitchen_serviceDesc.Streams[0], "/kitchen.v1.Kitchen/WatchEvents", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &kitchenWatchEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Kitchen_WatchEventsClient interface {
+	Recv() (*Event, error)
+	grpc.ClientStream
+}
+
+type kitchenWatchEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *kitchenWatchEventsClient) Recv() (*Event, error) {
+	m := new(Event)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// KitchenServer is the server API for Kitchen service.
+// All implementations must embed UnimplementedKitchenServer
+// for forward compatibility
+type KitchenServer interface {
+	// SubmitOrder queues the order, it is put on the rack as soon as
+	// the simulation reads it. Call waits for room in the full queue
+	// until its deadline
+	SubmitOrder(context.Context, *SubmitOrderRequest) (*SubmitOrderResponse, error)
+	// CancelOrder takes the order off the rack before its courier
+	// arrives
+	CancelOrder(context.Context, *CancelOrderRequest) (*CancelOrderResponse, error)
+	// GetRack returns shelves with their orders and stats of the
+	// processed orders
+	GetRack(context.Context, *GetRackRequest) (*Rack, error)
+	// WatchEvents streams rack events as they happen
+	WatchEvents(*WatchEventsRequest, Kitchen_WatchEventsServer) error
+	mustEmbedUnimplementedKitchenServer()
+}
+
+// UnimplementedKitchenServer must be embedded to have forward compatible implementations.
+type UnimplementedKitchenServer struct {
+}
+
+func (UnimplementedKitchenServer) SubmitOrder(context.Context, *SubmitOrderRequest) (*SubmitOrderResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubmitOrder not implemented")
+}
+func (UnimplementedKitchenServer) CancelOrder(context.Context, *CancelOrderRequest) (*CancelOrderResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelOrder not implemented")
+}
+func (UnimplementedKitchenServer) GetRack(context.Context, *GetRackRequest) (*Rack, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRack not implemented")
+}
+func (UnimplementedKitchenServer) WatchEvents(*WatchEventsRequest, Kitchen_WatchEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchEvents not implemented")
+}
+func (UnimplementedKitchenServer) mustEmbedUnimplementedKitchenServer() {}
+
+// UnsafeKitchenServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to KitchenServer will
+// result in compilation errors.
+type UnsafeKitchenServer interface {
+	mustEmbedUnimplementedKitchenServer()
+}
+
+func RegisterKitchenServer(s grpc.ServiceRegistrar, srv KitchenServer) {
+	s.RegisterService(&_Kitchen_serviceDesc, srv)
+}
+
+func _Kitchen_SubmitOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubmitOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KitchenServer).SubmitOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kitchen.v1.Kitchen/SubmitOrder",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KitchenServer).SubmitOrder(ctx, req.(*SubmitOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kitchen_CancelOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KitchenServer).CancelOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kitchen.v1.Kitchen/CancelOrder",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KitchenServer).CancelOrder(ctx, req.(*CancelOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kitchen_GetRack_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRackRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KitchenServer).GetRack(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kitchen.v1.Kitchen/GetRack",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KitchenServer).GetRack(ctx, req.(*GetRackRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Kitchen_WatchEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(KitchenServer).WatchEvents(m, &kitchenWatchEventsServer{stream})
+}
+
+type Kitchen_WatchEventsServer interface {
+	Send(*Event) error
+	grpc.ServerStream
+}
+
+type kitchenWatchEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *kitchenWatchEventsServer) Send(m *Event) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _Kitchen_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "kitchen.v1.Kitchen",
+	HandlerType: (*KitchenServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SubmitOrder",
+			Handler:    _Kitchen_SubmitOrder_Handler,
+		},
+		{
+			MethodName: "CancelOrder",
+			Handler:    _Kitchen_CancelOrder_Handler,
+		},
+		{
+			MethodName: "GetRack",
+			Handler:    _Kitchen_GetRack_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchEvents",
+			Handler:       _Kitchen_WatchEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "pkg/kitchenpb/kitchen.proto",
+}
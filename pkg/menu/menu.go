@@ -0,0 +1,172 @@
+package menu
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/bgzzz/kitchen/pkg/orders"
+	"github.com/bgzzz/kitchen/pkg/source"
+)
+
+// Item is the food kitchen can cook, orders are the instances
+// of the menu items
+type Item struct {
+	Name string `json:"name" yaml:"name" required:"true" description:"Unique menu item name"`
+	// Temp is referred shelf storage temperature
+	Temp string `json:"temp,omitempty" yaml:"temp,omitempty" description:"Temperature of the shelf item is stored on"`
+	// ShelfLife is shelf wait max duration (seconds)
+	ShelfLife int `json:"shelfLife,omitempty" yaml:"shelfLife,omitempty" description:"Shelf wait max duration, seconds"`
+	// DecayRate is value deterioration modifier
+	DecayRate float64 `json:"decayRate,omitempty" yaml:"decayRate,omitempty" description:"Value deterioration modifier, share of the shelf life lost per second"`
+	Priority  int     `json:"priority,omitempty" yaml:"priority,omitempty" description:"Order importance class, higher is more important"`
+	Price     float64 `json:"price,omitempty" yaml:"price,omitempty" description:"Amount customer pays for the item, dollars"`
+	Cost      float64 `json:"cost,omitempty" yaml:"cost,omitempty" description:"Ingredient cost of the item, dollars"`
+	// Items are the parts of the multi-item menu item (ex: combo)
+	Items []*orders.ItemOptions `json:"items,omitempty" yaml:"items,omitempty" description:"Parts of the multi-item menu item, each one is placed on its own shelf"`
+}
+
+// Order returns options of the order of the menu item
+func (item *Item) Order(id string) *orders.OrderOptions {
+	return &orders.OrderOptions{
+		ID:        id,
+		Name:      item.Name,
+		Temp:      item.Temp,
+		ShelfLife: item.ShelfLife,
+		DecayRate: item.DecayRate,
+		Priority:  item.Priority,
+		Price:     item.Price,
+		Cost:      item.Cost,
+		Items:     item.Items,
+	}
+}
+
+// Weight is the relative frequency of the menu item in the orders
+type Weight struct {
+	Item   string  `yaml:"item" required:"true" description:"Name of the menu item"`
+	Weight float64 `yaml:"weight" description:"Relative frequency of the menu item"`
+}
+
+// Window is the period of the simulation with its own order mix
+type Window struct {
+	StartSeconds float64 `yaml:"start-seconds" description:"Start of the window since the simulation start, seconds"`
+	// EndSeconds is the end of the window, 0 means window lasts
+	// till the end of the simulation
+	EndSeconds float64   `yaml:"end-seconds" description:"End of the window since the simulation start, seconds, 0 means the end of the simulation"`
+	Weights    []*Weight `yaml:"weights" description:"Frequencies of the menu items ordered during the window"`
+}
+
+// Mix defines which menu items customers order
+type Mix struct {
+	Count int `yaml:"count" description:"Number of orders generated from the menu"`
+	// Seed makes the generated orders reproducible,
+	// 0 means random orders every run
+	Seed    int64     `yaml:"seed" description:"Random seed of the order generation, 0 means random orders every run"`
+	Windows []*Window `yaml:"windows" description:"Order mix per period of the simulation"`
+}
+
+// Generator creates orders of the menu items according to the mix.
+// Orders arrive evenly with ordersPerSecond rate, menu item of every
+// order is picked randomly by the weights of the window its arrival
+// falls into
+type Generator struct {
+	items   map[string]*Item
+	windows []*Window
+	count   int
+	rate    int
+	rnd     *rand.Rand
+	created int
+}
+
+// NewGenerator creates generator of the orders, menu and mix are
+// expected to be valid
+func NewGenerator(items []*Item, mix *Mix, ordersPerSecond int) *Generator {
+	seed := mix.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	g := &Generator{
+		items:   map[string]*Item{},
+		windows: append([]*Window{}, mix.Windows...),
+		count:   mix.Count,
+		rate:    ordersPerSecond,
+		rnd:     rand.New(rand.NewSource(seed)),
+	}
+	for _, item := range items {
+		g.items[item.Name] = item
+	}
+	sort.SliceStable(g.windows, func(i, j int) bool {
+		return g.windows[i].StartSeconds < g.windows[j].StartSeconds
+	})
+
+	return g
+}
+
+// Next returns next generated order
+func (g *Generator) Next() (*source.Order, error) {
+	if g.created >= g.count {
+		return nil, io.EOF
+	}
+
+	offset := float64(g.created) / float64(g.rate)
+	item := g.pick(g.window(offset))
+	g.created++
+
+	return &source.Order{
+		Opts:   item.Order(fmt.Sprintf("%08x-%d", g.rnd.Uint32(), g.created)),
+		Offset: time.Duration(offset * float64(time.Second)),
+		Timed:  true,
+	}, nil
+}
+
+// Close stops the generation
+func (g *Generator) Close() error {
+	g.count = 0
+	return nil
+}
+
+// window returns the window the offset falls into. The last
+// started window is used in case offset is out of all windows
+func (g *Generator) window(offset float64) *Window {
+	var last *Window
+	for _, w := range g.windows {
+		if w.StartSeconds > offset {
+			break
+		}
+		if w.EndSeconds == 0 || offset < w.EndSeconds {
+			return w
+		}
+		last = w
+	}
+	if last == nil {
+		return g.windows[0]
+	}
+	return last
+}
+
+// pick returns random menu item according to the weights
+func (g *Generator) pick(w *Window) *Item {
+	total := 0.0
+	for _, weight := range w.Weights {
+		total += weight.Weight
+	}
+
+	r := g.rnd.Float64() * total
+	for _, weight := range w.Weights {
+		if r < weight.Weight {
+			return g.items[weight.Item]
+		}
+		r -= weight.Weight
+	}
+
+	// rounding can leave r slightly above the total
+	for i := len(w.Weights) - 1; i >= 0; i-- {
+		if w.Weights[i].Weight > 0 {
+			return g.items[w.Weights[i].Item]
+		}
+	}
+	return nil
+}
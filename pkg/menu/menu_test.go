@@ -0,0 +1,108 @@
+package menu
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testItems = []*Item{
+	{Name: "pizza", Temp: "hot", ShelfLife: 10, DecayRate: 0.1, Price: 10},
+	{Name: "salad", Temp: "cold", ShelfLife: 10, DecayRate: 0.1},
+	{Name: "ice cream", Temp: "frozen", ShelfLife: 10, DecayRate: 0.1},
+}
+
+func TestGenerator(t *testing.T) {
+
+	mix := &Mix{
+		Count: 8,
+		Seed:  1,
+		Windows: []*Window{
+			// windows are sorted by start
+			{
+				StartSeconds: 2,
+				EndSeconds:   3,
+				Weights: []*Weight{
+					{Item: "salad", Weight: 1},
+					{Item: "pizza", Weight: 0},
+				},
+			},
+			{
+				StartSeconds: 0,
+				EndSeconds:   1,
+				Weights: []*Weight{
+					{Item: "pizza", Weight: 1},
+				},
+			},
+			{
+				StartSeconds: 3,
+				Weights: []*Weight{
+					{Item: "ice cream", Weight: 2},
+				},
+			},
+		},
+	}
+
+	g := NewGenerator(testItems, mix, 2)
+
+	names := []string{}
+	offsets := []time.Duration{}
+	ids := map[string]struct{}{}
+	for {
+		ord, err := g.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.Equal(t, nil, err, "should be equal")
+		assert.Equal(t, true, ord.Timed, "should be equal")
+		names = append(names, ord.Opts.Name)
+		offsets = append(offsets, ord.Offset)
+		ids[ord.Opts.ID] = struct{}{}
+	}
+
+	// gap between windows [1, 2) uses the last started window
+	assert.Equal(t, []string{
+		"pizza", "pizza", "pizza", "pizza",
+		"salad", "salad", "ice cream", "ice cream",
+	}, names, "should be equal")
+	assert.Equal(t, []time.Duration{
+		0, 500 * time.Millisecond, time.Second, 1500 * time.Millisecond,
+		2 * time.Second, 2500 * time.Millisecond, 3 * time.Second,
+		3500 * time.Millisecond,
+	}, offsets, "orders should arrive evenly")
+	assert.Equal(t, 8, len(ids), "order IDs should be unique")
+}
+
+func TestGeneratorWeights(t *testing.T) {
+	mix := &Mix{
+		Count: 4000,
+		Seed:  7,
+		Windows: []*Window{
+			{
+				Weights: []*Weight{
+					{Item: "pizza", Weight: 3},
+					{Item: "salad", Weight: 1},
+				},
+			},
+		},
+	}
+
+	counts := map[string]int{}
+	g := NewGenerator(testItems, mix, 1000)
+	for {
+		ord, err := g.Next()
+		if err == io.EOF {
+			break
+		}
+		counts[ord.Opts.Name]++
+		assert.Equal(t, ord.Opts.Name == "pizza", ord.Opts.Price == 10,
+			"order should have options of the menu item")
+	}
+
+	share := float64(counts["pizza"]) / float64(mix.Count)
+	assert.Equal(t, true, share > 0.72 && share < 0.78,
+		"items should be ordered according to weights")
+	assert.Equal(t, 0, counts["ice cream"], "should be equal")
+}
@@ -1,6 +1,7 @@
 package orders
 
 import (
+	"fmt"
 	"math/rand"
 	"sync"
 	"time"
@@ -11,17 +12,60 @@ import (
 type Config struct {
 	CourierReadyMin float64
 	CourierReadyMax float64
+	// CourierDelay is added to the courier arrival time of the order
+	CourierDelay time.Duration
 }
 
 type OrderOptions struct {
-	ID   string
-	Name string
+	ID   string `json:"id" yaml:"id" required:"true" description:"Unique order identifier"`
+	Name string `json:"name" yaml:"name" required:"true" description:"Order name"`
 	// Temp is referred shelf storage temperature
-	Temp string
+	Temp string `json:"temp,omitempty" yaml:"temp,omitempty" description:"Temperature of the shelf order is stored on"`
 	// ShelfLife is shelf wait max duration (seconds)
-	ShelfLife int
+	ShelfLife int `json:"shelfLife,omitempty" yaml:"shelfLife,omitempty" description:"Shelf wait max duration, seconds"`
 	// DecayRate is value deterioration modifier
-	DecayRate float64
+	DecayRate float64 `json:"decayRate,omitempty" yaml:"decayRate,omitempty" description:"Value deterioration modifier, share of the shelf life lost per second"`
+	// Priority is the order importance class, higher value is
+	// more important. Orders of the highest class are the last
+	// ones to be put on the overflow shelf or wasted
+	Priority int `json:"priority,omitempty" yaml:"priority,omitempty" description:"Order importance class, higher is more important"`
+	// Price is the amount customer pays for the order
+	Price float64 `json:"price,omitempty" yaml:"price,omitempty" description:"Amount customer pays for the order, dollars"`
+	// Cost is the ingredient cost of the order
+	Cost float64 `json:"cost,omitempty" yaml:"cost,omitempty" description:"Ingredient cost of the order, dollars"`
+	// Items are the parts of the multi-item order. When set
+	// Temp, ShelfLife and DecayRate of the order itself are not used
+	Items []*ItemOptions `json:"items,omitempty" yaml:"items,omitempty" description:"Parts of the multi-item order, each one is placed on its own shelf"`
+	// ArrivalOffset is the order creation time relative to the
+	// simulation start (seconds)
+	ArrivalOffset *float64 `json:"arrivalOffset,omitempty" yaml:"arrivalOffset,omitempty" description:"Order creation time relative to the simulation start, seconds"`
+	// ArrivalTime is the absolute order creation time (RFC3339),
+	// it is counted from the earliest arrival time of the orders
+	ArrivalTime string `json:"arrivalTime,omitempty" yaml:"arrivalTime,omitempty" description:"Absolute order creation time (RFC3339), counted from the earliest arrivalTime of the orders"`
+	// CourierOffset is the courier arrival time after the order
+	// creation (seconds), random within configured boundaries
+	// when not set
+	CourierOffset *float64 `json:"courierOffset,omitempty" yaml:"courierOffset,omitempty" description:"Courier arrival time after the order creation, seconds, random within delivery-min/max-seconds when not set"`
+	// Location is the delivery address of the order, it is used to
+	// route the order to the nearest kitchen
+	Location *Location `json:"location,omitempty" yaml:"location,omitempty" description:"Delivery address of the order, used to route it to the nearest kitchen"`
+}
+
+// Location is the geographic point
+type Location struct {
+	Lat float64 `json:"lat" yaml:"lat" required:"true" description:"Latitude, degrees"`
+	Lon float64 `json:"lon" yaml:"lon" required:"true" description:"Longitude, degrees"`
+}
+
+// ItemOptions defines a single item of the multi-item order
+type ItemOptions struct {
+	Name string `json:"name" yaml:"name" required:"true" description:"Item name"`
+	// Temp is referred shelf storage temperature
+	Temp string `json:"temp" yaml:"temp" required:"true" description:"Temperature of the shelf item is stored on"`
+	// ShelfLife is shelf wait max duration (seconds)
+	ShelfLife int `json:"shelfLife" yaml:"shelfLife" required:"true" description:"Shelf wait max duration, seconds"`
+	// DecayRate is value deterioration modifier
+	DecayRate float64 `json:"decayRate,omitempty" yaml:"decayRate,omitempty" description:"Value deterioration modifier, share of the shelf life lost per second"`
 }
 
 // Order is a structure defining the order in the kitchen
@@ -31,6 +75,8 @@ type Order struct {
 
 	startTS       *time.Time
 	shelfSwitchTS time.Time
+	// deliveryTS is the expected courier arrival time
+	deliveryTS time.Time
 
 	// timers and handlers release channels
 	spoilTimer        *time.Timer
@@ -46,20 +92,71 @@ type Order struct {
 
 	OnSpoil   func(ord *Order)
 	OnDeliver func(ord *Order)
+
+	// Items are the parts of the multi-item order, each of them is
+	// put on its own shelf. Empty for the single item order
+	Items []*Order
+	// Parent is the multi-item order the item belongs to
+	Parent *Order
 }
 
 // NewOrder creates order based on specified options
-// and configuration
+// and configuration. In case options define items, order is
+// created as multi-item one: every item is spoiled separately
+// (onSpoil is called with the item), while courier picks up
+// the whole order
 func NewOrder(opts *OrderOptions,
 	cfg *Config,
 	onSpoil func(ord *Order), onDeliver func(ord *Order)) *Order {
-	return &Order{
+	ord := &Order{
 		Opts:        opts,
 		cfg:         cfg,
 		OnSpoil:     onSpoil,
 		OnDeliver:   onDeliver,
 		shelfChange: make(chan shvs.Shelf),
 	}
+
+	for i, item := range opts.Items {
+		ord.Items = append(ord.Items, &Order{
+			Opts: &OrderOptions{
+				ID:        ItemID(opts.ID, i),
+				Name:      item.Name,
+				Temp:      item.Temp,
+				ShelfLife: item.ShelfLife,
+				DecayRate: item.DecayRate,
+				Priority:  opts.Priority,
+			},
+			cfg:         cfg,
+			OnSpoil:     onSpoil,
+			shelfChange: make(chan shvs.Shelf),
+			Parent:      ord,
+		})
+	}
+
+	return ord
+}
+
+// ItemID returns identifier of the item of the multi-item order
+func ItemID(orderID string, index int) string {
+	return fmt.Sprintf("%s/%d", orderID, index)
+}
+
+// Root returns the order the courier picks up: parent
+// in case of the item, order itself otherwise
+func (ord *Order) Root() *Order {
+	if ord.Parent != nil {
+		return ord.Parent
+	}
+	return ord
+}
+
+// Parts returns list of orders that are put on the shelves:
+// items in case of multi-item order, order itself otherwise
+func (ord *Order) Parts() []*Order {
+	if len(ord.Items) != 0 {
+		return ord.Items
+	}
+	return []*Order{ord}
 }
 
 // Init initializes the order structure and starts
@@ -71,6 +168,12 @@ func (ord *Order) Init(shelf *shvs.Shelf) {
 	ord.putOnTheShelf(shelf)
 }
 
+// StartCourier starts delivery timer of the multi-item order.
+// Items of the order have to be initialized separately via Init
+func (ord *Order) StartCourier() {
+	ord.startDeliverying(ord.timeToDeliver())
+}
+
 // ChangeShelf changes current shelf of the order and eventually
 // retriggers the spoil timer
 func (ord *Order) ChangeShelf(shelf *shvs.Shelf) {
@@ -82,6 +185,9 @@ func (ord *Order) ChangeShelf(shelf *shvs.Shelf) {
 func (ord *Order) Done() {
 	ord.stopTimers()
 	close(ord.shelfChange)
+	for _, item := range ord.Items {
+		item.Done()
+	}
 }
 
 // onSpoilTimerFired waits until either timer
@@ -175,8 +281,6 @@ func (ord *Order) putOnTheShelf(shelf *shvs.Shelf) {
 	// initalisation
 	// this one happens only once at start
 
-	timeToDeliver := time.Duration(ord.cfg.CourierReadyMin+
-		rand.Float64()*(ord.cfg.CourierReadyMax-ord.cfg.CourierReadyMin)) * time.Second
 	timeToSpoil := time.Duration(ord.calculateMaxOrderAge(shelf.ShelfDecayModifier)) *
 		time.Second
 
@@ -187,10 +291,27 @@ func (ord *Order) putOnTheShelf(shelf *shvs.Shelf) {
 	ord.value = 1
 	ord.valueLock.Unlock()
 
-	ord.startDeliverying(timeToDeliver)
+	// items of the multi-item order are picked up together
+	// by the courier of the parent order
+	if ord.Parent == nil {
+		ord.startDeliverying(ord.timeToDeliver())
+	}
 	ord.startSpoiling(timeToSpoil)
 }
 
+// timeToDeliver returns courier arrival time set for the order
+// or random one within configured boundaries, both are delayed
+// by the configured courier delay
+func (ord *Order) timeToDeliver() time.Duration {
+	if ord.Opts.CourierOffset != nil {
+		return time.Duration(*ord.Opts.CourierOffset*float64(time.Second)) +
+			ord.cfg.CourierDelay
+	}
+	return time.Duration(ord.cfg.CourierReadyMin+
+		rand.Float64()*(ord.cfg.CourierReadyMax-ord.cfg.CourierReadyMin))*time.Second +
+		ord.cfg.CourierDelay
+}
+
 func (ord *Order) currentValue(currentTime time.Time) float64 {
 	elapsedTillNow := float64(currentTime.UnixNano()-
 		ord.startTS.UnixNano()) / 1000000000
@@ -204,12 +325,66 @@ func (ord *Order) currentValue(currentTime time.Time) float64 {
 	return ord.value + valueNow - valueOnPrevShelfSwitch
 }
 
+// CurrentValue returns value of the order at the supplied time.
+// Value of the multi-item order is the average value of its items
 func (ord *Order) CurrentValue(currentTime time.Time) float64 {
+	if len(ord.Items) != 0 {
+		var sum float64
+		for _, item := range ord.Items {
+			sum += item.CurrentValue(currentTime)
+		}
+		return sum / float64(len(ord.Items))
+	}
+
 	ord.valueLock.RLock()
 	defer ord.valueLock.RUnlock()
+	// order that is not yet put on the shelf keeps its initial value
+	if ord.startTS == nil {
+		return 1
+	}
 	return ord.currentValue(currentTime)
 }
 
+// TimeToSpoil returns time left until the order is spoiled
+// on the current shelf
+func (ord *Order) TimeToSpoil(currentTime time.Time) time.Duration {
+	if ord.startTS == nil {
+		return time.Duration(ord.calculateMaxOrderAge(0)) * time.Second
+	}
+
+	elapsedTillNow := float64(currentTime.UnixNano()-
+		ord.startTS.UnixNano()) / 1000000000
+
+	return time.Duration((ord.calculateMaxOrderAge(ord.Shelf.ShelfDecayModifier) -
+		elapsedTillNow) * float64(time.Second))
+}
+
+// DeliveryETA returns expected courier arrival time, items of
+// the multi-item order share it with the parent. Zero time is
+// returned in case courier is not dispatched yet
+func (ord *Order) DeliveryETA() time.Time {
+	return ord.Root().deliveryTS
+}
+
+// ExpectedValue returns value the order is expected to have at the
+// courier arrival time in case it stays on the supplied shelf from
+// now on. Order that is expected to be spoiled has 0 value
+func (ord *Order) ExpectedValue(shelf *shvs.Shelf, currentTime time.Time) float64 {
+	left := ord.DeliveryETA().Sub(currentTime).Seconds()
+	if left < 0 {
+		left = 0
+	}
+
+	value := ord.CurrentValue(currentTime) - left*
+		(1+ord.Opts.DecayRate*float64(shelf.ShelfDecayModifier))/
+		float64(ord.Opts.ShelfLife)
+	if value < 0 {
+		return 0
+	}
+
+	return value
+}
+
 // shelfChangerLoop event loop to process on shelf change events
 func (ord *Order) shelfChangerLoop() {
 	for shelf := range ord.shelfChange {
@@ -219,6 +394,7 @@ func (ord *Order) shelfChangerLoop() {
 
 // startDeliverying explicitly starts timer and its handler
 func (ord *Order) startDeliverying(timeToDeliver time.Duration) {
+	ord.deliveryTS = time.Now().Add(timeToDeliver)
 	ord.deliveryTimer = time.NewTimer(timeToDeliver)
 	ord.stopDeliveryingCh = make(chan bool)
 	go ord.onDeliveryTimerFired()
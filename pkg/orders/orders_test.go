@@ -403,3 +403,83 @@ func TestCurrentValue(t *testing.T) {
 			})
 	}
 }
+
+func TestMultiItemOrder(t *testing.T) {
+	spoiled := make(chan *Order)
+
+	ordr := NewOrder(&OrderOptions{
+		ID:   "multi",
+		Name: "multi",
+		Items: []*ItemOptions{
+			{
+				Name:      "burger",
+				Temp:      "hot",
+				ShelfLife: 100,
+				DecayRate: 0,
+			},
+			{
+				Name:      "ice cream",
+				Temp:      "frozen",
+				ShelfLife: 2,
+				DecayRate: 1,
+			},
+		},
+	}, &Config{
+		CourierReadyMin: 10,
+		CourierReadyMax: 11,
+	}, func(ord *Order) {
+		spoiled <- ord
+	}, func(ord *Order) {})
+	defer ordr.Done()
+
+	assert.Equal(t, 2, len(ordr.Parts()), "should be equal")
+	assert.Equal(t, "multi/1", ordr.Items[1].Opts.ID, "should be equal")
+	assert.Equal(t, ordr, ordr.Items[1].Root(), "should be equal")
+
+	shelf := &shvs.Shelf{
+		Name:               "some",
+		Temp:               "some",
+		Capacity:           2,
+		ShelfDecayModifier: 1,
+	}
+
+	for _, item := range ordr.Items {
+		item.Init(shelf)
+	}
+	ordr.StartCourier()
+
+	assert.Equal(t, ordr.Items[1], <-spoiled, "should be equal")
+	assert.Equal(t, 0.49,
+		math.Floor(ordr.CurrentValue(time.Now())*100)/100,
+		"should be equal")
+}
+
+func TestCourierOffset(t *testing.T) {
+	offset := 2.5
+	cfg := &Config{
+		CourierReadyMin: 10,
+		CourierReadyMax: 20,
+	}
+
+	ord := NewOrder(&OrderOptions{
+		ID:            "test",
+		CourierOffset: &offset,
+	}, cfg, func(ord *Order) {}, func(ord *Order) {})
+	assert.Equal(t, 2500*time.Millisecond, ord.timeToDeliver(),
+		"courier offset of the order should be used")
+
+	ord = NewOrder(&OrderOptions{
+		ID: "test",
+	}, cfg, func(ord *Order) {}, func(ord *Order) {})
+	ttd := ord.timeToDeliver()
+	assert.Equal(t, true, ttd >= 10*time.Second && ttd <= 20*time.Second,
+		"random courier arrival should be within configured boundaries")
+
+	cfg.CourierDelay = 5 * time.Second
+	ord = NewOrder(&OrderOptions{
+		ID:            "test",
+		CourierOffset: &offset,
+	}, cfg, func(ord *Order) {}, func(ord *Order) {})
+	assert.Equal(t, 7500*time.Millisecond, ord.timeToDeliver(),
+		"courier delay should be added to the offset")
+}
@@ -0,0 +1,65 @@
+package rack
+
+import (
+	"time"
+
+	ordrs "github.com/bgzzz/kitchen/pkg/orders"
+)
+
+// Cancellation is the request to take the order off the rack before
+// its courier arrives
+type Cancellation struct {
+	OrderID string
+	// Reply gets the outcome of the cancellation
+	Reply chan CancelResult
+}
+
+// CancelResult is the outcome of the cancellation
+type CancelResult struct {
+	// Found is false in case order is not on the rack
+	Found bool
+	// Value is the current value of the cancelled order
+	Value float64
+}
+
+// Cancel takes the order off the rack, all items of the multi-item
+// order are taken off. It returns false in case order is not on
+// the rack
+func (sr *ShelfRack) Cancel(orderID string) (value float64, ok bool) {
+	reply := make(chan CancelResult, 1)
+	sr.Interact(&OrderEvent{
+		EventType: OECancel,
+		Cancel: &Cancellation{
+			OrderID: orderID,
+			Reply:   reply,
+		},
+	})
+	res := <-reply
+	return res.Value, res.Found
+}
+
+// cancelOrder processes the cancellation
+func (sr *ShelfRack) cancelOrder(c *Cancellation) {
+	root := sr.findOrder(c.OrderID)
+	if root == nil {
+		c.Reply <- CancelResult{}
+		return
+	}
+
+	value := root.CurrentValue(time.Now())
+	sr.removeOrder(root, orderStateCancelled)
+	c.Reply <- CancelResult{Found: true, Value: value}
+}
+
+// findOrder returns the order (root of the multi-item order) by its
+// id, nil in case it is not on the rack
+func (sr *ShelfRack) findOrder(id string) *ordrs.Order {
+	for _, shelfTemp := range sr.shelfList {
+		for _, ord := range sr.rack[shelfTemp].orders {
+			if ord.Root().Opts.ID == id {
+				return ord.Root()
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package rack
+
+import (
+	"time"
+
+	ordrs "github.com/bgzzz/kitchen/pkg/orders"
+)
+
+// EventType is the type of the rack event, it is the state of the
+// order after the event
+type EventType string
+
+const (
+	// EventPlaced is the order (or its item) put on the rack
+	EventPlaced EventType = orderStateCreated
+	// EventMoved is the order moved to the other shelf
+	EventMoved EventType = orderStateShelfChange
+	// EventDelivered is the order picked up by its courier
+	EventDelivered EventType = orderStateDelivered
+	// EventSpoiled is the order taken off the rack as its value
+	// dropped to 0
+	EventSpoiled EventType = orderStateSpoiled
+	// EventWasted is the order pushed out of the rack or the one
+	// that did not fit the rack
+	EventWasted EventType = orderStateWasted
+	// EventCancelled is the order taken off the rack by the client
+	// of the kitchen service
+	EventCancelled EventType = orderStateCancelled
+)
+
+// Event is the change of the order state on the rack
+type Event struct {
+	Type    EventType
+	Time    time.Time
+	OrderID string
+	Name    string
+	// Temp is the temp of the order, empty for multi-item orders
+	Temp string
+	// FromShelf is the temp of the shelf order was on before the
+	// event, empty for placed orders and multi-item orders, their
+	// items are reported one by one
+	FromShelf string
+	// ToShelf is the temp of the shelf order is on after the event,
+	// empty in case order left the rack
+	ToShelf string
+	// Value is the current value of the order at the moment of
+	// the event
+	Value float64
+	// Occupancy is the number of orders on every shelf (by shelf
+	// temp) after the event
+	Occupancy map[string]int
+	// Kitchen is the name of the kitchen the rack belongs to, empty
+	// in case single kitchen is simulated
+	Kitchen string
+}
+
+// subscriber is the function receiving the rack events
+type subscriber struct {
+	fn func(Event)
+}
+
+// Subscribe adds the function called on every rack event. Functions
+// are called by the event loop in order of subscription, so they
+// have to be quick. It returns the function cancelling the
+// subscription, function is not called after it returns
+func (sr *ShelfRack) Subscribe(fn func(Event)) (unsubscribe func()) {
+	s := &subscriber{fn: fn}
+
+	sr.subscribersLock.Lock()
+	defer sr.subscribersLock.Unlock()
+	sr.subscribers = append(sr.subscribers, s)
+
+	return func() {
+		sr.subscribersLock.Lock()
+		defer sr.subscribersLock.Unlock()
+		for i, sub := range sr.subscribers {
+			if sub == s {
+				sr.subscribers = append(sr.subscribers[:i:i],
+					sr.subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// stateChanged prints the order state and notifies the subscribers.
+// Shelf the order was on is the one of the previous event of the
+// order, so it is known even when order is already taken off
+func (sr *ShelfRack) stateChanged(order *ordrs.Order, state string, value float64) {
+	sr.PrintState(order.Opts.ID, state, value)
+
+	from := sr.location[order.Opts.ID]
+	to := sr.shelfOf(order)
+	if to == "" {
+		delete(sr.location, order.Opts.ID)
+		for _, part := range order.Parts() {
+			delete(sr.location, part.Opts.ID)
+		}
+	} else {
+		sr.location[order.Opts.ID] = to
+	}
+
+	// subscribers are called under the lock, so the function is not
+	// called once its subscription is cancelled
+	sr.subscribersLock.RLock()
+	defer sr.subscribersLock.RUnlock()
+	if len(sr.subscribers) == 0 {
+		return
+	}
+
+	occupancy := map[string]int{}
+	for shelfTemp, shelfSet := range sr.rack {
+		occupancy[shelfTemp] = len(shelfSet.orders)
+	}
+	event := Event{
+		Type:      EventType(state),
+		Time:      time.Now(),
+		OrderID:   order.Opts.ID,
+		Name:      order.Opts.Name,
+		Temp:      order.Opts.Temp,
+		FromShelf: from,
+		ToShelf:   to,
+		Value:     value,
+		Occupancy: occupancy,
+		Kitchen:   sr.kitchen,
+	}
+	for _, s := range sr.subscribers {
+		s.fn(event)
+	}
+}
+
+// shelfOf returns temp of the shelf the order is on, empty string
+// in case order is not on the rack
+func (sr *ShelfRack) shelfOf(order *ordrs.Order) string {
+	for _, shelfTemp := range sr.shelfList {
+		if _, ok := sr.rack[shelfTemp].orders[order.Opts.ID]; ok {
+			return shelfTemp
+		}
+	}
+	return ""
+}
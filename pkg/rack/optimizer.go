@@ -0,0 +1,164 @@
+package rack
+
+import (
+	"time"
+
+	ordrs "github.com/bgzzz/kitchen/pkg/orders"
+	shvs "github.com/bgzzz/kitchen/pkg/shelves"
+)
+
+const (
+	// DefaultOptimizerBudget is the default max number of
+	// arrangement evaluations done by the optimizer per rack event
+	DefaultOptimizerBudget = 1000
+
+	// minGain is the min value improvement the move is applied for,
+	// it prevents moving orders back and forth due to rounding
+	minGain = 1e-9
+)
+
+// optimizer rearranges orders across the shelves to maximize the
+// total value orders are expected to have at courier arrival.
+// It does steepest ascent local search starting from the current
+// arrangement: on each step the best of single order moves to the
+// shelf with free space and swaps of two orders is applied.
+// Search is bounded by the number of evaluated moves
+type optimizer struct {
+	budget int
+}
+
+// placement is the order with shelf it is assigned to
+type placement struct {
+	order *ordrs.Order
+	shelf string
+	// values keeps expected value of the order per candidate shelf
+	values map[string]float64
+}
+
+// UseOptimizer enables value maximizing rearrangement of the orders
+// on every rack event. Budget limits the number of evaluated moves
+// per event, DefaultOptimizerBudget is used in case budget <= 0
+func (sr *ShelfRack) UseOptimizer(budget int) {
+	if budget <= 0 {
+		budget = DefaultOptimizerBudget
+	}
+	sr.optimizer = &optimizer{
+		budget: budget,
+	}
+}
+
+// optimize computes the arrangement and moves orders accordingly
+func (sr *ShelfRack) optimize() {
+	if sr.optimizer == nil {
+		return
+	}
+
+	now := time.Now()
+	placements := []*placement{}
+	free := map[string]int{}
+	for _, shelfTemp := range sr.shelfList {
+		shelfSet := sr.rack[shelfTemp]
+		free[shelfTemp] = shelfSet.shelf.Capacity - len(shelfSet.orders)
+		for _, ord := range shelfSet.orders {
+			p := &placement{
+				order:  ord,
+				shelf:  shelfTemp,
+				values: map[string]float64{},
+			}
+			for _, candidate := range []string{ord.Opts.Temp, shvs.OverflowShelfTemp} {
+				if candidateSet, ok := sr.rack[candidate]; ok {
+					p.values[candidate] = ord.ExpectedValue(candidateSet.shelf, now)
+				}
+			}
+			placements = append(placements, p)
+		}
+	}
+
+	initial := map[*placement]string{}
+	for _, p := range placements {
+		initial[p] = p.shelf
+	}
+
+	sr.optimizer.search(placements, free)
+
+	for _, p := range placements {
+		if initial[p] == p.shelf {
+			continue
+		}
+		delete(sr.rack[initial[p]].orders, p.order.Opts.ID)
+		sr.rack[p.shelf].orders[p.order.Opts.ID] = p.order
+	}
+
+	for _, p := range placements {
+		if initial[p] == p.shelf {
+			continue
+		}
+		p.order.ChangeShelf(sr.rack[p.shelf].shelf)
+		sr.stateChanged(p.order, orderStateShelfChange,
+			p.order.CurrentValue(now))
+	}
+}
+
+// search improves arrangement of the placements in place and returns
+// the number of evaluated moves. Pass is cut once the budget is spent,
+// the best move found so far is applied
+func (opt *optimizer) search(placements []*placement,
+	free map[string]int) (evaluations int) {
+	for evaluations < opt.budget {
+		bestGain := minGain
+		var bestMove func()
+
+	pass:
+		for i, a := range placements {
+			// single order move to the shelf with free space
+			for shelf, value := range a.values {
+				if evaluations >= opt.budget {
+					break pass
+				}
+				evaluations++
+				if shelf == a.shelf || free[shelf] <= 0 {
+					continue
+				}
+				if gain := value - a.values[a.shelf]; gain > bestGain {
+					a, shelf := a, shelf
+					bestGain = gain
+					bestMove = func() {
+						free[a.shelf]++
+						free[shelf]--
+						a.shelf = shelf
+					}
+				}
+			}
+
+			// swap of two orders located on different shelves
+			for _, b := range placements[i+1:] {
+				if evaluations >= opt.budget {
+					break pass
+				}
+				evaluations++
+				if a.shelf == b.shelf {
+					continue
+				}
+				aValue, aOk := a.values[b.shelf]
+				bValue, bOk := b.values[a.shelf]
+				if !aOk || !bOk {
+					continue
+				}
+				gain := aValue + bValue - a.values[a.shelf] - b.values[b.shelf]
+				if gain > bestGain {
+					a, b := a, b
+					bestGain = gain
+					bestMove = func() {
+						a.shelf, b.shelf = b.shelf, a.shelf
+					}
+				}
+			}
+		}
+
+		if bestMove == nil {
+			return
+		}
+		bestMove()
+	}
+	return
+}
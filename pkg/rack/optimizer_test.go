@@ -0,0 +1,87 @@
+package rack
+
+import (
+	"testing"
+
+	ordrs "github.com/bgzzz/kitchen/pkg/orders"
+	shvs "github.com/bgzzz/kitchen/pkg/shelves"
+	"github.com/bgzzz/kitchen/pkg/stats"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptimizer(t *testing.T) {
+
+	shelves := []*shvs.Shelf{
+		{
+			Name:               "target",
+			Temp:               "target",
+			Capacity:           1,
+			ShelfDecayModifier: 1,
+		},
+		{
+			Name:               shvs.OverflowShelfTemp,
+			Temp:               shvs.OverflowShelfTemp,
+			Capacity:           1,
+			ShelfDecayModifier: 2,
+		},
+	}
+
+	newOrder := func(id string, decayRate float64) *ordrs.Order {
+		return ordrs.NewOrder(&ordrs.OrderOptions{
+			ID:        id,
+			Name:      id,
+			Temp:      "target",
+			ShelfLife: 1000,
+			DecayRate: decayRate,
+		}, &ordrs.Config{
+			CourierReadyMin: 100,
+			CourierReadyMax: 100,
+		}, func(ord *ordrs.Order) {},
+			func(ord *ordrs.Order) {})
+	}
+
+	sr := NewShelfRack(logrus.NewEntry(logrus.New()),
+		stats.NewStats(2), shelves, 2, func() {})
+	sr.UseOptimizer(0)
+
+	// order that does not decay occupies the optimal shelf
+	stable := newOrder("stable", 0)
+	sr.rack["target"].orders[stable.Opts.ID] = stable
+	stable.Init(sr.rack["target"].shelf)
+	defer stable.Done()
+
+	decaying := newOrder("decaying", 2)
+	sr.rack[shvs.OverflowShelfTemp].orders[decaying.Opts.ID] = decaying
+	decaying.Init(sr.rack[shvs.OverflowShelfTemp].shelf)
+	defer decaying.Done()
+
+	sr.optimize()
+
+	_, ok := sr.rack["target"].orders[decaying.Opts.ID]
+	assert.Equal(t, true, ok, "decaying order should be on target shelf")
+	_, ok = sr.rack[shvs.OverflowShelfTemp].orders[stable.Opts.ID]
+	assert.Equal(t, true, ok, "stable order should be on overflow shelf")
+}
+
+func TestOptimizerBudget(t *testing.T) {
+	placements := []*placement{}
+	for i := 0; i < 20; i++ {
+		placements = append(placements, &placement{
+			shelf: "target",
+			values: map[string]float64{
+				"target":               0.5,
+				shvs.OverflowShelfTemp: 0.4,
+			},
+		})
+	}
+	free := map[string]int{"target": 0, shvs.OverflowShelfTemp: 10}
+
+	// single pass takes 20 moves and 190 swaps
+	opt := &optimizer{budget: 50}
+	assert.Equal(t, 50, opt.search(placements, free), "should be equal")
+
+	opt = &optimizer{budget: 1000}
+	assert.Equal(t, 230, opt.search(placements, free),
+		"search stops after the pass without improvement")
+}
@@ -0,0 +1,134 @@
+package rack
+
+import (
+	"sort"
+	"time"
+
+	ordrs "github.com/bgzzz/kitchen/pkg/orders"
+	shvs "github.com/bgzzz/kitchen/pkg/shelves"
+)
+
+// Reconfig is the new set of shelves applied to the live rack
+type Reconfig struct {
+	Shelves []*shvs.Shelf
+	// WasteDisplaced wastes orders of the removed shelves and the
+	// ones exceeding reduced capacity instead of moving them to the
+	// other shelves
+	WasteDisplaced bool
+}
+
+// Reconfigure replaces shelves of the rack keeping the orders.
+// Orders of the changed shelves are moved to the new shelf
+// definitions, orders of the removed shelves and the ones exceeding
+// reduced capacity are moved to the other shelves the same way
+// newly created orders are placed or wasted
+func (sr *ShelfRack) Reconfigure(rc *Reconfig) {
+	sr.Interact(&OrderEvent{
+		EventType: OEReconfigure,
+		Reconfig:  rc,
+	})
+}
+
+// reconfigure applies new shelves to the rack
+func (sr *ShelfRack) reconfigure(rc *Reconfig) {
+	displaced := []*ordrs.Order{}
+
+	newRack := make(map[string]ShelfSet)
+	newShelfList := []string{}
+	// changed are the kept shelves orders decay differently on
+	changed := map[string]bool{}
+	for _, shelf := range rc.Shelves {
+		shelfSet := ShelfSet{
+			shelf:  shelf,
+			orders: make(map[string]*ordrs.Order),
+		}
+		if old, ok := sr.rack[shelf.Temp]; ok {
+			shelfSet.orders = old.orders
+			changed[shelf.Temp] = old.shelf.ShelfDecayModifier !=
+				shelf.ShelfDecayModifier
+		}
+		newRack[shelf.Temp] = shelfSet
+		newShelfList = append(newShelfList, shelf.Temp)
+	}
+
+	for _, shelfTemp := range sr.shelfList {
+		if _, ok := newRack[shelfTemp]; !ok {
+			displaced = append(displaced,
+				byPriority(sr.rack[shelfTemp].orders)...)
+		}
+	}
+
+	sr.rack = newRack
+	sr.shelfList = newShelfList
+	sr.log.Infof("rack is reconfigured%s", sr.shelvesContent())
+
+	now := time.Now()
+	for _, shelfTemp := range sr.shelfList {
+		shelfSet := sr.rack[shelfTemp]
+		ords := byPriority(shelfSet.orders)
+		// least important orders closest to be spoiled leave
+		// the shrunk shelf first
+		sort.SliceStable(ords, func(i, j int) bool {
+			if ords[i].Opts.Priority != ords[j].Opts.Priority {
+				return ords[i].Opts.Priority > ords[j].Opts.Priority
+			}
+			return ords[i].TimeToSpoil(now) > ords[j].TimeToSpoil(now)
+		})
+
+		for i, ord := range ords {
+			if i >= shelfSet.shelf.Capacity {
+				delete(shelfSet.orders, ord.Opts.ID)
+				displaced = append(displaced, ord)
+				continue
+			}
+			// shelf change restarts the spoil timer, so orders are
+			// moved only in case decay modifier is changed
+			if changed[shelfTemp] {
+				ord.ChangeShelf(shelfSet.shelf)
+			}
+		}
+	}
+
+	wasted := map[*ordrs.Order]bool{}
+	for _, ord := range displaced {
+		// order could be wasted with the other item of its order
+		if wasted[ord.Root()] {
+			continue
+		}
+		if rc.WasteDisplaced {
+			wasted[sr.wasteOrder(ord)] = true
+			continue
+		}
+		for _, root := range sr.migrate(ord) {
+			wasted[root] = true
+		}
+	}
+
+	// capacity could be added
+	sr.rebalance()
+}
+
+// migrate places the order taken off the shelf on the rack again.
+// It can push other orders out of the rack the same way newly
+// created order does. It returns the orders that were wasted
+func (sr *ShelfRack) migrate(order *ordrs.Order) []*ordrs.Order {
+	ordersToChange, ordersToWaste, shelf := sr.resolveRackState(order)
+	if shelf == nil {
+		return []*ordrs.Order{sr.wasteOrder(order)}
+	}
+	order.ChangeShelf(shelf)
+	sr.stateChanged(order, orderStateShelfChange,
+		order.CurrentValue(time.Now()))
+
+	for _, change := range ordersToChange {
+		change.order.ChangeShelf(change.shelf)
+		sr.stateChanged(change.order, orderStateShelfChange,
+			change.order.CurrentValue(time.Now()))
+	}
+
+	wasted := []*ordrs.Order{}
+	for _, ord := range ordersToWaste {
+		wasted = append(wasted, sr.wasteOrder(ord))
+	}
+	return wasted
+}
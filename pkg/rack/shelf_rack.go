@@ -5,6 +5,7 @@ import (
 	"math/rand"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/bgzzz/kitchen/pkg/orders"
@@ -18,14 +19,34 @@ const (
 	OECreated = iota
 	OEDelivered
 	OESpoiled
+	// OERebalance triggers moving of the overflow orders to their
+	// optimal shelves, event does not carry the order
+	OERebalance
+	// OEClosed notifies that no more orders are going to be created,
+	// it is needed in case number of orders is not known upfront
+	OEClosed
+	// OEReconfigure replaces shelves of the rack, event carries
+	// the new configuration instead of the order
+	OEReconfigure
+	// OESnapshot requests the state of the rack, event carries
+	// the channel the state is sent to
+	OESnapshot
+	// OECancel takes the order off the rack by its id, event carries
+	// the cancellation instead of the order
+	OECancel
 )
 
+// UnknownOrders is the number of expected orders in case orders are
+// streamed and their number is known only when the stream is closed
+const UnknownOrders = -1
+
 const (
 	orderStateCreated     = "CREATED"
 	orderStateDelivered   = "DELIVERED"
 	orderStateSpoiled     = "SPOILED"
 	orderStateShelfChange = "SHELF_CHANGE"
 	orderStateWasted      = "WASTED"
+	orderStateCancelled   = "CANCELLED"
 )
 
 // OrderEvent represents the state of the order and is needed
@@ -33,6 +54,9 @@ const (
 type OrderEvent struct {
 	EventType int
 	Order     *ordrs.Order
+	Reconfig  *Reconfig
+	Snapshot  chan *Snapshot
+	Cancel    *Cancellation
 }
 
 // ShelfSet represents shelf's properties in addition to
@@ -52,6 +76,19 @@ type ShelfRack struct {
 	shelfList              []string
 	expectedOrdrsToProcess int
 	onFinish               func()
+	optimizer              *optimizer
+	// streaming is true until OEClosed is received in case number
+	// of orders is not known upfront
+	streaming bool
+	finished  bool
+	// subscribers are notified about every rack event
+	subscribers     []*subscriber
+	subscribersLock sync.RWMutex
+	// location is the shelf temp of every order on the rack as it
+	// was reported by the latest event of the order
+	location map[string]string
+	// kitchen is the name of the kitchen the rack belongs to
+	kitchen string
 }
 
 // NewShelfRack creates shelf rack structure
@@ -66,6 +103,12 @@ func NewShelfRack(log *logrus.Entry, stats *stats.Stats, shelves []*shvs.Shelf,
 		expectedOrdrsToProcess: expectedToProcess,
 		onFinish:               onFinish,
 		stats:                  stats,
+		location:               make(map[string]string),
+	}
+
+	if expectedToProcess == UnknownOrders {
+		sr.expectedOrdrsToProcess = 0
+		sr.streaming = true
 	}
 
 	for _, shelf := range shelves {
@@ -79,42 +122,180 @@ func NewShelfRack(log *logrus.Entry, stats *stats.Stats, shelves []*shvs.Shelf,
 	return sr
 }
 
+// SetKitchen sets the name of the kitchen the rack belongs to, it is
+// reported by the rack events. It has to be called before Init
+func (sr *ShelfRack) SetKitchen(name string) {
+	sr.kitchen = name
+}
+
 // Init start event loop for processing the interaction with
 // shelf rack
 func (sr *ShelfRack) Init() {
 	go sr.eventLoop()
 }
 
+// StartRebalancing periodically triggers moving of the overflow
+// orders to their optimal shelves. It returns the function stopping
+// the rebalancing, rack is not rebalanced after it returns
+func (sr *ShelfRack) StartRebalancing(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				select {
+				case sr.eventCh <- OrderEvent{EventType: OERebalance}:
+				case <-done:
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+		})
+		<-stopped
+	}
+}
+
 // Interact allows to interact with the shelf rack by sending
 // order events
 func (sr *ShelfRack) Interact(event *OrderEvent) {
 	sr.eventCh <- *event
 }
 
-// removeOrder removes order from the shelf
+// removeOrder removes order from the shelf. In case of multi-item
+// order all of its items are removed, courier picks up the order
+// only when all items are present on the rack
 func (sr *ShelfRack) removeOrder(order *ordrs.Order, state string) {
-	_, isTempShelf := sr.rack[order.Shelf.Temp].orders[order.Opts.ID]
-	_, isOverflowShelf := sr.rack[shvs.OverflowShelfTemp].orders[order.Opts.ID]
-	if isOverflowShelf || isTempShelf {
-		delete(sr.rack[order.Shelf.Temp].orders, order.Opts.ID)
-		delete(sr.rack[shvs.OverflowShelfTemp].orders, order.Opts.ID)
-		ordrValue := order.CurrentValue(time.Now())
-		sr.PrintState(order.Opts.ID, state, ordrValue)
-		order.Done()
-		sr.expectedOrdrsToProcess--
-		if state == orderStateDelivered {
-			sr.stats.Delivered(ordrValue)
-			return
+	root := order.Root()
+	parts := root.Parts()
+
+	present := sr.partsOnRack(root)
+	if present == 0 {
+		return
+	}
+
+	if state == orderStateDelivered && present != len(parts) {
+		sr.log.Warnf("order %s: %d/%d items are on the rack, unable to pick up",
+			root.Opts.ID, present, len(parts))
+		return
+	}
+
+	for _, part := range parts {
+		sr.takeOff(part)
+	}
+
+	ordrValue := root.CurrentValue(time.Now())
+	sr.stateChanged(root, state, ordrValue)
+	root.Done()
+	sr.expectedOrdrsToProcess--
+	if state == orderStateDelivered {
+		sr.stats.Delivered(outcome(root, ordrValue))
+	}
+
+	if state == orderStateSpoiled {
+		sr.stats.Spoiled(outcome(root, ordrValue))
+	}
+
+	if state == orderStateCancelled {
+		sr.stats.Cancelled(outcome(root, ordrValue))
+	}
+
+	// removal frees space on the shelves
+	sr.rebalance()
+}
+
+// rebalance moves overflow orders to their optimal shelves in case
+// there is free space there. More important orders are moved first,
+// the ones that are closer to be spoiled are moved first within the
+// same priority class
+func (sr *ShelfRack) rebalance() {
+	now := time.Now()
+	ords := byPriority(sr.rack[shvs.OverflowShelfTemp].orders)
+	sort.SliceStable(ords, func(i, j int) bool {
+		if ords[i].Opts.Priority != ords[j].Opts.Priority {
+			return ords[i].Opts.Priority > ords[j].Opts.Priority
 		}
+		return ords[i].TimeToSpoil(now) < ords[j].TimeToSpoil(now)
+	})
 
-		if state == orderStateSpoiled {
-			sr.stats.Spoiled()
-			return
+	for _, ord := range ords {
+		if ord.Opts.Temp == shvs.OverflowShelfTemp {
+			continue
 		}
+		if sr.putOrdOnTheShelf(ord, ord.Opts.Temp) {
+			delete(sr.rack[shvs.OverflowShelfTemp].orders, ord.Opts.ID)
+			ord.ChangeShelf(sr.rack[ord.Opts.Temp].shelf)
+			sr.stateChanged(ord, orderStateShelfChange,
+				ord.CurrentValue(now))
+		}
+	}
+}
+
+// wasteOrder removes the order that is pushed out of the rack. The
+// rest of the items of the multi-item order are wasted with it.
+// It returns the order that was wasted
+func (sr *ShelfRack) wasteOrder(order *ordrs.Order) *ordrs.Order {
+	root := order.Root()
+	for _, part := range root.Parts() {
+		sr.takeOff(part)
+	}
+
+	ordrValue := root.CurrentValue(time.Now())
+	sr.stateChanged(root, orderStateWasted, ordrValue)
+	root.Done()
+	sr.stats.Wasted(outcome(root, ordrValue))
+	sr.expectedOrdrsToProcess--
+
+	return root
+}
 
+// outcome describes processed order for the stats
+func outcome(order *ordrs.Order, value float64) stats.Order {
+	return stats.Order{
+		Priority: order.Opts.Priority,
+		Value:    value,
+		Price:    order.Opts.Price,
+		Cost:     order.Opts.Cost,
 	}
 }
 
+// partsOnRack returns the number of order parts located on the rack
+func (sr *ShelfRack) partsOnRack(order *ordrs.Order) int {
+	present := 0
+	for _, part := range order.Parts() {
+		for _, shelfTemp := range sr.shelfList {
+			if _, ok := sr.rack[shelfTemp].orders[part.Opts.ID]; ok {
+				present++
+				break
+			}
+		}
+	}
+	return present
+}
+
+// takeOff deletes order from whatever shelf it is located on.
+// It returns false in case order is not on the rack
+func (sr *ShelfRack) takeOff(order *ordrs.Order) bool {
+	for _, shelfTemp := range sr.shelfList {
+		if _, ok := sr.rack[shelfTemp].orders[order.Opts.ID]; ok {
+			delete(sr.rack[shelfTemp].orders, order.Opts.ID)
+			return true
+		}
+	}
+	return false
+}
+
 // eventLoop is processing loop of shelf rack interaction events
 func (sr *ShelfRack) eventLoop() {
 
@@ -123,6 +304,9 @@ func (sr *ShelfRack) eventLoop() {
 		switch oe.EventType {
 		case OECreated:
 			{
+				if sr.streaming {
+					sr.expectedOrdrsToProcess++
+				}
 				sr.findShelf(oe.Order)
 			}
 		case OEDelivered:
@@ -134,12 +318,38 @@ func (sr *ShelfRack) eventLoop() {
 
 				sr.removeOrder(oe.Order, orderStateSpoiled)
 			}
+		case OERebalance:
+			{
+				sr.rebalance()
+			}
+		case OEClosed:
+			{
+				sr.streaming = false
+			}
+		case OEReconfigure:
+			{
+				sr.reconfigure(oe.Reconfig)
+			}
+		case OECancel:
+			{
+				sr.cancelOrder(oe.Cancel)
+			}
+		case OESnapshot:
+			{
+				oe.Snapshot <- sr.snapshot()
+				// rack is not changed
+				continue
+			}
 		default:
 			{
 				sr.log.Error("unsupported event supplied")
 			}
 		}
-		if sr.expectedOrdrsToProcess == 0 {
+		sr.optimize()
+		// rack events that are not related to the orders (ex: rebalance)
+		// can come after all orders are processed
+		if sr.expectedOrdrsToProcess == 0 && !sr.streaming && !sr.finished {
+			sr.finished = true
 			sr.log.Info(sr.stats.String())
 			sr.onFinish()
 		}
@@ -154,30 +364,38 @@ type ShelfChangeSet struct {
 }
 
 // findShelf represents the main logic of processing the order
-// via shelf rack
+// via shelf rack. Items of the multi-item order are placed one by one
 func (sr *ShelfRack) findShelf(order *ordrs.Order) {
 	// this is the place to implement different dispatching algorithms
 	// currently it is the on that works according to the simplified
 	// dispatching algorithm proposed in the task
-	ordersToChange, ordersToWaste, shelf := sr.resolveRackState(order)
-	order.Init(shelf)
-	sr.PrintState(order.Opts.ID, orderStateCreated,
-		order.CurrentValue(time.Now()))
-
-	for _, change := range ordersToChange {
-		change.order.ChangeShelf(change.shelf)
-		sr.PrintState(order.Opts.ID, orderStateShelfChange,
-			order.CurrentValue(time.Now()))
-	}
+	for _, part := range order.Parts() {
+		ordersToChange, ordersToWaste, shelf := sr.resolveRackState(part)
+		if shelf == nil {
+			// there is no room for the order on the rack
+			sr.wasteOrder(part)
+			return
+		}
+		part.Init(shelf)
+		sr.stateChanged(part, orderStateCreated,
+			part.CurrentValue(time.Now()))
+
+		for _, change := range ordersToChange {
+			change.order.ChangeShelf(change.shelf)
+			sr.stateChanged(change.order, orderStateShelfChange,
+				change.order.CurrentValue(time.Now()))
+		}
 
-	for _, ord := range ordersToWaste {
-		ordrValue := order.CurrentValue(time.Now())
-		sr.PrintState(order.Opts.ID, orderStateWasted,
-			ordrValue)
-		ord.Done()
-		sr.stats.Wasted(ordrValue)
+		for _, ord := range ordersToWaste {
+			// order can push out its own item placed previously
+			if sr.wasteOrder(ord) == order {
+				return
+			}
+		}
+	}
 
-		sr.expectedOrdrsToProcess--
+	if len(order.Items) != 0 {
+		order.StartCourier()
 	}
 }
 
@@ -190,8 +408,12 @@ func (sr *ShelfRack) PrintState(orderID, state string, value float64) {
 }
 
 // putOrdOnTheShelf return true if it successfully put order on the
-// shelf. Shelf's capacity is taken into account
+// shelf. Shelf's capacity is taken into account, missing shelf is
+// considered as the one without free space
 func (sr *ShelfRack) putOrdOnTheShelf(ord *ordrs.Order, shelfTemp string) bool {
+	if sr.rack[shelfTemp].shelf == nil {
+		return false
+	}
 	if sr.rack[shelfTemp].shelf.Capacity >=
 		(len(sr.rack[shelfTemp].orders) + 1) {
 		sr.rack[shelfTemp].orders[ord.Opts.ID] = ord
@@ -201,10 +423,11 @@ func (sr *ShelfRack) putOrdOnTheShelf(ord *ordrs.Order, shelfTemp string) bool {
 }
 
 // resolveRackState sets the newly created order to the appropriate
-// shelf. It returns:
+// shelf taking into account priority of the orders. It returns:
 // - list of orders that are needed to change the shelf
 // - list of orders that are considered wasted
-// - shelf of the newly created order
+// - shelf of the newly created order, nil in case order itself
+// is wasted
 func (sr *ShelfRack) resolveRackState(order *ordrs.Order) (ordsToChange []*ShelfChangeSet,
 	ordsToWaste []*ordrs.Order, shelf *shvs.Shelf) {
 	// trying to set order on the optimal shelf
@@ -213,14 +436,63 @@ func (sr *ShelfRack) resolveRackState(order *ordrs.Order) (ordsToChange []*Shelf
 		return
 	}
 
+	// trying to move less important order from the optimal shelf
+	// to the overflow
+	lowest := lowestPriority(sr.rack[order.Opts.Temp].orders)
+	if len(lowest) != 0 && lowest[0].Opts.Priority < order.Opts.Priority &&
+		sr.putOrdOnTheShelf(lowest[0], shvs.OverflowShelfTemp) {
+		ordsToChange = append(ordsToChange, &ShelfChangeSet{
+			order: lowest[0],
+			shelf: sr.rack[shvs.OverflowShelfTemp].shelf,
+		})
+		delete(sr.rack[order.Opts.Temp].orders, lowest[0].Opts.ID)
+		if !sr.putOrdOnTheShelf(order, order.Opts.Temp) {
+			panic("this should no happen")
+		}
+		shelf = sr.rack[order.Opts.Temp].shelf
+		return
+	}
+
+	// overflow is full, less important order leaves the optimal
+	// shelf anyway and takes the place of the order on the overflow
+	// or is wasted instead of it
+	if len(lowest) != 0 && lowest[0].Opts.Priority < order.Opts.Priority {
+		evicted := lowest[0]
+		delete(sr.rack[order.Opts.Temp].orders, evicted.Opts.ID)
+		if !sr.putOrdOnTheShelf(order, order.Opts.Temp) {
+			panic("this should no happen")
+		}
+		shelf = sr.rack[order.Opts.Temp].shelf
+
+		toChange, toWaste, evictedShelf := sr.resolveOverflow(evicted)
+		if evictedShelf != nil {
+			ordsToChange = append(ordsToChange, &ShelfChangeSet{
+				order: evicted,
+				shelf: evictedShelf,
+			})
+		}
+		ordsToChange = append(ordsToChange, toChange...)
+		ordsToWaste = append(ordsToWaste, toWaste...)
+		return
+	}
+
+	return sr.resolveOverflow(order)
+}
+
+// resolveOverflow sets the order that does not fit its optimal shelf
+// to the overflow taking into account priority of the orders. It
+// returns the same as resolveRackState
+func (sr *ShelfRack) resolveOverflow(order *ordrs.Order) (ordsToChange []*ShelfChangeSet,
+	ordsToWaste []*ordrs.Order, shelf *shvs.Shelf) {
 	// trying to set order on the overflow
 	if sr.putOrdOnTheShelf(order, shvs.OverflowShelfTemp) {
 		shelf = sr.rack[shvs.OverflowShelfTemp].shelf
 		return
 	}
 
-	// trying to free space on overflow
-	for _, ord := range sr.rack[shvs.OverflowShelfTemp].orders {
+	// trying to free space on overflow, more important orders
+	// are moved back first
+	for _, ord := range byPriority(sr.rack[shvs.OverflowShelfTemp].orders) {
 		if sr.putOrdOnTheShelf(ord, ord.Opts.Temp) {
 			ordsToChange = append(ordsToChange, &ShelfChangeSet{
 				order: ord,
@@ -236,22 +508,54 @@ func (sr *ShelfRack) resolveRackState(order *ordrs.Order) (ordsToChange []*Shelf
 		}
 	}
 
-	// put random order from overflow to waste
-	wastedIndex := rand.Intn(len(sr.rack[shvs.OverflowShelfTemp].orders))
+	// order itself is wasted in case everything on overflow
+	// is more important
+	candidates := lowestPriority(sr.rack[shvs.OverflowShelfTemp].orders)
+	if len(candidates) == 0 || candidates[0].Opts.Priority > order.Opts.Priority {
+		ordsToWaste = append(ordsToWaste, order)
+		return
+	}
 
-	i := 0
-	for _, ord := range sr.rack[shvs.OverflowShelfTemp].orders {
-		if i == wastedIndex {
-			delete(sr.rack[shvs.OverflowShelfTemp].orders, ord.Opts.ID)
-			ordsToWaste = append(ordsToWaste, ord)
-			sr.rack[shvs.OverflowShelfTemp].orders[order.Opts.ID] = order
-			shelf = sr.rack[shvs.OverflowShelfTemp].shelf
-			return
+	// put random order of the lowest priority from overflow to waste
+	ord := candidates[rand.Intn(len(candidates))]
+	delete(sr.rack[shvs.OverflowShelfTemp].orders, ord.Opts.ID)
+	ordsToWaste = append(ordsToWaste, ord)
+	sr.rack[shvs.OverflowShelfTemp].orders[order.Opts.ID] = order
+	shelf = sr.rack[shvs.OverflowShelfTemp].shelf
+
+	return
+}
+
+// byPriority returns orders sorted from the most important to
+// the least important one
+func byPriority(m map[string]*ordrs.Order) []*ordrs.Order {
+	ords := []*ordrs.Order{}
+	for _, ord := range m {
+		ords = append(ords, ord)
+	}
+
+	sort.SliceStable(ords, func(i, j int) bool {
+		return ords[i].Opts.Priority > ords[j].Opts.Priority
+	})
+
+	return ords
+}
+
+// lowestPriority returns orders of the lowest priority class
+func lowestPriority(m map[string]*ordrs.Order) []*ordrs.Order {
+	ords := byPriority(m)
+	if len(ords) == 0 {
+		return ords
+	}
+
+	lowest := ords[len(ords)-1].Opts.Priority
+	for i, ord := range ords {
+		if ord.Opts.Priority == lowest {
+			return ords[i:]
 		}
-		i++
 	}
 
-	return
+	return ords
 }
 
 // shelvesContent return the prepared output string showing
@@ -379,3 +379,700 @@ func TestShelfMapping(t *testing.T) {
 	}
 
 }
+
+func TestMultiItemOrder(t *testing.T) {
+
+	shelves := []*shvs.Shelf{
+		{
+			Name:               "hot",
+			Temp:               "hot",
+			Capacity:           1,
+			ShelfDecayModifier: 1,
+		},
+		{
+			Name:               "frozen",
+			Temp:               "frozen",
+			Capacity:           1,
+			ShelfDecayModifier: 1,
+		},
+		{
+			Name:               shvs.OverflowShelfTemp,
+			Temp:               shvs.OverflowShelfTemp,
+			Capacity:           1,
+			ShelfDecayModifier: 2,
+		},
+	}
+
+	tests := []struct {
+		frozenShelfLife int
+		cfg             ordrs.Config
+		delivered       bool
+	}{
+		// delivered together
+		{
+			frozenShelfLife: 100,
+			cfg: ordrs.Config{
+				CourierReadyMin: 1,
+				CourierReadyMax: 1,
+			},
+			delivered: true,
+		},
+		// frozen item spoils the whole order
+		{
+			frozenShelfLife: 2,
+			cfg: ordrs.Config{
+				CourierReadyMin: 10,
+				CourierReadyMax: 11,
+			},
+			delivered: false,
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("multi_item_%d", i),
+			func(t *testing.T) {
+				st := stats.NewStats(1)
+				done := make(chan bool)
+				sr := NewShelfRack(logrus.NewEntry(logrus.New()),
+					st, shelves, 1, func() {
+						done <- true
+					})
+				sr.Init()
+
+				order := ordrs.NewOrder(&ordrs.OrderOptions{
+					ID:   "multi",
+					Name: "multi",
+					Items: []*ordrs.ItemOptions{
+						{
+							Name:      "burger",
+							Temp:      "hot",
+							ShelfLife: 100,
+							DecayRate: 0.1,
+						},
+						{
+							Name:      "ice cream",
+							Temp:      "frozen",
+							ShelfLife: test.frozenShelfLife,
+							DecayRate: 1,
+						},
+					},
+				}, &test.cfg,
+					func(o *ordrs.Order) {
+						sr.Interact(&OrderEvent{
+							Order:     o,
+							EventType: OESpoiled,
+						})
+					}, func(o *ordrs.Order) {
+						sr.Interact(&OrderEvent{
+							Order:     o,
+							EventType: OEDelivered,
+						})
+					})
+
+				sr.Interact(&OrderEvent{
+					EventType: OECreated,
+					Order:     order,
+				})
+
+				<-done
+				assert.Equal(t, 0, len(sr.rack["hot"].orders), "should be equal")
+				assert.Equal(t, 0, len(sr.rack["frozen"].orders), "should be equal")
+				assert.Equal(t, test.delivered, st.AvgDelivered() > 0,
+					"should be equal")
+			})
+	}
+
+}
+
+func TestPriorityPlacement(t *testing.T) {
+
+	shelves := []*shvs.Shelf{
+		{
+			Name:               "target",
+			Temp:               "target",
+			Capacity:           1,
+			ShelfDecayModifier: 1,
+		},
+		{
+			Name:               shvs.OverflowShelfTemp,
+			Temp:               shvs.OverflowShelfTemp,
+			Capacity:           1,
+			ShelfDecayModifier: 2,
+		},
+	}
+
+	newOrder := func(id string, priority int) *ordrs.Order {
+		return ordrs.NewOrder(&ordrs.OrderOptions{
+			ID:        id,
+			Name:      id,
+			Temp:      "target",
+			ShelfLife: 100,
+			Priority:  priority,
+		}, &ordrs.Config{}, func(ord *ordrs.Order) {},
+			func(ord *ordrs.Order) {})
+	}
+
+	tests := []struct {
+		inTarget         *ordrs.Order
+		inOverflow       *ordrs.Order
+		order            *ordrs.Order
+		expectInTarget   string
+		expectInOverflow string
+		expectWasted     string
+	}{
+		// less important order goes to overflow
+		{
+			inTarget:         newOrder("low", 0),
+			order:            newOrder("high", 1),
+			expectInTarget:   "high",
+			expectInOverflow: "low",
+		},
+		// new order is wasted instead of more important ones
+		{
+			inTarget:         newOrder("high1", 1),
+			inOverflow:       newOrder("high2", 1),
+			order:            newOrder("low", 0),
+			expectInTarget:   "high1",
+			expectInOverflow: "high2",
+			expectWasted:     "low",
+		},
+		// less important order is wasted from overflow
+		{
+			inTarget:         newOrder("high1", 1),
+			inOverflow:       newOrder("low", 0),
+			order:            newOrder("high2", 1),
+			expectInTarget:   "high1",
+			expectInOverflow: "high2",
+			expectWasted:     "low",
+		},
+		// overflow is full, less important order leaves target shelf
+		// and is wasted instead of the more important ones
+		{
+			inTarget:         newOrder("low", 0),
+			inOverflow:       newOrder("high1", 2),
+			order:            newOrder("high2", 1),
+			expectInTarget:   "high2",
+			expectInOverflow: "high1",
+			expectWasted:     "low",
+		},
+		// overflow is full, less important order leaves target shelf
+		// for overflow, the one of the same priority is wasted
+		{
+			inTarget:         newOrder("low1", 0),
+			inOverflow:       newOrder("low2", 0),
+			order:            newOrder("high", 1),
+			expectInTarget:   "high",
+			expectInOverflow: "low1",
+			expectWasted:     "low2",
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("priority_%d", i),
+			func(t *testing.T) {
+				sr := NewShelfRack(logrus.NewEntry(logrus.New()),
+					stats.NewStats(3), shelves, 3, func() {})
+
+				if test.inTarget != nil {
+					sr.rack["target"].orders[test.inTarget.Opts.ID] = test.inTarget
+				}
+				if test.inOverflow != nil {
+					sr.rack[shvs.OverflowShelfTemp].orders[test.inOverflow.Opts.ID] = test.inOverflow
+				}
+
+				_, toWaste, _ := sr.resolveRackState(test.order)
+
+				_, ok := sr.rack["target"].orders[test.expectInTarget]
+				assert.Equal(t, true, ok, "%s should be on target shelf",
+					test.expectInTarget)
+				_, ok = sr.rack[shvs.OverflowShelfTemp].orders[test.expectInOverflow]
+				assert.Equal(t, true, ok, "%s should be on overflow shelf",
+					test.expectInOverflow)
+
+				if test.expectWasted == "" {
+					assert.Equal(t, 0, len(toWaste), "should be equal")
+					return
+				}
+				assert.Equal(t, 1, len(toWaste), "should be equal")
+				assert.Equal(t, test.expectWasted, toWaste[0].Opts.ID,
+					"should be equal")
+			})
+	}
+}
+
+func TestRebalance(t *testing.T) {
+
+	shelves := []*shvs.Shelf{
+		{
+			Name:               "target",
+			Temp:               "target",
+			Capacity:           1,
+			ShelfDecayModifier: 1,
+		},
+		{
+			Name:               shvs.OverflowShelfTemp,
+			Temp:               shvs.OverflowShelfTemp,
+			Capacity:           2,
+			ShelfDecayModifier: 2,
+		},
+	}
+
+	newOrder := func(id string, shelfLife int) *ordrs.Order {
+		return ordrs.NewOrder(&ordrs.OrderOptions{
+			ID:        id,
+			Name:      id,
+			Temp:      "target",
+			ShelfLife: shelfLife,
+			DecayRate: 0.1,
+		}, &ordrs.Config{
+			CourierReadyMin: 100,
+			CourierReadyMax: 100,
+		}, func(ord *ordrs.Order) {},
+			func(ord *ordrs.Order) {})
+	}
+
+	sr := NewShelfRack(logrus.NewEntry(logrus.New()),
+		stats.NewStats(3), shelves, 3, func() {})
+
+	delivered := newOrder("delivered", 100)
+	safe := newOrder("safe", 100)
+	atRisk := newOrder("atRisk", 10)
+
+	sr.rack["target"].orders[delivered.Opts.ID] = delivered
+	delivered.Init(sr.rack["target"].shelf)
+	for _, ord := range []*ordrs.Order{safe, atRisk} {
+		sr.rack[shvs.OverflowShelfTemp].orders[ord.Opts.ID] = ord
+		ord.Init(sr.rack[shvs.OverflowShelfTemp].shelf)
+		defer ord.Done()
+	}
+
+	sr.removeOrder(delivered, orderStateDelivered)
+
+	_, ok := sr.rack["target"].orders[atRisk.Opts.ID]
+	assert.Equal(t, true, ok, "order closer to be spoiled should be moved")
+	_, ok = sr.rack[shvs.OverflowShelfTemp].orders[safe.Opts.ID]
+	assert.Equal(t, true, ok, "order should stay on overflow")
+	assert.Equal(t, 1, len(sr.rack[shvs.OverflowShelfTemp].orders),
+		"should be equal")
+}
+
+func TestStreamedOrders(t *testing.T) {
+
+	finished := make(chan bool, 1)
+	sr := NewShelfRack(logrus.NewEntry(logrus.New()),
+		stats.NewStats(UnknownOrders), testShelves, UnknownOrders, func() {
+			finished <- true
+		})
+	sr.Init()
+
+	ord := ordrs.NewOrder(&ordrs.OrderOptions{
+		ID:        "test",
+		Name:      "test",
+		Temp:      "test",
+		ShelfLife: 100,
+		DecayRate: 0.1,
+	}, &ordrs.Config{
+		CourierReadyMin: 100,
+		CourierReadyMax: 100,
+	}, func(ord *ordrs.Order) {},
+		func(ord *ordrs.Order) {})
+
+	sr.Interact(&OrderEvent{
+		EventType: OECreated,
+		Order:     ord,
+	})
+	sr.Interact(&OrderEvent{
+		EventType: OESpoiled,
+		Order:     ord,
+	})
+	// event loop finishes processing of the previous event before
+	// it receives the next one
+	sr.Interact(&OrderEvent{
+		EventType: OERebalance,
+	})
+
+	assert.Equal(t, 0, len(finished),
+		"rack should not finish until stream is closed")
+
+	sr.Interact(&OrderEvent{
+		EventType: OEClosed,
+	})
+
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Error("rack should finish when stream is closed")
+	}
+}
+
+func TestReconfigure(t *testing.T) {
+
+	newOrder := func(id string, priority int) *ordrs.Order {
+		return ordrs.NewOrder(&ordrs.OrderOptions{
+			ID:        id,
+			Name:      id,
+			Temp:      "target",
+			ShelfLife: 100,
+			DecayRate: 0.1,
+			Priority:  priority,
+		}, &ordrs.Config{
+			CourierReadyMin: 100,
+			CourierReadyMax: 100,
+		}, func(ord *ordrs.Order) {},
+			func(ord *ordrs.Order) {})
+	}
+
+	tests := []struct {
+		shelves        []*shvs.Shelf
+		wasteDisplaced bool
+		// expected location of the orders, empty for the wasted ones
+		location map[string]string
+		wasted   int
+	}{
+		// capacity reduced, less important order is migrated
+		{
+			shelves: []*shvs.Shelf{
+				{Name: "target", Temp: "target", Capacity: 1, ShelfDecayModifier: 1},
+				{Name: "overflow", Temp: shvs.OverflowShelfTemp, Capacity: 1, ShelfDecayModifier: 2},
+			},
+			location: map[string]string{
+				"important": "target",
+				"regular":   shvs.OverflowShelfTemp,
+			},
+		},
+
+		// shelf removed, orders are migrated while there is space
+		{
+			shelves: []*shvs.Shelf{
+				{Name: "overflow", Temp: shvs.OverflowShelfTemp, Capacity: 1, ShelfDecayModifier: 2},
+			},
+			location: map[string]string{
+				"important": shvs.OverflowShelfTemp,
+				"regular":   "",
+			},
+			wasted: 1,
+		},
+
+		// shelf removed, orders are wasted
+		{
+			shelves: []*shvs.Shelf{
+				{Name: "overflow", Temp: shvs.OverflowShelfTemp, Capacity: 2, ShelfDecayModifier: 2},
+			},
+			wasteDisplaced: true,
+			location: map[string]string{
+				"important": "",
+				"regular":   "",
+			},
+			wasted: 2,
+		},
+
+		// capacity added
+		{
+			shelves: []*shvs.Shelf{
+				{Name: "target", Temp: "target", Capacity: 5, ShelfDecayModifier: 1},
+				{Name: "new", Temp: "new", Capacity: 1, ShelfDecayModifier: 1},
+				{Name: "overflow", Temp: shvs.OverflowShelfTemp, Capacity: 1, ShelfDecayModifier: 2},
+			},
+			location: map[string]string{
+				"important": "target",
+				"regular":   "target",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		shelves := []*shvs.Shelf{
+			{Name: "target", Temp: "target", Capacity: 2, ShelfDecayModifier: 1},
+			{Name: "overflow", Temp: shvs.OverflowShelfTemp, Capacity: 1, ShelfDecayModifier: 2},
+		}
+		st := stats.NewStats(2)
+		sr := NewShelfRack(logrus.NewEntry(logrus.New()), st, shelves, 2, func() {})
+
+		ords := []*ordrs.Order{newOrder("important", 1), newOrder("regular", 0)}
+		for _, ord := range ords {
+			sr.rack["target"].orders[ord.Opts.ID] = ord
+			ord.Init(sr.rack["target"].shelf)
+		}
+
+		sr.reconfigure(&Reconfig{
+			Shelves:        test.shelves,
+			WasteDisplaced: test.wasteDisplaced,
+		})
+
+		assert.Equal(t, len(test.shelves), len(sr.shelfList), "should be equal")
+		for id, shelfTemp := range test.location {
+			location := ""
+			for _, temp := range sr.shelfList {
+				if _, ok := sr.rack[temp].orders[id]; ok {
+					location = temp
+				}
+			}
+			assert.Equal(t, shelfTemp, location, fmt.Sprintf("location of %s", id))
+		}
+		assert.Equal(t, test.wasted, 2-sr.expectedOrdrsToProcess, "should be equal")
+
+		for _, ord := range ords {
+			if sr.takeOff(ord) {
+				ord.Done()
+			}
+		}
+	}
+}
+
+func TestReconfigureKeepsShelf(t *testing.T) {
+	shelves := []*shvs.Shelf{
+		{Name: "target", Temp: "target", Capacity: 2, ShelfDecayModifier: 1},
+		{Name: "overflow", Temp: shvs.OverflowShelfTemp, Capacity: 1, ShelfDecayModifier: 2},
+	}
+	sr := NewShelfRack(logrus.NewEntry(logrus.New()), stats.NewStats(1),
+		shelves, 1, func() {})
+
+	// order is not initialized, so it can't change the shelf
+	ord := ordrs.NewOrder(&ordrs.OrderOptions{
+		ID:        "1",
+		Temp:      "target",
+		ShelfLife: 100,
+		DecayRate: 0.1,
+	}, &ordrs.Config{}, func(ord *ordrs.Order) {}, func(ord *ordrs.Order) {})
+	sr.rack["target"].orders[ord.Opts.ID] = ord
+
+	// reloaded shelves are the new definitions of the same shelves
+	done := make(chan struct{})
+	go func() {
+		sr.reconfigure(&Reconfig{Shelves: []*shvs.Shelf{
+			{Name: "target", Temp: "target", Capacity: 3, ShelfDecayModifier: 1},
+			{Name: "overflow", Temp: shvs.OverflowShelfTemp, Capacity: 1, ShelfDecayModifier: 2},
+		}})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("order of the unchanged shelf should not change the shelf")
+	}
+	assert.Equal(t, 3, sr.rack["target"].shelf.Capacity, "should be equal")
+	assert.Equal(t, ord, sr.rack["target"].orders["1"], "should be equal")
+}
+
+func TestSnapshot(t *testing.T) {
+
+	shelves := []*shvs.Shelf{
+		{Name: "test", Temp: "test", Capacity: 1, ShelfDecayModifier: 1},
+		{Name: "overflow", Temp: shvs.OverflowShelfTemp, Capacity: 1,
+			ShelfDecayModifier: 2},
+	}
+
+	events := []Event{}
+	sr := NewShelfRack(logrus.NewEntry(logrus.New()),
+		stats.NewStats(2), shelves, 2, func() {})
+	sr.SetKitchen("downtown")
+	sr.Subscribe(func(event Event) {
+		events = append(events, event)
+	})
+	sr.Init()
+
+	for _, id := range []string{"1", "2"} {
+		sr.Interact(&OrderEvent{
+			EventType: OECreated,
+			Order: ordrs.NewOrder(&ordrs.OrderOptions{
+				ID:        id,
+				Name:      "name " + id,
+				Temp:      "test",
+				ShelfLife: 100,
+				DecayRate: 0.1,
+			}, &ordrs.Config{
+				CourierReadyMin: 100,
+				CourierReadyMax: 100,
+			}, func(ord *ordrs.Order) {},
+				func(ord *ordrs.Order) {}),
+		})
+	}
+
+	snap := sr.Snapshot()
+	assert.Equal(t, 2, len(snap.Shelves), "should be equal")
+	assert.Equal(t, "test", snap.Shelves[0].Temp, "should be equal")
+	assert.Equal(t, 1, len(snap.Shelves[0].Orders), "should be equal")
+	assert.Equal(t, 1, len(snap.Shelves[1].Orders), "should be equal")
+	assert.Equal(t, "2", snap.Shelves[1].Orders[0].ID, "should be equal")
+	assert.Equal(t, true, snap.Shelves[1].Orders[0].Value > 0.99,
+		"should be equal")
+	assert.Equal(t, 2, snap.Stats.Expected, "should be equal")
+	assert.Equal(t, true, snap.Shelves[0].Orders[0].AtRisk,
+		"order should be spoiled before the courier arrives")
+
+	// subscriber is called by the event loop before the snapshot
+	// is taken
+	types := []EventType{}
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, []EventType{EventPlaced, EventPlaced}, types,
+		"should be equal")
+	assert.Equal(t, "test", events[0].ToShelf, "should be equal")
+	assert.Equal(t, shvs.OverflowShelfTemp, events[1].ToShelf, "should be equal")
+	assert.Equal(t, "test", events[1].Temp, "should be equal")
+	assert.Equal(t, map[string]int{"test": 1, shvs.OverflowShelfTemp: 1},
+		events[1].Occupancy, "should be equal")
+	assert.Equal(t, "downtown", events[1].Kitchen, "should be equal")
+}
+
+func TestSubscribe(t *testing.T) {
+
+	shelves := []*shvs.Shelf{
+		{Name: "test", Temp: "test", Capacity: 1, ShelfDecayModifier: 1},
+		{Name: "overflow", Temp: shvs.OverflowShelfTemp, Capacity: 1,
+			ShelfDecayModifier: 1},
+	}
+
+	sr := NewShelfRack(logrus.NewEntry(logrus.New()),
+		stats.NewStats(4), shelves, 4, func() {})
+	events := []Event{}
+	unsubscribe := sr.Subscribe(func(event Event) {
+		events = append(events, event)
+	})
+	all := 0
+	sr.Subscribe(func(event Event) {
+		all++
+	})
+	sr.Init()
+
+	ords := map[string]*ordrs.Order{}
+	create := func(id string) {
+		ords[id] = ordrs.NewOrder(&ordrs.OrderOptions{
+			ID:        id,
+			Temp:      "test",
+			ShelfLife: 100,
+			DecayRate: 0.1,
+		}, &ordrs.Config{
+			CourierReadyMin: 100,
+			CourierReadyMax: 100,
+		}, func(ord *ordrs.Order) {},
+			func(ord *ordrs.Order) {})
+		sr.Interact(&OrderEvent{
+			EventType: OECreated,
+			Order:     ords[id],
+		})
+	}
+
+	create("1")
+	create("2")
+	// overflow is full, order 2 of the same priority is pushed out
+	create("3")
+	// order 3 is moved to the freed shelf
+	sr.Interact(&OrderEvent{
+		EventType: OEDelivered,
+		Order:     ords["1"],
+	})
+	sr.Snapshot()
+
+	type move struct {
+		Type      EventType
+		OrderID   string
+		FromShelf string
+		ToShelf   string
+	}
+	moves := []move{}
+	for _, event := range events {
+		moves = append(moves, move{event.Type, event.OrderID,
+			event.FromShelf, event.ToShelf})
+	}
+	assert.Equal(t, []move{
+		{EventPlaced, "1", "", "test"},
+		{EventPlaced, "2", "", shvs.OverflowShelfTemp},
+		{EventPlaced, "3", "", shvs.OverflowShelfTemp},
+		{EventWasted, "2", shvs.OverflowShelfTemp, ""},
+		{EventDelivered, "1", "test", ""},
+		{EventMoved, "3", shvs.OverflowShelfTemp, "test"},
+	}, moves, "should be equal")
+	assert.Equal(t, map[string]int{"test": 1, shvs.OverflowShelfTemp: 0},
+		events[5].Occupancy, "should be equal")
+
+	unsubscribe()
+	create("4")
+	sr.Snapshot()
+	assert.Equal(t, 6, len(events), "unsubscribed function is not called")
+	assert.Equal(t, 7, all, "should be equal")
+
+	// orders left on the rack
+	ords["3"].Done()
+	ords["4"].Done()
+}
+
+func TestCancel(t *testing.T) {
+
+	shelves := []*shvs.Shelf{
+		{Name: "test", Temp: "test", Capacity: 1, ShelfDecayModifier: 1},
+		{Name: "overflow", Temp: shvs.OverflowShelfTemp, Capacity: 1,
+			ShelfDecayModifier: 1},
+	}
+
+	done := make(chan bool, 1)
+	sr := NewShelfRack(logrus.NewEntry(logrus.New()),
+		stats.NewStats(1), shelves, 1, func() {
+			done <- true
+		})
+	events := []Event{}
+	sr.Subscribe(func(event Event) {
+		events = append(events, event)
+	})
+	sr.Init()
+
+	// multi-item order is cancelled with all of its items
+	sr.Interact(&OrderEvent{
+		EventType: OECreated,
+		Order: ordrs.NewOrder(&ordrs.OrderOptions{
+			ID:   "1",
+			Name: "combo",
+			Items: []*ordrs.ItemOptions{
+				{Name: "a", Temp: "test", ShelfLife: 100, DecayRate: 0.1},
+				{Name: "b", Temp: "test", ShelfLife: 100, DecayRate: 0.1},
+			},
+		}, &ordrs.Config{
+			CourierReadyMin: 100,
+			CourierReadyMax: 100,
+		}, func(ord *ordrs.Order) {},
+			func(ord *ordrs.Order) {}),
+	})
+
+	_, ok := sr.Cancel("2")
+	assert.Equal(t, false, ok, "order is not on the rack")
+
+	value, ok := sr.Cancel("1")
+	assert.Equal(t, true, ok, "should be equal")
+	assert.Equal(t, true, value > 0.99, "should be equal")
+	<-done
+
+	snap := sr.Snapshot()
+	assert.Equal(t, 0, len(snap.Shelves[0].Orders), "should be equal")
+	assert.Equal(t, 0, len(snap.Shelves[1].Orders), "should be equal")
+	assert.Equal(t, 1, snap.Stats.Cancelled, "should be equal")
+
+	last := events[len(events)-1]
+	assert.Equal(t, EventCancelled, last.Type, "should be equal")
+	assert.Equal(t, "1", last.OrderID, "should be equal")
+	assert.Equal(t, "", last.ToShelf, "should be equal")
+
+	_, ok = sr.Cancel("1")
+	assert.Equal(t, false, ok, "order is already cancelled")
+}
+
+func TestStartRebalancing(t *testing.T) {
+	sr := NewShelfRack(logrus.NewEntry(logrus.New()),
+		stats.NewStats(0), nil, 0, func() {})
+
+	stop := sr.StartRebalancing(time.Millisecond)
+	for i := 0; i < 2; i++ {
+		event := <-sr.eventCh
+		assert.Equal(t, OERebalance, event.EventType, "should be equal")
+	}
+
+	stop()
+	select {
+	case <-sr.eventCh:
+		t.Fatal("rack is not supposed to be rebalanced after stop")
+	case <-time.After(20 * time.Millisecond):
+	}
+	// stop can be called again
+	stop()
+}
@@ -0,0 +1,81 @@
+package rack
+
+import (
+	"sort"
+	"time"
+
+	"github.com/bgzzz/kitchen/pkg/stats"
+)
+
+// Snapshot is the state of the rack at the moment
+type Snapshot struct {
+	Time    time.Time
+	Shelves []ShelfSnapshot
+	Stats   stats.Summary
+}
+
+// ShelfSnapshot is the state of the rack shelf
+type ShelfSnapshot struct {
+	Name     string
+	Temp     string
+	Capacity int
+	// Orders are sorted by their current value
+	Orders []OrderSnapshot
+}
+
+// OrderSnapshot is the state of the order (or its item) on the shelf
+type OrderSnapshot struct {
+	ID       string
+	Name     string
+	Priority int
+	Value    float64
+	// AtRisk is true in case order is expected to be spoiled on the
+	// shelf before its courier arrives
+	AtRisk bool
+}
+
+// Snapshot returns the state of the rack. State is taken by the
+// event loop, so it is consistent
+func (sr *ShelfRack) Snapshot() *Snapshot {
+	reply := make(chan *Snapshot, 1)
+	sr.Interact(&OrderEvent{
+		EventType: OESnapshot,
+		Snapshot:  reply,
+	})
+	return <-reply
+}
+
+// snapshot returns current state of the rack
+func (sr *ShelfRack) snapshot() *Snapshot {
+	now := time.Now()
+	snap := &Snapshot{
+		Time:  now,
+		Stats: sr.stats.Summary(),
+	}
+	for _, shelfTemp := range sr.shelfList {
+		shelfSet := sr.rack[shelfTemp]
+		shelf := ShelfSnapshot{
+			Name:     shelfSet.shelf.Name,
+			Temp:     shelfSet.shelf.Temp,
+			Capacity: shelfSet.shelf.Capacity,
+			Orders:   []OrderSnapshot{},
+		}
+		for _, ord := range shelfSet.orders {
+			shelf.Orders = append(shelf.Orders, OrderSnapshot{
+				ID:       ord.Opts.ID,
+				Name:     ord.Opts.Name,
+				Priority: ord.Opts.Priority,
+				Value:    ord.CurrentValue(now),
+				AtRisk:   ord.ExpectedValue(shelfSet.shelf, now) <= 0,
+			})
+		}
+		sort.SliceStable(shelf.Orders, func(i, j int) bool {
+			if shelf.Orders[i].Value != shelf.Orders[j].Value {
+				return shelf.Orders[i].Value < shelf.Orders[j].Value
+			}
+			return shelf.Orders[i].ID < shelf.Orders[j].ID
+		})
+		snap.Shelves = append(snap.Shelves, shelf)
+	}
+	return snap
+}
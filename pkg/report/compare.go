@@ -0,0 +1,173 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bgzzz/kitchen/pkg/stats"
+)
+
+// significance is the p-value below which difference of the
+// metric is considered significant
+const significance = 0.05
+
+// Metric is the compared value of the run
+type Metric struct {
+	Name string
+	// value returns the metric of the run, false in case run does
+	// not have it (ex: shelf is not defined)
+	value func(*RunSummary) (float64, bool)
+}
+
+// ComparisonRow is the comparison of the metric of two reports
+type ComparisonRow struct {
+	Metric string
+	// A and B are means of the metric over the runs of the reports
+	A float64
+	B float64
+	// Tested is true in case both reports have several runs with
+	// the metric, so P is the p-value of the Welch's t-test
+	Tested bool
+	P      float64
+}
+
+// Diff returns difference of the metric: B - A
+func (row *ComparisonRow) Diff() float64 {
+	return row.B - row.A
+}
+
+// Comparison is the difference of two reports metric by metric
+type Comparison struct {
+	RunsA int
+	RunsB int
+	Rows  []*ComparisonRow
+}
+
+// metrics returns compared metrics of the runs: general ones and
+// the ones of every shelf of both reports
+func metrics(a, b *JSONReport) []Metric {
+	ms := []Metric{
+		{"delivered rate", func(r *RunSummary) (float64, bool) { return r.DeliveredRate, true }},
+		{"wasted rate", func(r *RunSummary) (float64, bool) { return r.WastedRate, true }},
+		{"spoiled rate", func(r *RunSummary) (float64, bool) { return r.SpoiledRate, true }},
+		{"avg delivered value", func(r *RunSummary) (float64, bool) { return r.AvgDeliveredValue, true }},
+		{"delivered value p10", func(r *RunSummary) (float64, bool) { return r.ValuePercentiles.P10, true }},
+		{"delivered value p50", func(r *RunSummary) (float64, bool) { return r.ValuePercentiles.P50, true }},
+		{"delivered value p90", func(r *RunSummary) (float64, bool) { return r.ValuePercentiles.P90, true }},
+		{"profit", func(r *RunSummary) (float64, bool) { return r.Profit, true }},
+	}
+
+	temps := []string{}
+	seen := map[string]bool{}
+	for _, rep := range []*JSONReport{a, b} {
+		for _, run := range rep.Runs {
+			for _, shelf := range run.Shelves {
+				if !seen[shelf.Temp] {
+					seen[shelf.Temp] = true
+					temps = append(temps, shelf.Temp)
+				}
+			}
+		}
+	}
+
+	for _, temp := range temps {
+		temp := temp
+		shelf := func(r *RunSummary) (*ShelfSummary, bool) {
+			for i := range r.Shelves {
+				if r.Shelves[i].Temp == temp {
+					return &r.Shelves[i], true
+				}
+			}
+			return nil, false
+		}
+		ms = append(ms,
+			Metric{fmt.Sprintf("shelf %s peak orders", temp), func(r *RunSummary) (float64, bool) {
+				s, ok := shelf(r)
+				if !ok {
+					return 0, false
+				}
+				return float64(s.PeakOrders), true
+			}},
+			Metric{fmt.Sprintf("shelf %s mean orders", temp), func(r *RunSummary) (float64, bool) {
+				s, ok := shelf(r)
+				if !ok {
+					return 0, false
+				}
+				return s.MeanOrders, true
+			}},
+			Metric{fmt.Sprintf("shelf %s moves in", temp), func(r *RunSummary) (float64, bool) {
+				s, ok := shelf(r)
+				if !ok {
+					return 0, false
+				}
+				return float64(s.MovesIn), true
+			}},
+		)
+	}
+
+	return ms
+}
+
+// values returns the metric of every run of the report having it
+func values(rep *JSONReport, m Metric) []float64 {
+	vs := []float64{}
+	for _, run := range rep.Runs {
+		if v, ok := m.value(run); ok {
+			vs = append(vs, v)
+		}
+	}
+	return vs
+}
+
+// Compare returns difference of the reports metric by metric.
+// Means of the metrics over the runs are compared, in case both
+// reports have several runs (ex: different seeds) difference is
+// checked by the Welch's t-test
+func Compare(a, b *JSONReport) *Comparison {
+	c := &Comparison{
+		RunsA: len(a.Runs),
+		RunsB: len(b.Runs),
+	}
+	for _, m := range metrics(a, b) {
+		va := values(a, m)
+		vb := values(b, m)
+		row := &ComparisonRow{
+			Metric: m.Name,
+			A:      stats.Mean(va),
+			B:      stats.Mean(vb),
+		}
+		if _, p, ok := stats.WelchTTest(va, vb); ok {
+			row.Tested = true
+			row.P = p
+		}
+		c.Rows = append(c.Rows, row)
+	}
+	return c
+}
+
+// String returns comparison as the table
+func (c *Comparison) String() string {
+	b := &strings.Builder{}
+	w := tabwriter.NewWriter(b, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "metric\ta (%d runs)\tb (%d runs)\tb - a\tp-value\t\n",
+		c.RunsA, c.RunsB)
+	for _, row := range c.Rows {
+		p := "-"
+		if row.Tested {
+			p = fmt.Sprintf("%.4f", row.P)
+			if row.P < significance {
+				p += " *"
+			}
+		}
+		fmt.Fprintf(w, "%s\t%.4f\t%.4f\t%+.4f\t%s\t\n", row.Metric,
+			row.A, row.B, row.Diff(), p)
+	}
+	w.Flush()
+
+	if c.RunsA > 1 && c.RunsB > 1 {
+		fmt.Fprintf(b, "* difference is significant (Welch's t-test, p < %v)\n",
+			significance)
+	}
+	return b.String()
+}
@@ -0,0 +1,72 @@
+package report
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testReport(delivered ...float64) *JSONReport {
+	rep := &JSONReport{}
+	for i, rate := range delivered {
+		shelves := []ShelfSummary{{Temp: "hot", PeakOrders: i + 1}}
+		rep.Runs = append(rep.Runs, &RunSummary{
+			Seed:          int64(i + 1),
+			DeliveredRate: rate,
+			Shelves:       shelves,
+		})
+	}
+	return rep
+}
+
+func row(c *Comparison, metric string) *ComparisonRow {
+	for _, r := range c.Rows {
+		if r.Metric == metric {
+			return r
+		}
+	}
+	return nil
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a         *JSONReport
+		b         *JSONReport
+		metric    string
+		diff      float64
+		tested    bool
+		different bool
+	}{
+		{testReport(0.5), testReport(0.7), "delivered rate", 0.2, false, false},
+		{testReport(0.5, 0.52, 0.48), testReport(0.8, 0.82, 0.79),
+			"delivered rate", 0.3033, true, true},
+		{testReport(0.5, 0.7, 0.4), testReport(0.6, 0.4, 0.5),
+			"delivered rate", -0.0333, true, false},
+		{testReport(0.5, 0.6), testReport(0.5, 0.6, 0.7),
+			"shelf hot peak orders", 0.5, true, false},
+		// shelf is missing in the runs of b
+		{testReport(0.5, 0.6), &JSONReport{Runs: []*RunSummary{{}, {}}},
+			"shelf hot peak orders", -1.5, false, false},
+	}
+
+	for _, test := range tests {
+		r := row(Compare(test.a, test.b), test.metric)
+		assert.NotNil(t, r, "should not be nil")
+		assert.InDelta(t, test.diff, r.Diff(), 1e-4, "should be equal")
+		assert.Equal(t, test.tested, r.Tested, "should be equal")
+		assert.Equal(t, test.different, r.Tested && r.P < significance,
+			"should be equal")
+	}
+}
+
+func TestComparisonString(t *testing.T) {
+	out := Compare(testReport(0.5, 0.52, 0.48), testReport(0.8, 0.82, 0.79)).String()
+
+	assert.True(t, strings.Contains(out, "a (3 runs)"), "should be true")
+	assert.True(t, strings.Contains(out, "shelf hot mean orders"), "should be true")
+	assert.True(t, strings.Contains(out, "significant"), "should be true")
+
+	out = Compare(testReport(0.5), testReport(0.7)).String()
+	assert.False(t, strings.Contains(out, "significant"), "should be false")
+}
@@ -0,0 +1,190 @@
+package report
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/bgzzz/kitchen/pkg/rack"
+	"github.com/pkg/errors"
+)
+
+// JSONReport is the list of run summaries. Runs of the same setup
+// with different seeds are collected in one report to compare
+// setups statistically
+type JSONReport struct {
+	Runs []*RunSummary `json:"runs"`
+}
+
+// RunSummary is the outcome of the run
+type RunSummary struct {
+	Start           time.Time `json:"start"`
+	DurationSeconds float64   `json:"durationSeconds"`
+	// Seed is the order mix seed in case orders are generated
+	// from the menu
+	Seed              int64          `json:"seed,omitempty"`
+	Interrupted       bool           `json:"interrupted,omitempty"`
+	Orders            int            `json:"orders"`
+	Delivered         int            `json:"delivered"`
+	Wasted            int            `json:"wasted"`
+	Spoiled           int            `json:"spoiled"`
+	DeliveredRate     float64        `json:"deliveredRate"`
+	WastedRate        float64        `json:"wastedRate"`
+	SpoiledRate       float64        `json:"spoiledRate"`
+	AvgDeliveredValue float64        `json:"avgDeliveredValue"`
+	ValuePercentiles  Percentiles    `json:"deliveredValuePercentiles"`
+	Profit            float64        `json:"profit"`
+	Shelves           []ShelfSummary `json:"shelves"`
+}
+
+// Percentiles of the delivered order values
+type Percentiles struct {
+	P10 float64 `json:"p10"`
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+}
+
+// ShelfSummary is the outcome of the run for the shelf
+type ShelfSummary struct {
+	Temp     string `json:"temp"`
+	Capacity int    `json:"capacity"`
+	// PeakOrders is the max number of orders on the shelf
+	PeakOrders int `json:"peakOrders"`
+	// MeanOrders is the time weighted mean number of orders
+	// on the shelf
+	MeanOrders float64 `json:"meanOrders"`
+	// MovesIn is the number of orders moved to the shelf from
+	// the other ones
+	MovesIn int `json:"movesIn"`
+}
+
+// Summarize returns summary of the run
+func Summarize(run *Run) *RunSummary {
+	summary := &RunSummary{
+		Start:             run.Start,
+		DurationSeconds:   run.End.Sub(run.Start).Seconds(),
+		Seed:              run.Seed,
+		Interrupted:       run.Details == "",
+		Orders:            run.Summary.Expected,
+		Delivered:         run.Summary.Delivered,
+		Wasted:            run.Summary.Wasted,
+		Spoiled:           run.Summary.Spoiled,
+		AvgDeliveredValue: run.Summary.AvgDelivered,
+		Profit:            run.Summary.Profit,
+		Shelves:           []ShelfSummary{},
+	}
+	if summary.Orders > 0 {
+		orders := float64(summary.Orders)
+		summary.DeliveredRate = float64(summary.Delivered) / orders
+		summary.WastedRate = float64(summary.Wasted) / orders
+		summary.SpoiledRate = float64(summary.Spoiled) / orders
+	}
+
+	values := []float64{}
+	movesIn := map[string]int{}
+	for _, event := range run.Events {
+		switch event.Type {
+		case rack.EventDelivered:
+			values = append(values, event.Value)
+		case rack.EventMoved:
+			movesIn[event.ToShelf]++
+		}
+	}
+	sort.Float64s(values)
+	summary.ValuePercentiles = Percentiles{
+		P10: percentile(values, 0.1),
+		P50: percentile(values, 0.5),
+		P90: percentile(values, 0.9),
+	}
+
+	capacity := map[string]int{}
+	for _, shelf := range run.Shelves {
+		capacity[shelf.Temp] = shelf.Capacity
+	}
+	temps, points := occupancy(run)
+	for _, temp := range temps {
+		peak, mean := occupancyStats(points[temp], run.End.Sub(run.Start))
+		summary.Shelves = append(summary.Shelves, ShelfSummary{
+			Temp:       temp,
+			Capacity:   capacity[temp],
+			PeakOrders: peak,
+			MeanOrders: mean,
+			MovesIn:    movesIn[temp],
+		})
+	}
+
+	return summary
+}
+
+// percentile returns percentile of the sorted values by linear
+// interpolation between the closest ranks, 0 for the empty list
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := p * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (rank-float64(lower))*(sorted[upper]-sorted[lower])
+}
+
+// occupancyStats returns peak and time weighted mean occupancy of
+// the shelf during the run
+func occupancyStats(points []occupancyPoint, duration time.Duration) (int, float64) {
+	peak := 0
+	var area float64
+	for i, p := range points {
+		if p.orders > peak {
+			peak = p.orders
+		}
+		until := duration
+		if i+1 < len(points) {
+			until = points[i+1].at
+		}
+		if until > p.at {
+			area += float64(p.orders) * (until - p.at).Seconds()
+		}
+	}
+	if duration <= 0 {
+		return peak, 0
+	}
+	return peak, area / duration.Seconds()
+}
+
+// LoadJSONFile reads JSON report of the runs
+func LoadJSONFile(path string) (*JSONReport, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read report")
+	}
+
+	rep := &JSONReport{}
+	if err := json.Unmarshal(b, rep); err != nil {
+		return nil, errors.Wrap(err, "unable to parse report")
+	}
+	return rep, nil
+}
+
+// AppendJSONFile adds summary of the run to the JSON report, report
+// is created in case it does not exist
+func AppendJSONFile(path string, summary *RunSummary) error {
+	rep := &JSONReport{}
+	if _, err := os.Stat(path); err == nil {
+		if rep, err = LoadJSONFile(path); err != nil {
+			return err
+		}
+	}
+	rep.Runs = append(rep.Runs, summary)
+
+	b, err := json.MarshalIndent(rep, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal report")
+	}
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		return errors.Wrap(err, "unable to write report")
+	}
+	return nil
+}
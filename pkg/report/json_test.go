@@ -0,0 +1,80 @@
+package report
+
+import (
+	"path/filepath"
+	"testing"
+
+	shvs "github.com/bgzzz/kitchen/pkg/shelves"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarize(t *testing.T) {
+	summary := Summarize(testRun())
+
+	assert.Equal(t, 4, summary.Orders, "should be equal")
+	assert.Equal(t, float64(10), summary.DurationSeconds, "should be equal")
+	assert.Equal(t, false, summary.Interrupted, "should be equal")
+	assert.InDelta(t, 0.5, summary.DeliveredRate, 1e-9, "should be equal")
+	assert.InDelta(t, 0.25, summary.WastedRate, 1e-9, "should be equal")
+	assert.InDelta(t, 0.25, summary.SpoiledRate, 1e-9, "should be equal")
+	assert.InDelta(t, 0.365, summary.ValuePercentiles.P10, 1e-9, "should be equal")
+	assert.InDelta(t, 0.625, summary.ValuePercentiles.P50, 1e-9, "should be equal")
+	assert.InDelta(t, 0.885, summary.ValuePercentiles.P90, 1e-9, "should be equal")
+
+	tests := []struct {
+		shelf ShelfSummary
+	}{
+		{ShelfSummary{Temp: "hot", Capacity: 1, PeakOrders: 1, MeanOrders: 0.6, MovesIn: 1}},
+		{ShelfSummary{Temp: shvs.OverflowShelfTemp, Capacity: 2, PeakOrders: 1, MeanOrders: 0.1}},
+		{ShelfSummary{Temp: "cold", Capacity: 0, PeakOrders: 1, MeanOrders: 0.2}},
+	}
+
+	assert.Equal(t, len(tests), len(summary.Shelves), "should be equal")
+	for i, test := range tests {
+		shelf := summary.Shelves[i]
+		assert.InDelta(t, test.shelf.MeanOrders, shelf.MeanOrders, 1e-9, "should be equal")
+		shelf.MeanOrders = test.shelf.MeanOrders
+		assert.Equal(t, test.shelf, shelf, "should be equal")
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	tests := []struct {
+		values []float64
+		p      float64
+		result float64
+	}{
+		{[]float64{}, 0.5, 0},
+		{[]float64{0.4}, 0.9, 0.4},
+		{[]float64{0, 1}, 0.5, 0.5},
+		{[]float64{0, 0.5, 1}, 0.9, 0.9},
+		{[]float64{0, 0.5, 1}, 1, 1},
+	}
+
+	for _, test := range tests {
+		assert.InDelta(t, test.result, percentile(test.values, test.p), 1e-9,
+			"should be equal")
+	}
+}
+
+func TestAppendJSONFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+
+	first := Summarize(testRun())
+	first.Seed = 1
+	second := Summarize(testRun())
+	second.Seed = 2
+
+	assert.Nil(t, AppendJSONFile(path, first), "should be nil")
+	assert.Nil(t, AppendJSONFile(path, second), "should be nil")
+
+	rep, err := LoadJSONFile(path)
+	assert.Nil(t, err, "should be nil")
+	assert.Equal(t, 2, len(rep.Runs), "should be equal")
+	assert.Equal(t, int64(1), rep.Runs[0].Seed, "should be equal")
+	assert.Equal(t, int64(2), rep.Runs[1].Seed, "should be equal")
+	assert.Equal(t, first.Shelves, rep.Runs[0].Shelves, "should be equal")
+
+	_, err = LoadJSONFile(filepath.Join(t.TempDir(), "missing.json"))
+	assert.NotNil(t, err, "should not be nil")
+}
@@ -0,0 +1,265 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bgzzz/kitchen/pkg/rack"
+	shvs "github.com/bgzzz/kitchen/pkg/shelves"
+	"github.com/bgzzz/kitchen/pkg/stats"
+	"github.com/pkg/errors"
+)
+
+// valueBins is the number of bins of the delivered value distribution
+const valueBins = 10
+
+// multiItemTemp is the label of multi-item orders that do not have
+// the temp of their own
+const multiItemTemp = "multi-item"
+
+// Recorder collects rack events of the run, it is supposed to be
+// subscribed to the rack events
+type Recorder struct {
+	lock   sync.Mutex
+	events []rack.Event
+}
+
+// NewRecorder creates recorder of the rack events
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Record adds the event to the recorded ones
+func (r *Recorder) Record(event rack.Event) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.events = append(r.events, event)
+}
+
+// Events returns recorded events
+func (r *Recorder) Events() []rack.Event {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]rack.Event{}, r.events...)
+}
+
+// Run is the completed simulation run the report is built from
+type Run struct {
+	Start   time.Time
+	End     time.Time
+	Shelves []*shvs.Shelf
+	Events  []rack.Event
+	Summary stats.Summary
+	// Seed is the order mix seed in case orders are generated from
+	// the menu, 0 otherwise
+	Seed int64
+	// Details is the text output of the stats, empty in case run
+	// was interrupted
+	Details string
+}
+
+// WriteHTMLFile writes self-contained HTML report of the run
+// to the file
+func WriteHTMLFile(path string, run *Run) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.Wrap(err, "unable to create report file")
+	}
+	defer f.Close()
+
+	if err := WriteHTML(f, run); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// WriteHTML writes self-contained HTML report of the run: charts are
+// inline SVG, so report works offline
+func WriteHTML(w io.Writer, run *Run) error {
+	data := struct {
+		Run         *Run
+		Duration    time.Duration
+		Occupancy   template.HTML
+		Values      template.HTML
+		Outcomes    template.HTML
+		Timeline    template.HTML
+		Interrupted bool
+	}{
+		Run:         run,
+		Duration:    run.End.Sub(run.Start).Round(time.Millisecond),
+		Occupancy:   occupancyChart(run),
+		Values:      valuesChart(valueDistribution(run.Events)),
+		Outcomes:    outcomesChart(outcomesByTemp(run.Events)),
+		Timeline:    timelineChart(run),
+		Interrupted: run.Details == "",
+	}
+
+	if err := pageTemplate.Execute(w, data); err != nil {
+		return errors.Wrap(err, "unable to write report")
+	}
+	return nil
+}
+
+// occupancyPoint is the number of orders on the shelf at the moment
+type occupancyPoint struct {
+	at     time.Duration
+	orders int
+}
+
+// occupancy returns occupancy of every shelf over time by shelf temp.
+// Shelves that are added by config reload are included
+func occupancy(run *Run) ([]string, map[string][]occupancyPoint) {
+	temps := []string{}
+	points := map[string][]occupancyPoint{}
+	for _, shelf := range run.Shelves {
+		temps = append(temps, shelf.Temp)
+		points[shelf.Temp] = []occupancyPoint{{}}
+	}
+
+	for _, event := range run.Events {
+		added := []string{}
+		for temp := range event.Occupancy {
+			if _, ok := points[temp]; !ok {
+				added = append(added, temp)
+			}
+		}
+		sort.Strings(added)
+		for _, temp := range added {
+			temps = append(temps, temp)
+			points[temp] = []occupancyPoint{}
+		}
+
+		at := event.Time.Sub(run.Start)
+		for _, temp := range temps {
+			points[temp] = append(points[temp], occupancyPoint{
+				at:     at,
+				orders: event.Occupancy[temp],
+			})
+		}
+	}
+
+	return temps, points
+}
+
+// valueDistribution returns number of delivered orders per value
+// bin, values are within [0, 1]
+func valueDistribution(events []rack.Event) []int {
+	bins := make([]int, valueBins)
+	for _, event := range events {
+		if event.Type != rack.EventDelivered {
+			continue
+		}
+		bin := int(event.Value * valueBins)
+		if bin < 0 {
+			bin = 0
+		}
+		if bin >= valueBins {
+			bin = valueBins - 1
+		}
+		bins[bin]++
+	}
+	return bins
+}
+
+// outcome is the number of orders of the temp by their outcome
+type outcome struct {
+	temp      string
+	delivered int
+	wasted    int
+	spoiled   int
+}
+
+// outcomesByTemp returns outcomes of the orders by their temp
+// sorted by temp
+func outcomesByTemp(events []rack.Event) []*outcome {
+	byTemp := map[string]*outcome{}
+	for _, event := range events {
+		if event.Type != rack.EventDelivered && event.Type != rack.EventWasted &&
+			event.Type != rack.EventSpoiled {
+			continue
+		}
+
+		temp := event.Temp
+		if temp == "" {
+			temp = multiItemTemp
+		}
+		o, ok := byTemp[temp]
+		if !ok {
+			o = &outcome{temp: temp}
+			byTemp[temp] = o
+		}
+
+		switch event.Type {
+		case rack.EventDelivered:
+			o.delivered++
+		case rack.EventWasted:
+			o.wasted++
+		case rack.EventSpoiled:
+			o.spoiled++
+		}
+	}
+
+	outcomes := []*outcome{}
+	for _, o := range byTemp {
+		outcomes = append(outcomes, o)
+	}
+	sort.Slice(outcomes, func(i, j int) bool {
+		return outcomes[i].temp < outcomes[j].temp
+	})
+	return outcomes
+}
+
+var pageTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"money": func(v float64) string {
+		return fmt.Sprintf("$%.2f", v)
+	},
+	"value": func(v float64) string {
+		return fmt.Sprintf("%.3f", v)
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Kitchen simulation report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+h2 { margin-top: 2em; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ccc; padding: 4px 10px; text-align: right; }
+th { background: #f4f4f4; }
+svg text { font-size: 12px; font-family: sans-serif; }
+pre { background: #f4f4f4; padding: 1em; }
+.note { color: #a00; }
+</style>
+</head>
+<body>
+<h1>Kitchen simulation report</h1>
+<p>Started {{.Run.Start.Format "2006-01-02 15:04:05"}}, lasted {{.Duration}}.</p>
+{{if .Interrupted}}<p class="note">Simulation was interrupted, not all orders are processed.</p>{{end}}
+
+<h2>Stats</h2>
+<table>
+<tr><th>Orders</th><th>Delivered</th><th>Wasted</th><th>Spoiled</th><th>Avg delivered value</th><th>Avg wasted value</th><th>Profit</th></tr>
+<tr><td>{{.Run.Summary.Expected}}</td><td>{{.Run.Summary.Delivered}}</td><td>{{.Run.Summary.Wasted}}</td><td>{{.Run.Summary.Spoiled}}</td><td>{{value .Run.Summary.AvgDelivered}}</td><td>{{value .Run.Summary.AvgWasted}}</td><td>{{money .Run.Summary.Profit}}</td></tr>
+</table>
+{{if .Run.Details}}<pre>{{.Run.Details}}</pre>{{end}}
+
+<h2>Shelf occupancy over time</h2>
+{{.Occupancy}}
+
+<h2>Delivered value distribution</h2>
+{{.Values}}
+
+<h2>Outcomes by order temp</h2>
+{{.Outcomes}}
+
+<h2>Moves and wastes</h2>
+{{.Timeline}}
+</body>
+</html>
+`))
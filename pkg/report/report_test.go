@@ -0,0 +1,104 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bgzzz/kitchen/pkg/rack"
+	shvs "github.com/bgzzz/kitchen/pkg/shelves"
+	"github.com/bgzzz/kitchen/pkg/stats"
+	"github.com/stretchr/testify/assert"
+)
+
+var testStart = time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func testRun() *Run {
+	at := func(seconds int) time.Time {
+		return testStart.Add(time.Duration(seconds) * time.Second)
+	}
+	return &Run{
+		Start: testStart,
+		End:   at(10),
+		Shelves: []*shvs.Shelf{
+			{Name: "hot", Temp: "hot", Capacity: 1},
+			{Name: "overflow", Temp: shvs.OverflowShelfTemp, Capacity: 2},
+		},
+		Events: []rack.Event{
+			{Type: rack.EventPlaced, Time: at(1), OrderID: "1", Temp: "hot", ToShelf: "hot",
+				Value: 1, Occupancy: map[string]int{"hot": 1, shvs.OverflowShelfTemp: 0}},
+			{Type: rack.EventPlaced, Time: at(2), OrderID: "2", Temp: "hot", ToShelf: shvs.OverflowShelfTemp,
+				Value: 1, Occupancy: map[string]int{"hot": 1, shvs.OverflowShelfTemp: 1}},
+			{Type: rack.EventDelivered, Time: at(3), OrderID: "1", Temp: "hot", FromShelf: "hot",
+				Value: 0.95, Occupancy: map[string]int{"hot": 0, shvs.OverflowShelfTemp: 1}},
+			{Type: rack.EventMoved, Time: at(3), OrderID: "2", Temp: "hot", FromShelf: shvs.OverflowShelfTemp,
+				ToShelf: "hot", Value: 0.9, Occupancy: map[string]int{"hot": 1, shvs.OverflowShelfTemp: 0}},
+			// shelf added by config reload
+			{Type: rack.EventPlaced, Time: at(4), OrderID: "3", Temp: "cold", ToShelf: "cold",
+				Value: 1, Occupancy: map[string]int{"hot": 1, shvs.OverflowShelfTemp: 0, "cold": 1}},
+			{Type: rack.EventWasted, Time: at(5), OrderID: "4",
+				Value: 0.5, Occupancy: map[string]int{"hot": 1, shvs.OverflowShelfTemp: 0, "cold": 1}},
+			{Type: rack.EventSpoiled, Time: at(6), OrderID: "3", Temp: "cold", FromShelf: "cold",
+				Value: 0, Occupancy: map[string]int{"hot": 1, shvs.OverflowShelfTemp: 0, "cold": 0}},
+			{Type: rack.EventDelivered, Time: at(7), OrderID: "2", Temp: "hot", FromShelf: "hot",
+				Value: 0.3, Occupancy: map[string]int{"hot": 0, shvs.OverflowShelfTemp: 0, "cold": 0}},
+		},
+		Summary: stats.Summary{
+			Expected:  4,
+			Delivered: 2,
+			Wasted:    1,
+			Spoiled:   1,
+		},
+		Details: "\n\tDelivered 2/4",
+	}
+}
+
+func TestOccupancy(t *testing.T) {
+	temps, points := occupancy(testRun())
+
+	assert.Equal(t, []string{"hot", shvs.OverflowShelfTemp, "cold"}, temps,
+		"added shelf should follow the initial ones")
+	assert.Equal(t, 9, len(points["hot"]), "initial point and the one per change")
+	assert.Equal(t, occupancyPoint{at: 3 * time.Second, orders: 0},
+		points[shvs.OverflowShelfTemp][4], "should be equal")
+	assert.Equal(t, 4, len(points["cold"]), "should be equal")
+	assert.Equal(t, occupancyPoint{at: 4 * time.Second, orders: 1},
+		points["cold"][0], "should be equal")
+}
+
+func TestValueDistribution(t *testing.T) {
+	assert.Equal(t, []int{0, 0, 0, 1, 0, 0, 0, 0, 0, 1},
+		valueDistribution(testRun().Events), "should be equal")
+}
+
+func TestOutcomesByTemp(t *testing.T) {
+	assert.Equal(t, []*outcome{
+		{temp: "cold", spoiled: 1},
+		{temp: "hot", delivered: 2},
+		{temp: multiItemTemp, wasted: 1},
+	}, outcomesByTemp(testRun().Events), "should be equal")
+}
+
+func TestWriteHTML(t *testing.T) {
+	buf := &bytes.Buffer{}
+	assert.Equal(t, nil, WriteHTML(buf, testRun()), "should be equal")
+
+	page := buf.String()
+	assert.Equal(t, 4, strings.Count(page, "<svg "), "every chart should be drawn")
+	assert.Equal(t, false, strings.Contains(page, "No data."), "should be equal")
+	assert.Equal(t, true, strings.Contains(page, "moved to hot"), "should be equal")
+	assert.Equal(t, false, strings.Contains(page, "interrupted"), "should be equal")
+	assert.Equal(t, false, strings.Contains(page, "http"),
+		"report should not refer to external resources")
+
+	buf.Reset()
+	assert.Equal(t, nil, WriteHTML(buf, &Run{
+		Start: testStart,
+		End:   testStart,
+	}), "should be equal")
+	assert.Equal(t, 4, strings.Count(buf.String(), "No data."),
+		"charts without data should not be drawn")
+	assert.Equal(t, true, strings.Contains(buf.String(), "interrupted"),
+		"should be equal")
+}
@@ -0,0 +1,306 @@
+package report
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bgzzz/kitchen/pkg/rack"
+)
+
+// Chart layout, pixels
+const (
+	chartWidth  = 900
+	chartHeight = 300
+	marginLeft  = 110
+	marginRight = 20
+	marginTop   = 20
+	marginBase  = 40
+	rowHeight   = 28
+	legendWidth = 120
+)
+
+// palette is the colors of the chart series
+var palette = []string{
+	"#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd",
+	"#8c564b", "#e377c2", "#7f7f7f", "#bcbd22", "#17becf",
+}
+
+// Colors of the order outcomes
+const (
+	colorDelivered = "#2ca02c"
+	colorWasted    = "#d62728"
+	colorSpoiled   = "#8c564b"
+)
+
+// svg is the builder of the chart
+type svg struct {
+	b strings.Builder
+}
+
+func newSVG(width, height int) *svg {
+	s := &svg{}
+	fmt.Fprintf(&s.b, `<svg width="%d" height="%d" viewBox="0 0 %d %d">`,
+		width, height, width, height)
+	return s
+}
+
+func (s *svg) add(format string, args ...interface{}) {
+	fmt.Fprintf(&s.b, format, args...)
+}
+
+func (s *svg) text(x, y float64, anchor, text string) {
+	s.add(`<text x="%.1f" y="%.1f" text-anchor="%s">%s</text>`,
+		x, y, anchor, html.EscapeString(text))
+}
+
+func (s *svg) html() template.HTML {
+	return template.HTML(s.b.String() + "</svg>")
+}
+
+// empty returns the placeholder of the chart without data
+func empty() template.HTML {
+	return template.HTML("<p>No data.</p>")
+}
+
+// axes draws x and y axes of the plot area with max values
+func (s *svg) axes(x0, y0, x1, y1 float64, xLabel, yLabel string) {
+	s.add(`<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#444"/>`, x0, y1, x1, y1)
+	s.add(`<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#444"/>`, x0, y0, x0, y1)
+	s.text((x0+x1)/2, y1+32, "middle", xLabel)
+	s.text(x0-8, y0+4, "end", yLabel)
+}
+
+// seconds returns duration in seconds
+func seconds(d time.Duration) float64 {
+	return d.Seconds()
+}
+
+// occupancyChart draws step lines of every shelf occupancy over time
+func occupancyChart(run *Run) template.HTML {
+	temps, points := occupancy(run)
+	if len(run.Events) == 0 || len(temps) == 0 {
+		return empty()
+	}
+
+	maxX := seconds(run.End.Sub(run.Start))
+	maxY := 1
+	for _, shelf := range run.Shelves {
+		if shelf.Capacity > maxY {
+			maxY = shelf.Capacity
+		}
+	}
+	for _, temp := range temps {
+		for _, p := range points[temp] {
+			if seconds(p.at) > maxX {
+				maxX = seconds(p.at)
+			}
+			if p.orders > maxY {
+				maxY = p.orders
+			}
+		}
+	}
+	if maxX <= 0 {
+		maxX = 1
+	}
+
+	x0, y0 := float64(marginLeft), float64(marginTop)
+	x1 := float64(chartWidth - marginRight - legendWidth)
+	y1 := float64(chartHeight - marginBase)
+	scaleX := func(at time.Duration) float64 {
+		return x0 + seconds(at)/maxX*(x1-x0)
+	}
+	scaleY := func(orders int) float64 {
+		return y1 - float64(orders)/float64(maxY)*(y1-y0)
+	}
+
+	s := newSVG(chartWidth, chartHeight)
+	s.axes(x0, y0, x1, y1, fmt.Sprintf("seconds (0 - %.1f)", maxX),
+		fmt.Sprintf("%d orders", maxY))
+
+	for i, temp := range temps {
+		color := palette[i%len(palette)]
+		coords := []string{}
+		prev := 0
+		for j, p := range points[temp] {
+			if j != 0 {
+				// occupancy stays the same until the next event
+				coords = append(coords, fmt.Sprintf("%.1f,%.1f",
+					scaleX(p.at), scaleY(prev)))
+			}
+			coords = append(coords, fmt.Sprintf("%.1f,%.1f",
+				scaleX(p.at), scaleY(p.orders)))
+			prev = p.orders
+		}
+		coords = append(coords, fmt.Sprintf("%.1f,%.1f",
+			scaleX(time.Duration(maxX*float64(time.Second))), scaleY(prev)))
+
+		s.add(`<polyline fill="none" stroke="%s" stroke-width="2" points="%s"><title>%s</title></polyline>`,
+			color, strings.Join(coords, " "), html.EscapeString(temp))
+		ly := y0 + float64(i)*18
+		s.add(`<rect x="%.1f" y="%.1f" width="12" height="12" fill="%s"/>`,
+			x1+16, ly, color)
+		s.text(x1+34, ly+10, "start", temp)
+	}
+
+	return s.html()
+}
+
+// valuesChart draws histogram of the delivered order values
+func valuesChart(bins []int) template.HTML {
+	maxY := 0
+	for _, n := range bins {
+		if n > maxY {
+			maxY = n
+		}
+	}
+	if maxY == 0 {
+		return empty()
+	}
+
+	x0, y0 := float64(marginLeft), float64(marginTop)
+	x1 := float64(chartWidth - marginRight - legendWidth)
+	y1 := float64(chartHeight - marginBase)
+	width := (x1 - x0) / float64(len(bins))
+
+	s := newSVG(chartWidth, chartHeight)
+	s.axes(x0, y0, x1, y1, "delivered value", fmt.Sprintf("%d orders", maxY))
+	for i, n := range bins {
+		h := float64(n) / float64(maxY) * (y1 - y0)
+		from := float64(i) / float64(len(bins))
+		to := float64(i+1) / float64(len(bins))
+		s.add(`<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"><title>%.1f - %.1f: %d orders</title></rect>`,
+			x0+float64(i)*width+1, y1-h, width-2, h, colorDelivered, from, to, n)
+		s.text(x0+float64(i)*width, y1+14, "middle", fmt.Sprintf("%.1f", from))
+	}
+	s.text(x1, y1+14, "middle", "1.0")
+
+	return s.html()
+}
+
+// outcomesChart draws stacked bars of the order outcomes by temp
+func outcomesChart(outcomes []*outcome) template.HTML {
+	if len(outcomes) == 0 {
+		return empty()
+	}
+
+	maxX := 0
+	for _, o := range outcomes {
+		if total := o.delivered + o.wasted + o.spoiled; total > maxX {
+			maxX = total
+		}
+	}
+
+	x0 := float64(marginLeft)
+	x1 := float64(chartWidth - marginRight - legendWidth)
+	height := marginTop + len(outcomes)*rowHeight + marginBase
+	y1 := float64(marginTop + len(outcomes)*rowHeight)
+
+	s := newSVG(chartWidth, height)
+	s.axes(x0, marginTop, x1, y1, fmt.Sprintf("orders (0 - %d)", maxX), "")
+	for i, o := range outcomes {
+		y := float64(marginTop + i*rowHeight)
+		s.text(x0-8, y+rowHeight/2+4, "end", o.temp)
+		x := x0
+		for _, part := range []struct {
+			name  string
+			n     int
+			color string
+		}{
+			{"delivered", o.delivered, colorDelivered},
+			{"wasted", o.wasted, colorWasted},
+			{"spoiled", o.spoiled, colorSpoiled},
+		} {
+			w := float64(part.n) / float64(maxX) * (x1 - x0)
+			s.add(`<rect x="%.1f" y="%.1f" width="%.1f" height="%d" fill="%s"><title>%s %s: %d</title></rect>`,
+				x, y+4, w, rowHeight-8, part.color, html.EscapeString(o.temp),
+				part.name, part.n)
+			x += w
+		}
+	}
+
+	for i, part := range []struct {
+		name  string
+		color string
+	}{
+		{"delivered", colorDelivered},
+		{"wasted", colorWasted},
+		{"spoiled", colorSpoiled},
+	} {
+		ly := float64(marginTop + i*18)
+		s.add(`<rect x="%.1f" y="%.1f" width="12" height="12" fill="%s"/>`,
+			x1+16, ly, part.color)
+		s.text(x1+34, ly+10, "start", part.name)
+	}
+
+	return s.html()
+}
+
+// timelineChart draws moves of the orders between shelves (by the
+// destination shelf) and wastes over time
+func timelineChart(run *Run) template.HTML {
+	rows := map[string]int{}
+	labels := []string{}
+	for _, event := range run.Events {
+		label := ""
+		switch event.Type {
+		case rack.EventMoved:
+			label = "moved to " + event.ToShelf
+		case rack.EventWasted:
+			label = "wasted"
+		default:
+			continue
+		}
+		if _, ok := rows[label]; !ok {
+			rows[label] = 0
+			labels = append(labels, label)
+		}
+	}
+	if len(labels) == 0 {
+		return empty()
+	}
+	// moves go first, wastes are the last row
+	sort.SliceStable(labels, func(i, j int) bool {
+		if (labels[i] == "wasted") != (labels[j] == "wasted") {
+			return labels[j] == "wasted"
+		}
+		return labels[i] < labels[j]
+	})
+	for i, label := range labels {
+		rows[label] = i
+	}
+
+	maxX := math.Max(seconds(run.End.Sub(run.Start)), 1)
+	x0 := float64(marginLeft)
+	x1 := float64(chartWidth - marginRight - legendWidth)
+	height := marginTop + len(labels)*rowHeight + marginBase
+	y1 := float64(marginTop + len(labels)*rowHeight)
+
+	s := newSVG(chartWidth, height)
+	s.axes(x0, marginTop, x1, y1, fmt.Sprintf("seconds (0 - %.1f)", maxX), "")
+	for i, label := range labels {
+		s.text(x0-8, float64(marginTop+i*rowHeight)+rowHeight/2+4, "end", label)
+	}
+
+	for _, event := range run.Events {
+		label := "wasted"
+		color := colorWasted
+		if event.Type == rack.EventMoved {
+			label = "moved to " + event.ToShelf
+			color = palette[0]
+		} else if event.Type != rack.EventWasted {
+			continue
+		}
+		at := seconds(event.Time.Sub(run.Start))
+		s.add(`<circle cx="%.1f" cy="%.1f" r="4" fill="%s" fill-opacity="0.6"><title>%.2fs %s %s, value %.3f</title></circle>`,
+			x0+at/maxX*(x1-x0), float64(marginTop+rows[label]*rowHeight)+rowHeight/2,
+			color, at, html.EscapeString(event.OrderID),
+			html.EscapeString(event.Name), event.Value)
+	}
+
+	return s.html()
+}
@@ -0,0 +1,163 @@
+package routing
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/bgzzz/kitchen/pkg/orders"
+	"github.com/pkg/errors"
+)
+
+// Policies of choosing the kitchen of the order
+const (
+	// PolicyRoundRobin assigns orders to the kitchens in turn
+	PolicyRoundRobin = "round-robin"
+	// PolicyLeastLoaded assigns order to the kitchen with the lowest
+	// shelf occupancy
+	PolicyLeastLoaded = "least-loaded"
+	// PolicyNearest assigns order to the kitchen nearest to its
+	// location, orders without location are assigned to the least
+	// loaded kitchen
+	PolicyNearest = "nearest"
+)
+
+// Policies returns supported routing policies
+func Policies() []string {
+	return []string{PolicyRoundRobin, PolicyLeastLoaded, PolicyNearest}
+}
+
+// Kitchen is the kitchen orders are routed to
+type Kitchen struct {
+	Name string
+	// Location is the kitchen location, it is required by the
+	// nearest policy
+	Location *orders.Location
+	// Load returns the share of the kitchen shelf capacity taken by
+	// the orders, it is required by the least loaded and nearest
+	// policies
+	Load func() float64
+	// Validate returns error in case order can't be put on the
+	// kitchen shelves, kitchen accepts all orders when it is nil
+	Validate func(*orders.OrderOptions) error
+}
+
+// Router assigns orders to the kitchens according to the policy.
+// It is not safe for concurrent use
+type Router struct {
+	policy   string
+	kitchens []Kitchen
+	// next is the kitchen the next turn starts from
+	next int
+}
+
+// New creates router of the policy
+func New(policy string, kitchens []Kitchen) (*Router, error) {
+	if len(kitchens) == 0 {
+		return nil, errors.New("there are no kitchens to route orders to")
+	}
+
+	switch policy {
+	case PolicyRoundRobin:
+	case PolicyLeastLoaded, PolicyNearest:
+		for _, k := range kitchens {
+			if k.Load == nil {
+				return nil, errors.New(fmt.Sprintf("load of kitchen %s is unknown",
+					k.Name))
+			}
+			if policy == PolicyNearest && k.Location == nil {
+				return nil, errors.New(fmt.Sprintf("location of kitchen %s is not set",
+					k.Name))
+			}
+		}
+	default:
+		return nil, errors.New(fmt.Sprintf("unknown routing policy %s, supported: %s",
+			policy, strings.Join(Policies(), ", ")))
+	}
+
+	return &Router{
+		policy:   policy,
+		kitchens: kitchens,
+	}, nil
+}
+
+// Route returns index of the kitchen the order is assigned to. Only
+// kitchens accepting the order are considered, ties are broken in
+// turn
+func (r *Router) Route(opts *orders.OrderOptions) (int, error) {
+	candidates := []int{}
+	rejections := []string{}
+	for i, k := range r.kitchens {
+		if k.Validate != nil {
+			if err := k.Validate(opts); err != nil {
+				rejections = append(rejections,
+					fmt.Sprintf("%s: %s", k.Name, err.Error()))
+				continue
+			}
+		}
+		candidates = append(candidates, i)
+	}
+	if len(candidates) == 0 {
+		return -1, errors.New(fmt.Sprintf("no kitchen accepts order %s (%s)",
+			opts.ID, strings.Join(rejections, "; ")))
+	}
+
+	switch {
+	case r.policy == PolicyNearest && opts.Location != nil:
+		candidates = r.best(candidates, func(k Kitchen) float64 {
+			return distance(k.Location, opts.Location)
+		})
+	case r.policy == PolicyNearest || r.policy == PolicyLeastLoaded:
+		candidates = r.best(candidates, func(k Kitchen) float64 {
+			return k.Load()
+		})
+	}
+
+	return r.turn(candidates), nil
+}
+
+// best returns candidates with the lowest cost
+func (r *Router) best(candidates []int, cost func(Kitchen) float64) []int {
+	best := []int{}
+	min := math.Inf(1)
+	for _, i := range candidates {
+		c := cost(r.kitchens[i])
+		switch {
+		case c < min:
+			min = c
+			best = []int{i}
+		case c == min:
+			best = append(best, i)
+		}
+	}
+	return best
+}
+
+// turn returns the first candidate starting from the next kitchen
+// and moves the turn past it
+func (r *Router) turn(candidates []int) int {
+	chosen := candidates[0]
+	for _, i := range candidates {
+		if i >= r.next {
+			chosen = i
+			break
+		}
+	}
+	r.next = (chosen + 1) % len(r.kitchens)
+	return chosen
+}
+
+// earthRadius is the mean radius of the Earth, km
+const earthRadius = 6371
+
+// distance returns the great-circle distance between the locations, km
+func distance(a, b *orders.Location) float64 {
+	lat1 := a.Lat * math.Pi / 180
+	lat2 := b.Lat * math.Pi / 180
+	dLat := lat2 - lat1
+	dLon := (b.Lon - a.Lon) * math.Pi / 180
+
+	h := math.Pow(math.Sin(dLat/2), 2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin(dLon/2), 2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(h))
+}
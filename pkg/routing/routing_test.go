@@ -0,0 +1,150 @@
+package routing
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/bgzzz/kitchen/pkg/orders"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// hotOnly accepts only the hot orders
+func hotOnly(opts *orders.OrderOptions) error {
+	if opts.Temp != "hot" {
+		return errors.New(fmt.Sprintf("there is no shelf for temp %s", opts.Temp))
+	}
+	return nil
+}
+
+func TestRoute(t *testing.T) {
+	loads := []float64{0.5, 0.2, 0.2}
+	kitchens := []Kitchen{}
+	for i, name := range []string{"north", "center", "south"} {
+		i := i
+		kitchens = append(kitchens, Kitchen{
+			Name:     name,
+			Location: &orders.Location{Lat: 52.3 + 0.1*float64(1-i), Lon: 4.9},
+			Load: func() float64 {
+				return loads[i]
+			},
+		})
+	}
+	kitchens[2].Validate = hotOnly
+
+	hot := &orders.OrderOptions{ID: "h", Temp: "hot"}
+	cold := &orders.OrderOptions{ID: "c", Temp: "cold"}
+	near := func(temp string, lat float64) *orders.OrderOptions {
+		return &orders.OrderOptions{ID: "n", Temp: temp,
+			Location: &orders.Location{Lat: lat, Lon: 4.93}}
+	}
+
+	tests := []struct {
+		policy string
+		orders []*orders.OrderOptions
+		routed []int
+	}{
+		// kitchens take orders in turn, the ones not accepting the
+		// order are skipped
+		{
+			policy: PolicyRoundRobin,
+			orders: []*orders.OrderOptions{hot, hot, hot, cold, cold, hot},
+			routed: []int{0, 1, 2, 0, 1, 2},
+		},
+		{
+			policy: PolicyRoundRobin,
+			orders: []*orders.OrderOptions{hot, hot, cold, hot},
+			routed: []int{0, 1, 0, 1},
+		},
+		// equally loaded kitchens take orders in turn
+		{
+			policy: PolicyLeastLoaded,
+			orders: []*orders.OrderOptions{hot, hot, cold, cold},
+			routed: []int{1, 2, 1, 1},
+		},
+		{
+			policy: PolicyNearest,
+			orders: []*orders.OrderOptions{near("hot", 52.22), near("cold", 52.22),
+				near("hot", 52.39), near("hot", 52.36), hot, hot},
+			routed: []int{2, 1, 0, 0, 1, 2},
+		},
+	}
+
+	for i, test := range tests {
+		r, err := New(test.policy, kitchens)
+		assert.Nil(t, err, "test %d", i)
+
+		routed := []int{}
+		for _, opts := range test.orders {
+			k, err := r.Route(opts)
+			assert.Nil(t, err, "test %d", i)
+			routed = append(routed, k)
+		}
+		assert.Equal(t, test.routed, routed, "test %d", i)
+	}
+}
+
+func TestRouteRejected(t *testing.T) {
+	r, err := New(PolicyRoundRobin, []Kitchen{
+		{Name: "north", Validate: hotOnly},
+		{Name: "south", Validate: hotOnly},
+	})
+	assert.Nil(t, err, "should be nil")
+
+	_, err = r.Route(&orders.OrderOptions{ID: "1", Temp: "frozen"})
+	assert.Equal(t, "no kitchen accepts order 1 (north: there is no shelf for temp frozen; "+
+		"south: there is no shelf for temp frozen)", err.Error(), "should be equal")
+}
+
+func TestNew(t *testing.T) {
+	load := func() float64 { return 0 }
+
+	tests := []struct {
+		policy   string
+		kitchens []Kitchen
+		err      string
+	}{
+		{
+			policy: PolicyRoundRobin,
+			err:    "there are no kitchens to route orders to",
+		},
+		{
+			policy:   "random",
+			kitchens: []Kitchen{{Name: "north"}},
+			err:      "unknown routing policy random, supported: round-robin, least-loaded, nearest",
+		},
+		{
+			policy:   PolicyLeastLoaded,
+			kitchens: []Kitchen{{Name: "north"}},
+			err:      "load of kitchen north is unknown",
+		},
+		{
+			policy: PolicyNearest,
+			kitchens: []Kitchen{
+				{Name: "north", Load: load, Location: &orders.Location{}},
+				{Name: "south", Load: load},
+			},
+			err: "location of kitchen south is not set",
+		},
+		{
+			policy:   PolicyRoundRobin,
+			kitchens: []Kitchen{{Name: "north"}},
+		},
+	}
+
+	for i, test := range tests {
+		_, err := New(test.policy, test.kitchens)
+		if test.err == "" {
+			assert.Nil(t, err, "test %d", i)
+			continue
+		}
+		assert.Equal(t, test.err, err.Error(), "test %d", i)
+	}
+}
+
+func TestDistance(t *testing.T) {
+	amsterdam := &orders.Location{Lat: 52.3676, Lon: 4.9041}
+	rotterdam := &orders.Location{Lat: 51.9244, Lon: 4.4777}
+	assert.InDelta(t, 57.2, distance(amsterdam, rotterdam), 0.5, "should be equal")
+	assert.Equal(t, 0.0, distance(amsterdam, amsterdam), "should be equal")
+}
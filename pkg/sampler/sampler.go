@@ -0,0 +1,163 @@
+package sampler
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bgzzz/kitchen/pkg/rack"
+	"github.com/pkg/errors"
+)
+
+// Header is the header of the samples CSV, every sample has
+// the row per shelf
+var Header = []string{
+	"seconds", "shelf", "capacity", "orders", "at_risk", "mean_value",
+}
+
+// ShelfSample is the state of the shelf at the moment of sampling
+type ShelfSample struct {
+	Temp     string
+	Capacity int
+	Orders   int
+	// AtRisk is the number of orders expected to be spoiled on the
+	// shelf before their couriers arrive
+	AtRisk int
+	// MeanValue is the mean current value of the orders on the
+	// shelf, 0 for the empty shelf
+	MeanValue float64
+}
+
+// Sample is the state of the rack shelves at the moment since the
+// simulation start
+type Sample struct {
+	At      time.Duration
+	Shelves []ShelfSample
+}
+
+// NewSample returns sample of the rack snapshot taken at the
+// moment since the simulation start
+func NewSample(at time.Duration, snap *rack.Snapshot) *Sample {
+	sample := &Sample{At: at}
+	for _, shelf := range snap.Shelves {
+		shelfSample := ShelfSample{
+			Temp:     shelf.Temp,
+			Capacity: shelf.Capacity,
+			Orders:   len(shelf.Orders),
+		}
+		var sum float64
+		for _, ord := range shelf.Orders {
+			sum += ord.Value
+			if ord.AtRisk {
+				shelfSample.AtRisk++
+			}
+		}
+		if len(shelf.Orders) != 0 {
+			shelfSample.MeanValue = sum / float64(len(shelf.Orders))
+		}
+		sample.Shelves = append(sample.Shelves, shelfSample)
+	}
+	return sample
+}
+
+// Records returns CSV records of the sample, one per shelf
+func (s *Sample) Records() [][]string {
+	records := [][]string{}
+	for _, shelf := range s.Shelves {
+		records = append(records, []string{
+			fmt.Sprintf("%.3f", s.At.Seconds()),
+			shelf.Temp,
+			fmt.Sprintf("%d", shelf.Capacity),
+			fmt.Sprintf("%d", shelf.Orders),
+			fmt.Sprintf("%d", shelf.AtRisk),
+			fmt.Sprintf("%.4f", shelf.MeanValue),
+		})
+	}
+	return records
+}
+
+// Sampler periodically samples the rack and writes samples as CSV
+// rows while simulation runs, so samples of the interrupted
+// simulation are kept
+type Sampler struct {
+	snapshot func() *rack.Snapshot
+	w        *csv.Writer
+	closer   io.Closer
+
+	stop    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+	err     error
+}
+
+// New creates sampler writing samples of the rack snapshots to w
+func New(w io.Writer, snapshot func() *rack.Snapshot) *Sampler {
+	return &Sampler{
+		snapshot: snapshot,
+		w:        csv.NewWriter(w),
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+}
+
+// Create creates sampler writing samples to the file
+func Create(path string, snapshot func() *rack.Snapshot) (*Sampler, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create samples file")
+	}
+	s := New(f, snapshot)
+	s.closer = f
+	return s, nil
+}
+
+// Start samples the rack every interval since the start until
+// sampler is stopped
+func (s *Sampler) Start(start time.Time, interval time.Duration) {
+	go func() {
+		defer close(s.stopped)
+		if s.err = s.w.Write(Header); s.err != nil {
+			return
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+
+			sample := NewSample(time.Since(start), s.snapshot())
+			if s.err = s.w.WriteAll(sample.Records()); s.err != nil {
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops sampling and closes the output
+// return error in case samples are not written
+func (s *Sampler) Stop() error {
+	s.once.Do(func() {
+		close(s.stop)
+		<-s.stopped
+		if s.err == nil {
+			s.w.Flush()
+			s.err = s.w.Error()
+		}
+		if s.closer != nil {
+			if err := s.closer.Close(); err != nil && s.err == nil {
+				s.err = err
+			}
+		}
+	})
+	if s.err != nil {
+		return errors.Wrap(s.err, "unable to write samples")
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package sampler
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/bgzzz/kitchen/pkg/rack"
+	"github.com/stretchr/testify/assert"
+)
+
+var testSnapshot = &rack.Snapshot{
+	Shelves: []rack.ShelfSnapshot{
+		{
+			Temp:     "hot",
+			Capacity: 10,
+			Orders: []rack.OrderSnapshot{
+				{ID: "1", Value: 0.5, AtRisk: true},
+				{ID: "2", Value: 1},
+			},
+		},
+		{
+			Temp:     "any",
+			Capacity: 15,
+			Orders:   []rack.OrderSnapshot{},
+		},
+	},
+}
+
+func TestSample(t *testing.T) {
+	sample := NewSample(1500*time.Millisecond, testSnapshot)
+
+	assert.Equal(t, []ShelfSample{
+		{Temp: "hot", Capacity: 10, Orders: 2, AtRisk: 1, MeanValue: 0.75},
+		{Temp: "any", Capacity: 15},
+	}, sample.Shelves, "should be equal")
+	assert.Equal(t, [][]string{
+		{"1.500", "hot", "10", "2", "1", "0.7500"},
+		{"1.500", "any", "15", "0", "0", "0.0000"},
+	}, sample.Records(), "should be equal")
+}
+
+func TestSampler(t *testing.T) {
+	buf := &bytes.Buffer{}
+	s := New(buf, func() *rack.Snapshot {
+		return testSnapshot
+	})
+	s.Start(time.Now(), 50*time.Millisecond)
+	time.Sleep(180 * time.Millisecond)
+	assert.Equal(t, nil, s.Stop(), "should be equal")
+	assert.Equal(t, nil, s.Stop(), "sampler can be stopped twice")
+
+	records, err := csv.NewReader(buf).ReadAll()
+	assert.Equal(t, nil, err, "should be equal")
+	assert.Equal(t, Header, records[0], "should be equal")
+	// every sample has the row per shelf
+	assert.Equal(t, true, len(records) >= 5 && (len(records)-1)%2 == 0,
+		"should be equal")
+	assert.Equal(t, "hot", records[1][1], "should be equal")
+}
@@ -0,0 +1,80 @@
+package scenario
+
+import (
+	"sort"
+	"time"
+)
+
+// Scenario is the list of operational events applied to the
+// running simulation at the set time
+type Scenario struct {
+	Events []*Event `json:"events" yaml:"events" description:"Timed events applied during the run"`
+}
+
+// Event is the single operational change of the simulation. Event
+// defines exactly one of the actions: setting change, shelf capacity
+// change or courier delay
+type Event struct {
+	AtSeconds float64 `json:"at-seconds" yaml:"at-seconds" required:"true" description:"Time of the event since the simulation start, seconds"`
+	// UntilSeconds is the end of the courier delay, 0 means delay
+	// lasts till the end of the simulation
+	UntilSeconds float64 `json:"until-seconds,omitempty" yaml:"until-seconds,omitempty" description:"End of the courier delay since the simulation start, seconds, 0 means the end of the simulation"`
+
+	// Setting is the simulation config setting key (ex:
+	// orders-config.orders-per-second) set to Value
+	Setting string `json:"setting,omitempty" yaml:"setting,omitempty" description:"Simulation config setting changed by the event, ex: orders-config.orders-per-second"`
+	Value   string `json:"value,omitempty" yaml:"value,omitempty" description:"New value of the setting"`
+
+	// Shelf is the temp of the shelf its capacity is set to Capacity
+	Shelf    string `json:"shelf,omitempty" yaml:"shelf,omitempty" description:"Temp of the shelf capacity of which is changed by the event"`
+	Capacity *int   `json:"capacity,omitempty" yaml:"capacity,omitempty" description:"New capacity of the shelf"`
+
+	// CourierDelaySeconds is added to the courier arrival time of
+	// the orders created between AtSeconds and UntilSeconds
+	CourierDelaySeconds float64 `json:"courier-delay-seconds,omitempty" yaml:"courier-delay-seconds,omitempty" description:"Delay added to the courier arrival of orders created between at-seconds and until-seconds, seconds"`
+}
+
+// At returns time of the event since the simulation start
+func (e *Event) At() time.Duration {
+	return seconds(e.AtSeconds)
+}
+
+// IsDelay returns true in case event is the courier delay
+func (e *Event) IsDelay() bool {
+	return e.CourierDelaySeconds != 0
+}
+
+// Timeline returns events that change the simulation at their time
+// (setting and shelf changes) sorted by time
+func (sc *Scenario) Timeline() []*Event {
+	events := []*Event{}
+	for _, e := range sc.Events {
+		if !e.IsDelay() {
+			events = append(events, e)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].AtSeconds < events[j].AtSeconds
+	})
+	return events
+}
+
+// CourierDelay returns total courier delay of the order created at
+// elapsed time since the simulation start
+func (sc *Scenario) CourierDelay(elapsed time.Duration) time.Duration {
+	delay := time.Duration(0)
+	for _, e := range sc.Events {
+		if !e.IsDelay() || elapsed < e.At() {
+			continue
+		}
+		if e.UntilSeconds != 0 && elapsed >= seconds(e.UntilSeconds) {
+			continue
+		}
+		delay += seconds(e.CourierDelaySeconds)
+	}
+	return delay
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
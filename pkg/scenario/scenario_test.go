@@ -0,0 +1,51 @@
+package scenario
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeline(t *testing.T) {
+	capacity := 5
+	sc := &Scenario{
+		Events: []*Event{
+			{AtSeconds: 300, Shelf: "hot", Capacity: &capacity},
+			{AtSeconds: 600, UntilSeconds: 900, CourierDelaySeconds: 5},
+			{AtSeconds: 120, Setting: "orders-config.orders-per-second", Value: "8"},
+		},
+	}
+
+	timeline := sc.Timeline()
+	assert.Equal(t, 2, len(timeline), "courier delays are not the timeline events")
+	assert.Equal(t, 120*time.Second, timeline[0].At(), "should be equal")
+	assert.Equal(t, 300*time.Second, timeline[1].At(), "should be equal")
+}
+
+func TestCourierDelay(t *testing.T) {
+	sc := &Scenario{
+		Events: []*Event{
+			{AtSeconds: 10, UntilSeconds: 20, CourierDelaySeconds: 5},
+			{AtSeconds: 15, CourierDelaySeconds: 0.5},
+			{AtSeconds: 0, Setting: "orders-config.orders-per-second", Value: "8"},
+		},
+	}
+
+	tests := []struct {
+		elapsed time.Duration
+		delay   time.Duration
+	}{
+		{elapsed: 0, delay: 0},
+		{elapsed: 10 * time.Second, delay: 5 * time.Second},
+		{elapsed: 15 * time.Second, delay: 5500 * time.Millisecond},
+		// end of the delay is not included
+		{elapsed: 20 * time.Second, delay: 500 * time.Millisecond},
+		{elapsed: time.Hour, delay: 500 * time.Millisecond},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.delay, sc.CourierDelay(test.elapsed),
+			"should be equal")
+	}
+}
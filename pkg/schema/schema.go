@@ -0,0 +1,212 @@
+package schema
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// draft is the JSON Schema version generated schemas conform to
+	draft = "http://json-schema.org/draft-07/schema#"
+
+	// tagDescription is the struct tag containing property description
+	tagDescription = "description"
+	// tagRequired is the struct tag marking property as required
+	tagRequired = "required"
+)
+
+// Schema is the subset of JSON Schema used to describe
+// configuration files
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+}
+
+// Generate creates schema of the supplied value type. Property names
+// are taken from the struct tag with tagKey name (ex: json, yaml),
+// descriptions from the "description" tag, properties with
+// required:"true" tag are required. Unknown properties are not
+// allowed
+func Generate(title string, v interface{}, tagKey string) *Schema {
+	s := generate(reflect.TypeOf(v), tagKey)
+	s.Schema = draft
+	s.Title = title
+	return s
+}
+
+func generate(t reflect.Type, tagKey string) *Schema {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return generate(t.Elem(), tagKey)
+	case reflect.Slice, reflect.Array:
+		return &Schema{
+			Type:  "array",
+			Items: generate(t.Elem(), tagKey),
+		}
+	case reflect.Struct:
+		additional := false
+		s := &Schema{
+			Type:                 "object",
+			Properties:           map[string]*Schema{},
+			AdditionalProperties: &additional,
+		}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			name := propertyName(field, tagKey)
+			if name == "-" {
+				continue
+			}
+			property := generate(field.Type, tagKey)
+			property.Description = field.Tag.Get(tagDescription)
+			s.Properties[name] = property
+			if field.Tag.Get(tagRequired) == "true" {
+				s.Required = append(s.Required, name)
+			}
+		}
+		return s
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	}
+
+	return &Schema{}
+}
+
+// propertyName returns name of the struct field in the file
+func propertyName(field reflect.StructField, tagKey string) string {
+	name := strings.Split(field.Tag.Get(tagKey), ",")[0]
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// Validate checks generic value (decoded JSON/YAML document)
+// against the schema. All found problems are returned, each one
+// with the path to the wrong value
+func Validate(s *Schema, v interface{}) error {
+	problems := validate(s, v, "")
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(problems, "; "))
+}
+
+func validate(s *Schema, v interface{}, path string) []string {
+	// null is the same as absent value
+	if v == nil {
+		return nil
+	}
+
+	at := path
+	if at == "" {
+		at = "document"
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: has to be an object", at)}
+		}
+		problems := []string{}
+		keys := []string{}
+		for key := range obj {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			property, ok := s.Properties[key]
+			if !ok {
+				if s.AdditionalProperties == nil || *s.AdditionalProperties {
+					continue
+				}
+				problems = append(problems,
+					fmt.Sprintf("%s: unknown field %q", at, key))
+				continue
+			}
+			problems = append(problems,
+				validate(property, obj[key], joinPath(path, key))...)
+		}
+		for _, key := range s.Required {
+			if _, ok := obj[key]; !ok {
+				problems = append(problems,
+					fmt.Sprintf("%s: required field %q is missing", at, key))
+			}
+		}
+		return problems
+	case "array":
+		arr, ok := v.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: has to be an array", at)}
+		}
+		problems := []string{}
+		for i, item := range arr {
+			problems = append(problems,
+				validate(s.Items, item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return problems
+	case "string":
+		if _, ok := v.(string); !ok {
+			return []string{fmt.Sprintf("%s: has to be a string", at)}
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return []string{fmt.Sprintf("%s: has to be a boolean", at)}
+		}
+	case "number":
+		if _, ok := toFloat(v); !ok {
+			return []string{fmt.Sprintf("%s: has to be a number", at)}
+		}
+	case "integer":
+		if f, ok := toFloat(v); !ok || f != math.Trunc(f) {
+			return []string{fmt.Sprintf("%s: has to be an integer", at)}
+		}
+	}
+
+	return nil
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// toFloat converts numeric value of decoded document
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	}
+	return 0, false
+}
@@ -0,0 +1,81 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testItem struct {
+	Name   string  `json:"name" required:"true" description:"item name"`
+	Weight float64 `json:"weight,omitempty"`
+	Count  int     `json:"count"`
+}
+
+type testDoc struct {
+	Title string      `json:"title"`
+	Items []*testItem `json:"items"`
+	Flag  bool        `json:"flag"`
+}
+
+func TestGenerate(t *testing.T) {
+	s := Generate("doc", testDoc{}, "json")
+
+	assert.Equal(t, "object", s.Type, "should be equal")
+	assert.Equal(t, "array", s.Properties["items"].Type, "should be equal")
+
+	item := s.Properties["items"].Items
+	assert.Equal(t, []string{"name"}, item.Required, "should be equal")
+	assert.Equal(t, "item name", item.Properties["name"].Description,
+		"should be equal")
+	assert.Equal(t, "number", item.Properties["weight"].Type, "should be equal")
+	assert.Equal(t, "integer", item.Properties["count"].Type, "should be equal")
+	assert.Equal(t, false, *item.AdditionalProperties, "should be equal")
+}
+
+func TestValidate(t *testing.T) {
+	s := Generate("doc", testDoc{}, "json")
+
+	tests := []struct {
+		doc string
+		err string
+	}{
+		{
+			doc: `{"title": "a", "items": [{"name": "b", "count": 2}], "flag": true}`,
+			err: "",
+		},
+		{
+			doc: `{"title": "a", "items": [{"nme": "b"}]}`,
+			err: `items[0]: unknown field "nme"; items[0]: required field "name" is missing`,
+		},
+		{
+			doc: `{"title": 1, "items": [{"name": "b", "count": 1.5}], "flag": "yes"}`,
+			err: "flag: has to be a boolean; items[0].count: has to be an integer; title: has to be a string",
+		},
+		{
+			doc: `[]`,
+			err: "document: has to be an object",
+		},
+	}
+
+	for i, test := range tests {
+		test := test
+		t.Run(fmt.Sprintf("validate_%d", i),
+			func(t *testing.T) {
+				t.Parallel()
+				var doc interface{}
+				if err := json.Unmarshal([]byte(test.doc), &doc); err != nil {
+					t.Fatalf("unable to parse test document: %v", err)
+				}
+
+				err := Validate(s, doc)
+				if test.err == "" {
+					assert.Nil(t, err, "should be valid")
+					return
+				}
+				assert.EqualError(t, err, test.err, "should be equal")
+			})
+	}
+}
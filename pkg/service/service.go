@@ -0,0 +1,321 @@
+package service
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	"github.com/bgzzz/kitchen/pkg/kitchenpb"
+	ordrs "github.com/bgzzz/kitchen/pkg/orders"
+	"github.com/bgzzz/kitchen/pkg/rack"
+	"github.com/bgzzz/kitchen/pkg/source"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// defaultEventBuffer is the number of events buffered for the watcher
+// that does not keep up with the rack
+const defaultEventBuffer = 256
+
+// eventTypes maps rack event types to the service ones
+var eventTypes = map[rack.EventType]kitchenpb.EventType{
+	rack.EventPlaced:    kitchenpb.EventType_EVENT_TYPE_PLACED,
+	rack.EventMoved:     kitchenpb.EventType_EVENT_TYPE_MOVED,
+	rack.EventDelivered: kitchenpb.EventType_EVENT_TYPE_DELIVERED,
+	rack.EventSpoiled:   kitchenpb.EventType_EVENT_TYPE_SPOILED,
+	rack.EventWasted:    kitchenpb.EventType_EVENT_TYPE_WASTED,
+	rack.EventCancelled: kitchenpb.EventType_EVENT_TYPE_CANCELLED,
+}
+
+// Kitchen is the gRPC kitchen service backed by the shelf rack.
+// Submitted orders are pushed to the queue the simulation reads
+// orders from, the rest of the calls are served by the rack
+type Kitchen struct {
+	kitchenpb.UnimplementedKitchenServer
+
+	log      *logrus.Entry
+	queue    *source.Queue
+	validate func(*ordrs.OrderOptions) error
+	// EventBuffer is the number of events buffered for every
+	// watcher, watch is ended in case watcher falls behind more
+	EventBuffer int
+
+	sr          *rack.ShelfRack
+	unsubscribe func()
+	server      *grpc.Server
+	lis         net.Listener
+
+	// submitted are the orders that are queued or on the rack, ID
+	// can be submitted again once its order leaves the rack
+	lock      sync.Mutex
+	submitted map[string]struct{}
+}
+
+// terminal are the rack events order leaves the rack with
+var terminal = map[rack.EventType]bool{
+	rack.EventDelivered: true,
+	rack.EventSpoiled:   true,
+	rack.EventWasted:    true,
+	rack.EventCancelled: true,
+}
+
+// New creates the kitchen service pushing submitted orders to the
+// queue. Validate checks the submitted order before it is queued
+func New(log *logrus.Entry, queue *source.Queue,
+	validate func(*ordrs.OrderOptions) error) *Kitchen {
+	return &Kitchen{
+		log:         log,
+		queue:       queue,
+		validate:    validate,
+		EventBuffer: defaultEventBuffer,
+		submitted:   map[string]struct{}{},
+	}
+}
+
+// Serve starts serving the rack on the address (ex: :50051).
+// Rack has to be initialized
+func (k *Kitchen) Serve(address string, sr *rack.ShelfRack) error {
+	lis, err := net.Listen("tcp", address)
+	if err != nil {
+		return errors.Wrap(err, "unable to listen for the kitchen service")
+	}
+
+	k.sr = sr
+	k.unsubscribe = sr.Subscribe(k.forget)
+	k.lis = lis
+	k.server = grpc.NewServer()
+	kitchenpb.RegisterKitchenServer(k.server, k)
+
+	go func() {
+		if err := k.server.Serve(lis); err != nil {
+			k.log.Errorf("kitchen service is stopped: %s", err.Error())
+		}
+	}()
+	k.log.Infof("kitchen service is listening on %s", lis.Addr())
+	return nil
+}
+
+// Addr returns the address service is listening on
+func (k *Kitchen) Addr() net.Addr {
+	return k.lis.Addr()
+}
+
+// Stop stops the service, event watches are ended
+func (k *Kitchen) Stop() {
+	k.server.Stop()
+	k.unsubscribe()
+}
+
+// forget releases ID of the order that left the rack, it is
+// subscribed to the rack events
+func (k *Kitchen) forget(event rack.Event) {
+	if !terminal[event.Type] {
+		return
+	}
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	delete(k.submitted, event.OrderID)
+}
+
+// SubmitOrder queues the order, it is put on the rack as soon as the
+// simulation reads it. It waits for room in the full queue until the
+// call context is done
+func (k *Kitchen) SubmitOrder(ctx context.Context,
+	req *kitchenpb.SubmitOrderRequest) (*kitchenpb.SubmitOrderResponse, error) {
+	if req.Order == nil {
+		return nil, status.Error(codes.InvalidArgument, "order is not set")
+	}
+
+	opts := orderOptions(req.Order)
+	if err := k.validate(opts); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// id is reserved, so the lock is not held while the queue is full
+	k.lock.Lock()
+	if _, ok := k.submitted[opts.ID]; ok {
+		k.lock.Unlock()
+		return nil, status.Errorf(codes.AlreadyExists,
+			"order %s is already submitted", opts.ID)
+	}
+	k.submitted[opts.ID] = struct{}{}
+	k.lock.Unlock()
+
+	if err := k.queue.Push(ctx, opts); err != nil {
+		k.lock.Lock()
+		delete(k.submitted, opts.ID)
+		k.lock.Unlock()
+
+		switch err {
+		case source.ErrQueueClosed:
+			return nil, status.Error(codes.Unavailable, "kitchen is closing")
+		case context.DeadlineExceeded:
+			return nil, status.Error(codes.DeadlineExceeded, "queue is full")
+		case context.Canceled:
+			return nil, status.Error(codes.Canceled, err.Error())
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &kitchenpb.SubmitOrderResponse{Id: opts.ID}, nil
+}
+
+// CancelOrder takes the order off the rack. Order that is just
+// submitted can be not on the rack yet
+func (k *Kitchen) CancelOrder(ctx context.Context,
+	req *kitchenpb.CancelOrderRequest) (*kitchenpb.CancelOrderResponse, error) {
+	if req.Id == "" {
+		return nil, status.Error(codes.InvalidArgument, "order id is not set")
+	}
+
+	value, ok := k.sr.Cancel(req.Id)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound,
+			"order %s is not on the rack", req.Id)
+	}
+	return &kitchenpb.CancelOrderResponse{Value: value}, nil
+}
+
+// GetRack returns the snapshot of the rack
+func (k *Kitchen) GetRack(ctx context.Context,
+	req *kitchenpb.GetRackRequest) (*kitchenpb.Rack, error) {
+	return rackSnapshot(k.sr.Snapshot())
+}
+
+// WatchEvents streams rack events of the requested types. Headers
+// are sent as soon as the watch is subscribed to the rack, so the
+// events that happen after the headers are received are streamed.
+// Watch is ended with ResourceExhausted in case watcher falls behind
+// the rack by more than EventBuffer events
+func (k *Kitchen) WatchEvents(req *kitchenpb.WatchEventsRequest,
+	stream kitchenpb.Kitchen_WatchEventsServer) error {
+	types := map[kitchenpb.EventType]bool{}
+	for _, t := range req.Types {
+		types[t] = true
+	}
+
+	events := make(chan rack.Event, k.EventBuffer)
+	overflow := make(chan struct{})
+	var once sync.Once
+	// subscribers are called by the rack event loop, so it is not
+	// blocked by the slow watcher
+	unsubscribe := k.sr.Subscribe(func(event rack.Event) {
+		if len(types) != 0 && !types[eventTypes[event.Type]] {
+			return
+		}
+		select {
+		case events <- event:
+		default:
+			once.Do(func() { close(overflow) })
+		}
+	})
+	defer unsubscribe()
+
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-overflow:
+			return status.Error(codes.ResourceExhausted,
+				"watcher falls behind the rack events")
+		case event := <-events:
+			ev, err := rackEvent(event)
+			if err != nil {
+				return status.Error(codes.Internal, err.Error())
+			}
+			if err := stream.Send(ev); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// orderOptions converts the submitted order to the order options
+func orderOptions(o *kitchenpb.Order) *ordrs.OrderOptions {
+	opts := &ordrs.OrderOptions{
+		ID:        o.Id,
+		Name:      o.Name,
+		Temp:      o.Temp,
+		ShelfLife: int(o.ShelfLife),
+		DecayRate: o.DecayRate,
+		Priority:  int(o.Priority),
+		Price:     o.Price,
+		Cost:      o.Cost,
+	}
+	for _, item := range o.Items {
+		opts.Items = append(opts.Items, &ordrs.ItemOptions{
+			Name:      item.Name,
+			Temp:      item.Temp,
+			ShelfLife: int(item.ShelfLife),
+			DecayRate: item.DecayRate,
+		})
+	}
+	return opts
+}
+
+// rackSnapshot converts the rack snapshot to the service one
+func rackSnapshot(snap *rack.Snapshot) (*kitchenpb.Rack, error) {
+	at, err := ptypes.TimestampProto(snap.Time)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	r := &kitchenpb.Rack{
+		Time: at,
+		Stats: &kitchenpb.Stats{
+			Orders:            int32(snap.Stats.Expected),
+			Delivered:         int32(snap.Stats.Delivered),
+			Wasted:            int32(snap.Stats.Wasted),
+			Spoiled:           int32(snap.Stats.Spoiled),
+			Cancelled:         int32(snap.Stats.Cancelled),
+			AvgDeliveredValue: snap.Stats.AvgDelivered,
+			AvgWastedValue:    snap.Stats.AvgWasted,
+			Profit:            snap.Stats.Profit,
+		},
+	}
+	for _, shelf := range snap.Shelves {
+		s := &kitchenpb.Shelf{
+			Name:     shelf.Name,
+			Temp:     shelf.Temp,
+			Capacity: int32(shelf.Capacity),
+		}
+		for _, ord := range shelf.Orders {
+			s.Orders = append(s.Orders, &kitchenpb.ShelfOrder{
+				Id:       ord.ID,
+				Name:     ord.Name,
+				Priority: int32(ord.Priority),
+				Value:    ord.Value,
+				AtRisk:   ord.AtRisk,
+			})
+		}
+		r.Shelves = append(r.Shelves, s)
+	}
+	return r, nil
+}
+
+// rackEvent converts the rack event to the service one
+func rackEvent(event rack.Event) (*kitchenpb.Event, error) {
+	at, err := ptypes.TimestampProto(event.Time)
+	if err != nil {
+		return nil, err
+	}
+	return &kitchenpb.Event{
+		Type:      eventTypes[event.Type],
+		Time:      at,
+		OrderId:   event.OrderID,
+		Name:      event.Name,
+		Temp:      event.Temp,
+		FromShelf: event.FromShelf,
+		ToShelf:   event.ToShelf,
+		Value:     event.Value,
+	}, nil
+}
@@ -0,0 +1,262 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bgzzz/kitchen/pkg/kitchenpb"
+	ordrs "github.com/bgzzz/kitchen/pkg/orders"
+	"github.com/bgzzz/kitchen/pkg/rack"
+	shvs "github.com/bgzzz/kitchen/pkg/shelves"
+	"github.com/bgzzz/kitchen/pkg/source"
+	"github.com/bgzzz/kitchen/pkg/stats"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// serve starts the kitchen service on the streaming rack, orders of
+// the queue are put on the rack as they are read
+func serve(t *testing.T) (*Kitchen, kitchenpb.KitchenClient, *source.Queue,
+	func()) {
+	shelves := []*shvs.Shelf{
+		{Name: "hot", Temp: "hot", Capacity: 2, ShelfDecayModifier: 1},
+		{Name: "overflow", Temp: shvs.OverflowShelfTemp, Capacity: 1,
+			ShelfDecayModifier: 1},
+	}
+	log := logrus.NewEntry(logrus.New())
+	sr := rack.NewShelfRack(log, stats.NewStats(rack.UnknownOrders),
+		shelves, rack.UnknownOrders, func() {})
+	sr.Init()
+
+	queue := source.NewQueue(10)
+	go func() {
+		for {
+			ord, err := queue.Next()
+			if err != nil {
+				return
+			}
+			sr.Interact(&rack.OrderEvent{
+				EventType: rack.OECreated,
+				Order: ordrs.NewOrder(ord.Opts, &ordrs.Config{
+					CourierReadyMin: 100,
+					CourierReadyMax: 100,
+				}, func(ord *ordrs.Order) {}, func(ord *ordrs.Order) {}),
+			})
+		}
+	}()
+
+	k := New(log, queue, func(opts *ordrs.OrderOptions) error {
+		if opts.Temp != "hot" {
+			return errors.New("unsupported temp")
+		}
+		return nil
+	})
+	if err := k.Serve("127.0.0.1:0", sr); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := grpc.Dial(k.Addr().String(), grpc.WithInsecure(),
+		grpc.WithBlock())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return k, kitchenpb.NewKitchenClient(conn), queue, func() {
+		conn.Close()
+		k.Stop()
+		queue.Close()
+	}
+}
+
+func order(id string) *kitchenpb.Order {
+	return &kitchenpb.Order{
+		Id:        id,
+		Name:      "soup",
+		Temp:      "hot",
+		ShelfLife: 100,
+		DecayRate: 0.1,
+	}
+}
+
+func TestKitchen(t *testing.T) {
+	_, client, queue, stop := serve(t)
+	defer stop()
+	ctx := context.Background()
+
+	watch, err := client.WatchEvents(ctx, &kitchenpb.WatchEventsRequest{
+		Types: []kitchenpb.EventType{
+			kitchenpb.EventType_EVENT_TYPE_PLACED,
+			kitchenpb.EventType_EVENT_TYPE_CANCELLED,
+		},
+	})
+	assert.Equal(t, nil, err, "should be equal")
+	// watch is subscribed once headers are received
+	_, err = watch.Header()
+	assert.Equal(t, nil, err, "should be equal")
+
+	tcs := []struct {
+		order *kitchenpb.Order
+		code  codes.Code
+	}{
+		{order: order("1"), code: codes.OK},
+		{order: order("1"), code: codes.AlreadyExists},
+		{order: &kitchenpb.Order{Id: "2", Name: "ice", Temp: "cold"},
+			code: codes.InvalidArgument},
+		{order: nil, code: codes.InvalidArgument},
+		{order: order("3"), code: codes.OK},
+	}
+	for _, tc := range tcs {
+		_, err := client.SubmitOrder(ctx, &kitchenpb.SubmitOrderRequest{
+			Order: tc.order,
+		})
+		assert.Equal(t, tc.code, status.Code(err), "should be equal")
+	}
+
+	for _, id := range []string{"1", "3"} {
+		ev, err := watch.Recv()
+		assert.Equal(t, nil, err, "should be equal")
+		assert.Equal(t, kitchenpb.EventType_EVENT_TYPE_PLACED, ev.Type,
+			"should be equal")
+		assert.Equal(t, id, ev.OrderId, "should be equal")
+		assert.Equal(t, "hot", ev.ToShelf, "should be equal")
+	}
+
+	r, err := client.GetRack(ctx, &kitchenpb.GetRackRequest{})
+	assert.Equal(t, nil, err, "should be equal")
+	assert.Equal(t, 2, len(r.Shelves), "should be equal")
+	assert.Equal(t, 2, len(r.Shelves[0].Orders), "should be equal")
+	assert.Equal(t, int32(2), r.Shelves[0].Capacity, "should be equal")
+
+	cancelled, err := client.CancelOrder(ctx,
+		&kitchenpb.CancelOrderRequest{Id: "1"})
+	assert.Equal(t, nil, err, "should be equal")
+	assert.Equal(t, true, cancelled.Value > 0.9, "should be equal")
+
+	_, err = client.CancelOrder(ctx, &kitchenpb.CancelOrderRequest{Id: "1"})
+	assert.Equal(t, codes.NotFound, status.Code(err), "should be equal")
+
+	ev, err := watch.Recv()
+	assert.Equal(t, nil, err, "should be equal")
+	assert.Equal(t, kitchenpb.EventType_EVENT_TYPE_CANCELLED, ev.Type,
+		"should be equal")
+	assert.Equal(t, "1", ev.OrderId, "should be equal")
+	assert.Equal(t, "hot", ev.FromShelf, "should be equal")
+
+	r, err = client.GetRack(ctx, &kitchenpb.GetRackRequest{})
+	assert.Equal(t, nil, err, "should be equal")
+	assert.Equal(t, 1, len(r.Shelves[0].Orders), "should be equal")
+	assert.Equal(t, int32(1), r.Stats.Cancelled, "should be equal")
+
+	// ID of the order that left the rack can be submitted again
+	_, err = client.SubmitOrder(ctx, &kitchenpb.SubmitOrderRequest{
+		Order: order("1"),
+	})
+	assert.Equal(t, codes.OK, status.Code(err), "should be equal")
+
+	queue.Close()
+	_, err = client.SubmitOrder(ctx, &kitchenpb.SubmitOrderRequest{
+		Order: order("4"),
+	})
+	assert.Equal(t, codes.Unavailable, status.Code(err), "should be equal")
+}
+
+func TestSubmitOrderQueueFull(t *testing.T) {
+	queue := source.NewQueue(1)
+	defer queue.Close()
+	k := New(logrus.NewEntry(logrus.New()), queue,
+		func(*ordrs.OrderOptions) error { return nil })
+	submit := func(ctx context.Context, id string) error {
+		_, err := k.SubmitOrder(ctx, &kitchenpb.SubmitOrderRequest{
+			Order: order(id),
+		})
+		return err
+	}
+	assert.Equal(t, nil, submit(context.Background(), "1"), "should be equal")
+
+	// submission waiting for room does not block the other ones
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	submitted := make(chan error)
+	go func() {
+		submitted <- submit(ctx, "2")
+	}()
+	time.Sleep(20 * time.Millisecond)
+	short, cancelShort := context.WithTimeout(context.Background(),
+		20*time.Millisecond)
+	defer cancelShort()
+	assert.Equal(t, codes.AlreadyExists, status.Code(submit(short, "1")),
+		"should be equal")
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(submit(short, "3")),
+		"should be equal")
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(<-submitted),
+		"should be equal")
+
+	// id of the order that is not queued can be submitted again
+	_, err := queue.Next()
+	assert.Equal(t, nil, err, "should be equal")
+	assert.Equal(t, nil, submit(context.Background(), "2"), "should be equal")
+}
+
+// blockedStream is the watch stream that does not send events until
+// it is released
+type blockedStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	release chan struct{}
+}
+
+func (s *blockedStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *blockedStream) SendHeader(metadata.MD) error {
+	return nil
+}
+
+func (s *blockedStream) Send(*kitchenpb.Event) error {
+	<-s.release
+	return nil
+}
+
+func TestWatchOverflow(t *testing.T) {
+	k, client, _, stop := serve(t)
+	defer stop()
+	k.EventBuffer = 1
+
+	stream := &blockedStream{
+		ctx:     context.Background(),
+		release: make(chan struct{}),
+	}
+	watched := make(chan error)
+	go func() {
+		watched <- k.WatchEvents(&kitchenpb.WatchEventsRequest{}, stream)
+	}()
+	// watch is subscribed before it sends headers
+	time.Sleep(100 * time.Millisecond)
+
+	// the first event is being sent, the second one is buffered and
+	// the third one does not fit
+	for i := 0; i < 3; i++ {
+		_, err := client.SubmitOrder(context.Background(),
+			&kitchenpb.SubmitOrderRequest{Order: order(fmt.Sprint(i))})
+		assert.Equal(t, nil, err, "should be equal")
+	}
+	for placed := 0; placed != 3; {
+		placed = 0
+		for _, shelf := range k.sr.Snapshot().Shelves {
+			placed += len(shelf.Orders)
+		}
+	}
+	close(stream.release)
+
+	err := <-watched
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err),
+		"should be equal")
+}
@@ -2,10 +2,10 @@ package shelves
 
 // Shelf is a structure defining the kitchen shelf options
 type Shelf struct {
-	Name               string
-	Temp               string
-	Capacity           int
-	ShelfDecayModifier int
+	Name               string `json:"name" yaml:"name" required:"true" description:"Unique shelf name"`
+	Temp               string `json:"temp" yaml:"temp" required:"true" description:"Temperature of the orders stored on the shelf, \"any\" for the overflow shelf"`
+	Capacity           int    `json:"capacity" yaml:"capacity" description:"Max number of orders on the shelf"`
+	ShelfDecayModifier int    `json:"shelfDecayModifier" yaml:"shelfDecayModifier" description:"Multiplier of the order decay rate while it is on the shelf"`
 }
 
 const (
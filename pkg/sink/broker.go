@@ -0,0 +1,66 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/bgzzz/kitchen/pkg/broker"
+	"github.com/pkg/errors"
+)
+
+// Broker publishes records to the subject of the message broker,
+// record per message
+type Broker struct {
+	publisher broker.Publisher
+	closer    io.Closer
+	subject   string
+}
+
+// OpenBroker connects to the NATS server records are published to
+func OpenBroker(url, subject string) (*Broker, error) {
+	conn, err := broker.Dial(url)
+	if err != nil {
+		return nil, err
+	}
+	return NewBroker(conn, conn, subject), nil
+}
+
+// NewBroker creates sink publishing to the subject by the publisher.
+// Closer (ex: broker connection) is closed with the sink, it can
+// be nil
+func NewBroker(publisher broker.Publisher, closer io.Closer,
+	subject string) *Broker {
+	return &Broker{
+		publisher: publisher,
+		closer:    closer,
+		subject:   subject,
+	}
+}
+
+// Write publishes the records one by one, publishing is stopped once
+// the context is done. Publish of the single record is bounded by the
+// write timeout of the broker connection
+func (bs *Broker) Write(ctx context.Context, records []Record) error {
+	for _, rec := range records {
+		if err := ctx.Err(); err != nil {
+			return errors.Wrap(err, "publishing is given up")
+		}
+		data, err := json.Marshal(rec)
+		if err != nil {
+			return errors.Wrap(err, "unable to marshal record")
+		}
+		if err := bs.publisher.Publish(bs.subject, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the broker connection
+func (bs *Broker) Close() error {
+	if bs.closer == nil {
+		return nil
+	}
+	return bs.closer.Close()
+}
@@ -0,0 +1,112 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// File writes records to the NDJSON file, record per line. File is
+// rotated when it exceeds the max size: path is renamed to path.1,
+// path.1 to path.2 and so on, the ones beyond max files are removed
+type File struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+
+	f    *os.File
+	size int64
+}
+
+// OpenFile opens the file sink, records are appended to the existing
+// file. MaxSize is the size of the file in bytes it is rotated at, 0
+// disables rotation. MaxFiles is the number of the rotated files kept
+func OpenFile(path string, maxSize int64, maxFiles int) (*File, error) {
+	fs := &File{
+		path:     path,
+		maxSize:  maxSize,
+		maxFiles: maxFiles,
+	}
+	if err := fs.open(); err != nil {
+		return nil, err
+	}
+	return fs, nil
+}
+
+// open opens the file for appending
+func (fs *File) open() error {
+	f, err := os.OpenFile(fs.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return errors.Wrap(err, "unable to open sink file")
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return errors.Wrap(err, "unable to open sink file")
+	}
+
+	fs.f = f
+	fs.size = info.Size()
+	return nil
+}
+
+// Write appends the records to the file, file is rotated before the
+// record that does not fit it
+func (fs *File) Write(ctx context.Context, records []Record) error {
+	for _, rec := range records {
+		line, err := json.Marshal(rec)
+		if err != nil {
+			return errors.Wrap(err, "unable to marshal record")
+		}
+		line = append(line, '\n')
+
+		if fs.maxSize > 0 && fs.size > 0 && fs.size+int64(len(line)) > fs.maxSize {
+			if err := fs.rotate(); err != nil {
+				return err
+			}
+		}
+
+		n, err := fs.f.Write(line)
+		fs.size += int64(n)
+		if err != nil {
+			return errors.Wrap(err, "unable to write sink file")
+		}
+	}
+	return nil
+}
+
+// rotate shifts the rotated files and starts the new file
+func (fs *File) rotate() error {
+	if err := fs.f.Close(); err != nil {
+		return errors.Wrap(err, "unable to close sink file")
+	}
+
+	rotated := func(i int) string {
+		return fmt.Sprintf("%s.%d", fs.path, i)
+	}
+	if fs.maxFiles > 0 {
+		os.Remove(rotated(fs.maxFiles))
+		for i := fs.maxFiles - 1; i >= 1; i-- {
+			if _, err := os.Stat(rotated(i)); err == nil {
+				if err := os.Rename(rotated(i), rotated(i+1)); err != nil {
+					return errors.Wrap(err, "unable to rotate sink file")
+				}
+			}
+		}
+		if err := os.Rename(fs.path, rotated(1)); err != nil {
+			return errors.Wrap(err, "unable to rotate sink file")
+		}
+	} else if err := os.Remove(fs.path); err != nil {
+		return errors.Wrap(err, "unable to rotate sink file")
+	}
+
+	return fs.open()
+}
+
+// Close closes the file
+func (fs *File) Close() error {
+	return fs.f.Close()
+}
@@ -0,0 +1,65 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// ids returns order IDs of the NDJSON records of the file
+func ids(t *testing.T, path string) []string {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		var rec Record
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, rec.OrderID)
+	}
+	return ids
+}
+
+func TestFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sink")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "events.ndjson")
+
+	line, _ := json.Marshal(Record{Event: EventPlaced, OrderID: "1"})
+	// every file holds 2 records
+	fs, err := OpenFile(path, int64(2*(len(line)+1)), 2)
+	assert.Nil(t, err, "should be nil")
+
+	records := []Record{}
+	for _, id := range []string{"1", "2", "3", "4", "5", "6", "7"} {
+		records = append(records, Record{Event: EventPlaced, OrderID: id})
+	}
+	assert.Nil(t, fs.Write(context.Background(), records[:3]), "should be nil")
+	assert.Nil(t, fs.Write(context.Background(), records[3:]), "should be nil")
+	assert.Nil(t, fs.Close(), "should be nil")
+
+	assert.Equal(t, []string{"7"}, ids(t, path), "should be equal")
+	assert.Equal(t, []string{"5", "6"}, ids(t, path+".1"), "should be equal")
+	assert.Equal(t, []string{"3", "4"}, ids(t, path+".2"), "should be equal")
+	_, err = os.Stat(path + ".3")
+	assert.Equal(t, true, os.IsNotExist(err), "the oldest file is removed")
+
+	// records are appended to the existing file
+	fs, err = OpenFile(path, 0, 0)
+	assert.Nil(t, err, "should be nil")
+	assert.Nil(t, fs.Write(context.Background(), records[:1]), "should be nil")
+	assert.Nil(t, fs.Close(), "should be nil")
+	assert.Equal(t, []string{"7", "1"}, ids(t, path), "should be equal")
+}
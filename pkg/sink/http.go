@@ -0,0 +1,130 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Poster posts JSON bodies to the URL. Failed post is retried up to
+// Retries times in case of network errors, 5xx and 429 responses,
+// Backoff is the delay before the first retry, it is doubled for
+// every next one
+type Poster struct {
+	URL     string
+	Client  *http.Client
+	Retries int
+	Backoff time.Duration
+}
+
+// NewPoster creates poster with the timeout of the single post
+func NewPoster(url string, timeout time.Duration, retries int,
+	backoff time.Duration) *Poster {
+	return &Poster{
+		URL:     url,
+		Client:  &http.Client{Timeout: timeout},
+		Retries: retries,
+		Backoff: backoff,
+	}
+}
+
+// Post posts the body with the headers retrying in case of the
+// temporary failure. Retrying is given up once the context is done
+func (ps *Poster) Post(ctx context.Context, body []byte,
+	header http.Header) error {
+	backoff := ps.Backoff
+	for attempt := 0; ; attempt++ {
+		retriable, err := ps.send(ctx, body, header)
+		if err == nil {
+			return nil
+		}
+
+		if !retriable {
+			return err
+		}
+		if attempt >= ps.Retries {
+			return errors.Wrap(err,
+				fmt.Sprintf("unable to post after %d attempts", attempt+1))
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return errors.Wrap(err, "posting is given up")
+		}
+		backoff *= 2
+	}
+}
+
+// send does single post of the body. In case of failure it returns
+// whether post may succeed later
+func (ps *Poster) send(ctx context.Context, body []byte,
+	header http.Header) (retriable bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, ps.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, errors.Wrap(err, "unable to create request")
+	}
+	req = req.WithContext(ctx)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := ps.Client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, errors.Wrap(err, "unable to post")
+	}
+	defer res.Body.Close()
+	// body is drained, so connection is reused
+	io.Copy(ioutil.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		// server errors and rate limiting may be temporary
+		retriable = res.StatusCode >= 500 ||
+			res.StatusCode == http.StatusTooManyRequests
+		return retriable, errors.New(fmt.Sprintf(
+			"unexpected response status %s", res.Status))
+	}
+	return false, nil
+}
+
+// Close releases idle connections
+func (ps *Poster) Close() error {
+	ps.Client.CloseIdleConnections()
+	return nil
+}
+
+// HTTP posts batches of records to the URL as the JSON array
+type HTTP struct {
+	poster *Poster
+}
+
+// NewHTTP creates http sink, failed posts are retried the way Poster
+// does it
+func NewHTTP(url string, timeout time.Duration, retries int,
+	backoff time.Duration) *HTTP {
+	return &HTTP{
+		poster: NewPoster(url, timeout, retries, backoff),
+	}
+}
+
+// Write posts the batch retrying in case of the temporary failure
+func (hs *HTTP) Write(ctx context.Context, records []Record) error {
+	body, err := json.Marshal(records)
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal records")
+	}
+	return hs.poster.Post(ctx, body, nil)
+}
+
+// Close releases idle connections
+func (hs *HTTP) Close() error {
+	return hs.poster.Close()
+}
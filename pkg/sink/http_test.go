@@ -0,0 +1,59 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTP(t *testing.T) {
+	var lock sync.Mutex
+	batches := [][]Record{}
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		attempts++
+		// the first post fails temporarily
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var batch []Record
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"),
+			"should be equal")
+		batches = append(batches, batch)
+	}))
+	defer srv.Close()
+
+	hs := NewHTTP(srv.URL, time.Second, 1, time.Millisecond)
+	records := []Record{
+		{Event: EventDelivered, OrderID: "1", Value: 0.5},
+		{Event: EventWasted, OrderID: "2"},
+	}
+	assert.Nil(t, hs.Write(context.Background(), records), "should be nil")
+	assert.Equal(t, 2, attempts, "should be equal")
+	assert.Equal(t, [][]Record{records}, batches, "batch is posted at once")
+
+	// retries are over
+	attempts = 0
+	hs = NewHTTP(srv.URL, time.Second, 0, time.Millisecond)
+	assert.NotNil(t, hs.Write(context.Background(), records), "should not be nil")
+
+	// closed sink does not retry
+	attempts = 0
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	hs = NewHTTP(srv.URL, time.Second, 3, time.Hour)
+	assert.NotNil(t, hs.Write(ctx, records), "should not be nil")
+}
@@ -0,0 +1,367 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bgzzz/kitchen/pkg/rack"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Types of the sinks
+const (
+	TypeFile   = "file"
+	TypeHTTP   = "http"
+	TypeBroker = "broker"
+)
+
+// Policies of the events that do not fit the buffer
+const (
+	// PolicyDrop drops the new event
+	PolicyDrop = "drop"
+	// PolicyDropOldest drops the oldest buffered event
+	PolicyDropOldest = "drop-oldest"
+	// PolicyBlock holds the rack event loop until there is room in
+	// the buffer or publisher gives up writing on close
+	PolicyBlock = "block"
+)
+
+// Event names of the sink config and records
+const (
+	EventPlaced    = "placed"
+	EventMoved     = "moved"
+	EventDelivered = "delivered"
+	EventSpoiled   = "spoiled"
+	EventWasted    = "wasted"
+	EventCancelled = "cancelled"
+)
+
+// defaultDrainTimeout is the time buffered events are written for
+// when publisher is closed
+const defaultDrainTimeout = 10 * time.Second
+
+// names are the sink event names of the rack events
+var names = map[rack.EventType]string{
+	rack.EventPlaced:    EventPlaced,
+	rack.EventMoved:     EventMoved,
+	rack.EventDelivered: EventDelivered,
+	rack.EventSpoiled:   EventSpoiled,
+	rack.EventWasted:    EventWasted,
+	rack.EventCancelled: EventCancelled,
+}
+
+// Events returns sorted names of the supported events
+func Events() []string {
+	events := []string{}
+	for _, name := range names {
+		events = append(events, name)
+	}
+	sort.Strings(events)
+	return events
+}
+
+// Types returns supported sink types
+func Types() []string {
+	return []string{TypeFile, TypeHTTP, TypeBroker}
+}
+
+// Policies returns supported policies of the full buffer
+func Policies() []string {
+	return []string{PolicyDrop, PolicyDropOldest, PolicyBlock}
+}
+
+// Record is the rack event written to the sink
+type Record struct {
+	Event   string    `json:"event"`
+	Time    time.Time `json:"time"`
+	OrderID string    `json:"orderId"`
+	Name    string    `json:"name,omitempty"`
+	// Temp is the temp of the order, empty for multi-item orders
+	Temp string `json:"temp,omitempty"`
+	// FromShelf and ToShelf are the temps of the shelf order was on
+	// before and after the event
+	FromShelf string  `json:"fromShelf,omitempty"`
+	ToShelf   string  `json:"toShelf,omitempty"`
+	Value     float64 `json:"value"`
+	// Kitchen is the name of the kitchen of the order in case
+	// several kitchens are simulated
+	Kitchen string `json:"kitchen,omitempty"`
+}
+
+// EventSink is the destination rack events are written to
+type EventSink interface {
+	// Write writes the batch of records in order. Context is
+	// cancelled in case writing is given up (ex: retries of the
+	// failed write)
+	Write(ctx context.Context, records []Record) error
+	// Close releases the sink
+	Close() error
+}
+
+// Options of the sink and its publisher
+type Options struct {
+	Type string
+	// Events are the names of the written events, all supported
+	// events are written in case it is empty
+	Events []string
+
+	// Path, MaxSize and MaxFiles are the settings of the file sink
+	Path     string
+	MaxSize  int64
+	MaxFiles int
+
+	// URL is the http(s) link of the http sink or the NATS server
+	// of the broker sink
+	URL string
+	// Subject is the subject of the broker sink
+	Subject string
+	// Timeout, Retries and Backoff are the settings of the http sink
+	Timeout time.Duration
+	Retries int
+	Backoff time.Duration
+
+	// BufferSize is the number of events waiting to be written
+	BufferSize int
+	// BatchSize is the max number of events written at once
+	BatchSize int
+	// FlushInterval is the max time event waits for the batch to
+	// be filled
+	FlushInterval time.Duration
+	// Policy defines what happens to the event that does not fit
+	// the buffer
+	Policy string
+	// DrainTimeout is the time buffered events are written for when
+	// publisher is closed, the rest are not written
+	DrainTimeout time.Duration
+}
+
+// Open creates the sink of the options type and starts publishing
+// events to it
+func Open(log *logrus.Entry, opts Options) (*Publisher, error) {
+	var s EventSink
+	var err error
+	switch opts.Type {
+	case TypeFile:
+		s, err = OpenFile(opts.Path, opts.MaxSize, opts.MaxFiles)
+	case TypeHTTP:
+		s = NewHTTP(opts.URL, opts.Timeout, opts.Retries, opts.Backoff)
+	case TypeBroker:
+		s, err = OpenBroker(opts.URL, opts.Subject)
+	default:
+		err = errors.New(fmt.Sprintf("unknown sink type %s", opts.Type))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	name := "sink " + opts.Type + " " + opts.Path + opts.URL
+	return NewPublisher(log, name, s, opts), nil
+}
+
+// Publisher buffers rack events and writes them to the sink in
+// batches by its goroutine, so rack event loop is not blocked by the
+// slow sink unless block policy is used
+type Publisher struct {
+	log    *logrus.Entry
+	name   string
+	sink   EventSink
+	opts   Options
+	events map[string]bool
+
+	buffer chan Record
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+	once   sync.Once
+	// closing guards closed buffer from the notified events
+	closing sync.RWMutex
+	closed  bool
+
+	lock    sync.Mutex
+	dropped int
+	failed  int
+}
+
+// NewPublisher starts publishing events to the sink, name is the sink
+// reference used in the log and errors (ex: sink file events.ndjson)
+func NewPublisher(log *logrus.Entry, name string, s EventSink,
+	opts Options) *Publisher {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = 1
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = time.Second
+	}
+	if opts.DrainTimeout <= 0 {
+		opts.DrainTimeout = defaultDrainTimeout
+	}
+	events := map[string]bool{}
+	for _, event := range opts.Events {
+		events[event] = true
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Publisher{
+		log:    log.WithField("output", name),
+		name:   name,
+		sink:   s,
+		opts:   opts,
+		events: events,
+		buffer: make(chan Record, opts.BufferSize),
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// Notify buffers the rack event, it is supposed to be subscribed to
+// the rack events. It blocks only in case of block policy, events
+// notified after the publisher is closed are ignored
+func (p *Publisher) Notify(event rack.Event) {
+	name, ok := names[event.Type]
+	if !ok || (len(p.events) != 0 && !p.events[name]) {
+		return
+	}
+
+	p.closing.RLock()
+	defer p.closing.RUnlock()
+	if p.closed {
+		return
+	}
+	rec := Record{
+		Event:     name,
+		Time:      event.Time,
+		OrderID:   event.OrderID,
+		Name:      event.Name,
+		Temp:      event.Temp,
+		FromShelf: event.FromShelf,
+		ToShelf:   event.ToShelf,
+		Value:     event.Value,
+		Kitchen:   event.Kitchen,
+	}
+
+	switch p.opts.Policy {
+	case PolicyBlock:
+		// stalled sink does not hold the rack after the drain timeout
+		select {
+		case p.buffer <- rec:
+		case <-p.ctx.Done():
+			p.drop(1)
+		}
+	case PolicyDropOldest:
+		for {
+			select {
+			case p.buffer <- rec:
+				return
+			default:
+			}
+			select {
+			case <-p.buffer:
+				p.drop(1)
+			default:
+			}
+		}
+	default:
+		select {
+		case p.buffer <- rec:
+		default:
+			p.drop(1)
+		}
+	}
+}
+
+// Close writes buffered events and closes the sink. Events that are
+// not written within the drain timeout are given up together with the
+// blocked notifications. It returns error in case any event was not
+// written. Events notified after Close are ignored, so publisher can
+// be closed before it is unsubscribed from the rack
+func (p *Publisher) Close() error {
+	p.once.Do(func() {
+		drain := time.AfterFunc(p.opts.DrainTimeout, p.cancel)
+		defer drain.Stop()
+
+		// blocked notifications are released by the buffer writes
+		// or the drain timeout
+		p.closing.Lock()
+		p.closed = true
+		close(p.buffer)
+		p.closing.Unlock()
+		<-p.done
+	})
+	<-p.done
+	p.cancel()
+
+	err := p.sink.Close()
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.dropped != 0 || p.failed != 0 {
+		return errors.New(fmt.Sprintf("%s: %d events dropped as buffer is full, %d events failed to write",
+			p.name, p.dropped, p.failed))
+	}
+	if err != nil {
+		return errors.Wrap(err, p.name)
+	}
+	return nil
+}
+
+// run writes buffered events in batches until the buffer is closed.
+// Batch is written when it is full or flush interval passes
+func (p *Publisher) run() {
+	defer close(p.done)
+	ticker := time.NewTicker(p.opts.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Record, 0, p.opts.BatchSize)
+	for {
+		select {
+		case rec, ok := <-p.buffer:
+			if !ok {
+				p.write(batch)
+				return
+			}
+			batch = append(batch, rec)
+			if len(batch) >= p.opts.BatchSize {
+				p.write(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			p.write(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+// write writes the batch to the sink
+func (p *Publisher) write(batch []Record) {
+	if len(batch) == 0 {
+		return
+	}
+
+	err := p.ctx.Err()
+	if err == nil {
+		err = p.sink.Write(p.ctx, batch)
+	}
+	if err != nil {
+		p.log.Warnf("%d events are not written: %v", len(batch), err)
+		p.lock.Lock()
+		p.failed += len(batch)
+		p.lock.Unlock()
+	}
+}
+
+// drop counts events dropped as buffer is full
+func (p *Publisher) drop(n int) {
+	p.lock.Lock()
+	p.dropped += n
+	p.lock.Unlock()
+}
@@ -0,0 +1,303 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bgzzz/kitchen/pkg/broker"
+	"github.com/bgzzz/kitchen/pkg/broker/brokertest"
+	"github.com/bgzzz/kitchen/pkg/rack"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// memory is the sink keeping written batches, it waits for release
+// before the first write in case release is set
+type memory struct {
+	lock    sync.Mutex
+	batches [][]string
+	release chan struct{}
+	closed  bool
+}
+
+func (m *memory) Write(ctx context.Context, records []Record) error {
+	if m.release != nil {
+		<-m.release
+	}
+	batch := []string{}
+	for _, rec := range records {
+		batch = append(batch, rec.OrderID)
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.batches = append(m.batches, batch)
+	return nil
+}
+
+func (m *memory) Close() error {
+	m.closed = true
+	return nil
+}
+
+func (m *memory) written() [][]string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.batches
+}
+
+func event(id string, t rack.EventType) rack.Event {
+	return rack.Event{Type: t, OrderID: id, Time: time.Now()}
+}
+
+func TestPublisher(t *testing.T) {
+	log := logrus.NewEntry(logrus.New())
+
+	tests := []struct {
+		opts    Options
+		events  []rack.Event
+		batches [][]string
+		err     string
+	}{
+		// batches are written when they are full and on close
+		{
+			opts: Options{BufferSize: 10, BatchSize: 2, FlushInterval: time.Hour},
+			events: []rack.Event{
+				event("1", rack.EventPlaced),
+				event("1", rack.EventMoved),
+				event("1", rack.EventDelivered),
+			},
+			batches: [][]string{{"1", "1"}, {"1"}},
+		},
+		// events are filtered
+		{
+			opts: Options{BufferSize: 10, BatchSize: 10,
+				Events: []string{EventWasted, EventSpoiled}},
+			events: []rack.Event{
+				event("1", rack.EventPlaced),
+				event("1", rack.EventWasted),
+				event("2", rack.EventPlaced),
+				event("2", rack.EventSpoiled),
+			},
+			batches: [][]string{{"1", "2"}},
+		},
+		// new events are dropped while sink is blocked, the first one
+		// is being written
+		{
+			opts: Options{BufferSize: 2, BatchSize: 1, Policy: PolicyDrop},
+			events: []rack.Event{
+				event("1", rack.EventPlaced),
+				event("2", rack.EventPlaced),
+				event("3", rack.EventPlaced),
+				event("4", rack.EventPlaced),
+			},
+			batches: [][]string{{"1"}, {"2"}, {"3"}},
+			err:     "1 events dropped as buffer is full",
+		},
+		// the oldest events are dropped
+		{
+			opts: Options{BufferSize: 2, BatchSize: 1, Policy: PolicyDropOldest},
+			events: []rack.Event{
+				event("1", rack.EventPlaced),
+				event("2", rack.EventPlaced),
+				event("3", rack.EventPlaced),
+				event("4", rack.EventPlaced),
+			},
+			batches: [][]string{{"1"}, {"3"}, {"4"}},
+			err:     "1 events dropped as buffer is full",
+		},
+	}
+
+	for i, test := range tests {
+		m := &memory{release: make(chan struct{})}
+		p := NewPublisher(log, fmt.Sprint(i), m, test.opts)
+
+		p.Notify(test.events[0])
+		// the first event is taken by the writer
+		for len(p.buffer) != 0 {
+			time.Sleep(time.Millisecond)
+		}
+		for _, e := range test.events[1:] {
+			p.Notify(e)
+		}
+		close(m.release)
+
+		err := p.Close()
+		if test.err == "" {
+			assert.Nil(t, err, "test %d", i)
+		} else {
+			assert.Equal(t, true, err != nil && strings.Contains(err.Error(), test.err),
+				"test %d: %v", i, err)
+		}
+		assert.Equal(t, test.batches, m.written(), "test %d", i)
+		assert.Equal(t, true, m.closed, "should be equal")
+	}
+}
+
+func TestPublisherBlock(t *testing.T) {
+	m := &memory{release: make(chan struct{})}
+	p := NewPublisher(logrus.NewEntry(logrus.New()), "block", m, Options{
+		BufferSize: 1,
+		BatchSize:  1,
+		Policy:     PolicyBlock,
+	})
+
+	p.Notify(event("1", rack.EventPlaced))
+	p.Notify(event("2", rack.EventPlaced))
+
+	// buffer is full, so rack waits until the sink takes the event
+	notified := make(chan struct{})
+	go func() {
+		p.Notify(event("3", rack.EventPlaced))
+		close(notified)
+	}()
+	select {
+	case <-notified:
+		t.Fatal("event is not supposed to fit the buffer")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(m.release)
+	<-notified
+	assert.Nil(t, p.Close(), "should be nil")
+	assert.Equal(t, [][]string{{"1"}, {"2"}, {"3"}}, m.written(),
+		"nothing is dropped")
+}
+
+// stalled is the sink that never writes, it gives up once the
+// context is done
+type stalled struct{}
+
+func (stalled) Write(ctx context.Context, records []Record) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (stalled) Close() error {
+	return nil
+}
+
+func TestPublisherBlockClose(t *testing.T) {
+	p := NewPublisher(logrus.NewEntry(logrus.New()), "stalled", stalled{}, Options{
+		BufferSize:   1,
+		BatchSize:    1,
+		Policy:       PolicyBlock,
+		DrainTimeout: 50 * time.Millisecond,
+	})
+
+	// first event is taken by the stalled write, second one fills
+	// the buffer, third one blocks the rack
+	notified := make(chan struct{})
+	go func() {
+		for _, id := range []string{"1", "2", "3"} {
+			p.Notify(event(id, rack.EventPlaced))
+		}
+		close(notified)
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	start := time.Now()
+	assert.NotNil(t, p.Close(), "events should not be written")
+	<-notified
+	assert.True(t, time.Since(start) < time.Second,
+		"close should not wait for the stalled sink")
+}
+
+func TestPublisherFlush(t *testing.T) {
+	m := &memory{}
+	p := NewPublisher(logrus.NewEntry(logrus.New()), "flush", m, Options{
+		BufferSize:    10,
+		BatchSize:     10,
+		FlushInterval: 10 * time.Millisecond,
+	})
+	defer p.Close()
+
+	p.Notify(event("1", rack.EventPlaced))
+	deadline := time.Now().Add(5 * time.Second)
+	for len(m.written()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, [][]string{{"1"}}, m.written(),
+		"incomplete batch is written after flush interval")
+}
+
+func TestPublisherClosed(t *testing.T) {
+	m := &memory{}
+	p := NewPublisher(logrus.NewEntry(logrus.New()), "closed", m, Options{})
+	assert.Nil(t, p.Close(), "should be nil")
+
+	// rack can still emit events after the publisher is closed
+	assert.NotPanics(t, func() {
+		p.Notify(event("1", rack.EventPlaced))
+	}, "notify should not panic")
+	assert.Equal(t, 0, len(m.written()), "should be equal")
+}
+
+func TestBroker(t *testing.T) {
+	srv := brokertest.NewServer()
+	defer srv.Close()
+
+	p, err := Open(logrus.NewEntry(logrus.New()), Options{
+		Type:       TypeBroker,
+		URL:        srv.URL(),
+		Subject:    "kitchen.events",
+		BufferSize: 10,
+		BatchSize:  10,
+	})
+	assert.Nil(t, err, "should be nil")
+
+	conn, err := broker.Dial(srv.URL())
+	assert.Nil(t, err, "should be nil")
+	defer conn.Close()
+	sub, err := conn.Subscribe("kitchen.events", "", 10)
+	assert.Nil(t, err, "should be nil")
+
+	p.Notify(rack.Event{
+		Type:      rack.EventMoved,
+		OrderID:   "1",
+		Name:      "soup",
+		FromShelf: "hot",
+		ToShelf:   "any",
+		Value:     0.5,
+	})
+	p.Notify(event("2", rack.EventWasted))
+	assert.Nil(t, p.Close(), "should be nil")
+
+	for _, expected := range []Record{
+		{Event: EventMoved, OrderID: "1", Name: "soup", FromShelf: "hot",
+			ToShelf: "any", Value: 0.5},
+		{Event: EventWasted, OrderID: "2"},
+	} {
+		msg, err := sub.Next()
+		assert.Nil(t, err, "should be nil")
+		var rec Record
+		assert.Nil(t, json.Unmarshal(msg.Data(), &rec), "should be nil")
+		rec.Time = time.Time{}
+		assert.Equal(t, expected, rec, "should be equal")
+	}
+}
+
+// countingPublisher counts published messages
+type countingPublisher struct {
+	published int
+}
+
+func (cp *countingPublisher) Publish(subject string, data []byte) error {
+	cp.published++
+	return nil
+}
+
+func TestBrokerCancelled(t *testing.T) {
+	cp := &countingPublisher{}
+	bs := NewBroker(cp, nil, "kitchen.events")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.NotNil(t, bs.Write(ctx, []Record{{OrderID: "1"}, {OrderID: "2"}}),
+		"publishing should be given up")
+	assert.Equal(t, 0, cp.published, "should be equal")
+}
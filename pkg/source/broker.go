@@ -0,0 +1,81 @@
+package source
+
+import (
+	"io"
+	"sync"
+
+	"github.com/bgzzz/kitchen/pkg/broker"
+)
+
+// BrokerSource provides orders consumed from the message broker,
+// every message is a single JSON order record. Orders arrive as soon
+// as they are consumed unless their record has arrival time. Message
+// is acknowledged once its order is put on the rack (see Order.Ack),
+// so orders are processed at least once: redelivered order is skipped
+// as the duplicate. Broken records are acknowledged right away, they
+// are never processed.
+// Follow and FollowIdle of the stream options are not used
+type BrokerSource struct {
+	consumer broker.Consumer
+	closer   io.Closer
+	parser   *recordParser
+	read     int
+
+	closeOnce sync.Once
+}
+
+// NewBrokerSource creates source of the orders consumed by the
+// consumer. Closer (ex: broker connection) is closed with the source,
+// it can be nil
+func NewBrokerSource(consumer broker.Consumer, closer io.Closer,
+	opts StreamOptions) *BrokerSource {
+	return &BrokerSource{
+		consumer: consumer,
+		closer:   closer,
+		parser:   newRecordParser(opts),
+	}
+}
+
+// Next returns the order of the next message, it blocks until
+// message arrives
+func (bs *BrokerSource) Next() (*Order, error) {
+	msg, err := bs.consumer.Next()
+	if err != nil {
+		return nil, err
+	}
+	bs.read++
+
+	ord, err := bs.parser.parse(msg.Data())
+	if err != nil {
+		recordErr := &RecordError{
+			Line: bs.read,
+			Err:  err,
+		}
+		if ackErr := msg.Ack(); ackErr != nil {
+			return nil, ackErr
+		}
+		return nil, recordErr
+	}
+
+	if !ord.Timed {
+		// offset is 0, so order arrives right away
+		ord.Timed = true
+	}
+	ord.Ack = msg.Ack
+	return ord, nil
+}
+
+// Close stops consuming, messages that are consumed but not
+// acknowledged are redelivered by the broker
+func (bs *BrokerSource) Close() error {
+	var err error
+	bs.closeOnce.Do(func() {
+		err = bs.consumer.Close()
+		if bs.closer != nil {
+			if closeErr := bs.closer.Close(); err == nil {
+				err = closeErr
+			}
+		}
+	})
+	return err
+}
@@ -0,0 +1,254 @@
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bgzzz/kitchen/pkg/orders"
+	"github.com/bgzzz/kitchen/pkg/schema"
+	"github.com/pkg/errors"
+)
+
+// followPollInterval is the period of checking the followed
+// file for the appended records
+const followPollInterval = 200 * time.Millisecond
+
+// StreamOptions defines how the stream of orders is read
+type StreamOptions struct {
+	// Follow waits for the records appended to the file
+	// instead of stopping at its end
+	Follow bool
+	// FollowIdle stops following the file in case nothing is
+	// appended to it during this period, 0 means never stop
+	FollowIdle time.Duration
+	// TimestampField is the name of the record field containing
+	// arrival time of the order (RFC3339 string or unix seconds).
+	// Arrival offsets are counted from the first timed record
+	TimestampField string
+	// Schema is the schema of the single order record
+	Schema *schema.Schema
+	// Validate checks the order read from the record
+	Validate func(opts *orders.OrderOptions) error
+}
+
+// NDJSONSource streams orders from the newline delimited JSON,
+// every line is a single order record
+type NDJSONSource struct {
+	opts    StreamOptions
+	reader  *bufio.Reader
+	closer  io.Closer
+	partial []byte
+	line    int
+	parser  *recordParser
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// OpenNDJSON opens the file (or standard input in case path is "-")
+// as the stream of orders
+func OpenNDJSON(path string, opts StreamOptions) (*NDJSONSource, error) {
+	if path == "-" {
+		return NewNDJSONSource(os.Stdin, nil, opts), nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to open orders stream")
+	}
+
+	return NewNDJSONSource(f, f, opts), nil
+}
+
+// NewNDJSONSource creates the stream of orders read from r. Closer
+// is closed with the source, it can be nil
+func NewNDJSONSource(r io.Reader, closer io.Closer, opts StreamOptions) *NDJSONSource {
+	return &NDJSONSource{
+		opts:   opts,
+		reader: bufio.NewReader(r),
+		closer: closer,
+		parser: newRecordParser(opts),
+		done:   make(chan struct{}),
+	}
+}
+
+// Next returns next order of the stream
+func (ns *NDJSONSource) Next() (*Order, error) {
+	for {
+		line, err := ns.readLine()
+		if err != nil {
+			return nil, err
+		}
+		ns.line++
+
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		ord, err := ns.parser.parse(line)
+		if err != nil {
+			return nil, &RecordError{
+				Line: ns.line,
+				Err:  err,
+			}
+		}
+
+		return ord, nil
+	}
+}
+
+// Close stops reading of the stream
+func (ns *NDJSONSource) Close() error {
+	var err error
+	ns.closeOnce.Do(func() {
+		close(ns.done)
+		if ns.closer != nil {
+			err = ns.closer.Close()
+		}
+	})
+	return err
+}
+
+// readLine returns the next complete line of the stream, in follow
+// mode it waits until the line is appended
+func (ns *NDJSONSource) readLine() ([]byte, error) {
+	idleSince := time.Now()
+	for {
+		select {
+		case <-ns.done:
+			return nil, io.EOF
+		default:
+		}
+
+		chunk, err := ns.reader.ReadBytes('\n')
+		ns.partial = append(ns.partial, chunk...)
+		if err == nil {
+			line := ns.partial
+			ns.partial = nil
+			return line, nil
+		}
+		if err != io.EOF {
+			return nil, errors.Wrap(err, "unable to read orders stream")
+		}
+
+		stop := !ns.opts.Follow
+		if len(chunk) != 0 {
+			idleSince = time.Now()
+		}
+		if ns.opts.FollowIdle > 0 && time.Since(idleSince) >= ns.opts.FollowIdle {
+			stop = true
+		}
+
+		if stop {
+			// the last line may not be terminated
+			if len(ns.partial) != 0 {
+				line := ns.partial
+				ns.partial = nil
+				return line, nil
+			}
+			return nil, io.EOF
+		}
+
+		select {
+		case <-ns.done:
+			return nil, io.EOF
+		case <-time.After(followPollInterval):
+		}
+	}
+}
+
+// recordParser converts JSON records into orders
+type recordParser struct {
+	opts StreamOptions
+	// clock counts absolute arrival times from the first
+	// timed record
+	clock arrivalClock
+	ids   map[string]struct{}
+}
+
+func newRecordParser(opts StreamOptions) *recordParser {
+	return &recordParser{
+		opts: opts,
+		ids:  map[string]struct{}{},
+	}
+}
+
+// parse converts the record into the order
+func (rp *recordParser) parse(line []byte) (*Order, error) {
+	var record map[string]interface{}
+	if err := json.Unmarshal(line, &record); err != nil {
+		return nil, errors.Wrap(err, "unable to parse json")
+	}
+
+	ord := &Order{
+		Opts: &orders.OrderOptions{},
+	}
+
+	if field := rp.opts.TimestampField; field != "" {
+		if v, ok := record[field]; ok {
+			ts, err := parseTimestamp(v)
+			if err != nil {
+				return nil, errors.Wrap(err, fmt.Sprintf("field %s", field))
+			}
+			delete(record, field)
+
+			ord.Offset = rp.clock.offset(ts)
+			ord.Timed = true
+		}
+	}
+
+	if rp.opts.Schema != nil {
+		if err := schema.Validate(rp.opts.Schema, record); err != nil {
+			return nil, errors.Wrap(err, "order does not match schema")
+		}
+	}
+
+	b, err := json.Marshal(record)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to convert record")
+	}
+	if err := json.Unmarshal(b, ord.Opts); err != nil {
+		return nil, errors.Wrap(err, "unable to parse order")
+	}
+
+	if _, ok := rp.ids[ord.Opts.ID]; ok {
+		return nil, errors.New(fmt.Sprintf("order with id %s was previously defined",
+			ord.Opts.ID))
+	}
+
+	if rp.opts.Validate != nil {
+		if err := rp.opts.Validate(ord.Opts); err != nil {
+			return nil, err
+		}
+	}
+
+	// timestamp field takes precedence over arrival of the order
+	if !ord.Timed {
+		if err := rp.clock.arrival(ord); err != nil {
+			return nil, err
+		}
+	}
+	rp.ids[ord.Opts.ID] = struct{}{}
+
+	return ord, nil
+}
+
+// parseTimestamp parses RFC3339 string or unix seconds
+func parseTimestamp(v interface{}) (time.Time, error) {
+	switch ts := v.(type) {
+	case string:
+		return time.Parse(time.RFC3339Nano, ts)
+	case float64:
+		sec, frac := math.Modf(ts)
+		return time.Unix(int64(sec), int64(frac*float64(time.Second))), nil
+	}
+
+	return time.Time{}, errors.New("timestamp has to be RFC3339 string or unix seconds")
+}
@@ -0,0 +1,84 @@
+package source
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/bgzzz/kitchen/pkg/orders"
+	"github.com/pkg/errors"
+)
+
+// ErrQueueClosed is returned by Push in case the queue is closed
+var ErrQueueClosed = errors.New("queue is closed")
+
+// Queue provides orders submitted while simulation runs (ex: by the
+// kitchen service clients). Orders arrive immediately, so they are
+// created as soon as they are read
+type Queue struct {
+	orders chan *Order
+	lock   sync.RWMutex
+	closed bool
+	// closing releases pushes waiting for room before the queue
+	// is closed
+	closing     chan struct{}
+	closingOnce sync.Once
+}
+
+// NewQueue creates queue holding up to size submitted orders that
+// are not read yet
+func NewQueue(size int) *Queue {
+	return &Queue{
+		orders:  make(chan *Order, size),
+		closing: make(chan struct{}),
+	}
+}
+
+// Push adds the order to the queue, it blocks while the queue is full
+// until the context is done. It returns ErrQueueClosed in case queue
+// is closed and the context error in case context is done first
+func (q *Queue) Push(ctx context.Context, opts *orders.OrderOptions) error {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+
+	select {
+	case q.orders <- &Order{
+		Opts:  opts,
+		Timed: true,
+	}:
+		return nil
+	case <-q.closing:
+		return ErrQueueClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Next returns next submitted order, it blocks until order is
+// submitted or queue is closed
+func (q *Queue) Next() (*Order, error) {
+	ord, ok := <-q.orders
+	if !ok {
+		return nil, io.EOF
+	}
+	return ord, nil
+}
+
+// Close stops accepting orders, orders that are already submitted
+// are read before io.EOF
+func (q *Queue) Close() error {
+	q.closingOnce.Do(func() {
+		close(q.closing)
+	})
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if !q.closed {
+		q.closed = true
+		close(q.orders)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package source
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/bgzzz/kitchen/pkg/orders"
+	"github.com/pkg/errors"
+)
+
+// Order is the order read from the source
+type Order struct {
+	Opts *orders.OrderOptions
+	// Offset is the arrival time of the order relative to the start
+	// of the simulation, it is set only in case Timed is true
+	Offset time.Duration
+	Timed  bool
+	// Ack acknowledges the order is put on the rack, it is nil in
+	// case source does not need it
+	Ack func() error
+}
+
+// OrderSource provides orders for the simulation one by one
+type OrderSource interface {
+	// Next returns next order of the source, io.EOF is returned
+	// in case there are no more orders. *RecordError is returned
+	// in case of the problem with a single record, source can be
+	// read further after it
+	Next() (*Order, error)
+	// Close releases the source, Next returns io.EOF after it
+	Close() error
+}
+
+// RecordError is the problem of the single record of the source
+type RecordError struct {
+	// Line is the number of the line record is located on
+	// (number of the entry in case of the list, number of the
+	// message in case of the broker)
+	Line int
+	Err  error
+}
+
+// Error returns problem description with the line context
+func (re *RecordError) Error() string {
+	return fmt.Sprintf("line %d: %s", re.Line, re.Err.Error())
+}
+
+// arrivalClock converts arrival times of the orders into offsets
+// from the simulation start
+type arrivalClock struct {
+	// first is the earliest absolute arrival time
+	first *time.Time
+}
+
+// offset returns offset of the absolute arrival time, orders
+// arriving before the first one arrive immediately
+func (ac *arrivalClock) offset(ts time.Time) time.Duration {
+	if ac.first == nil {
+		ac.first = &ts
+	}
+	offset := ts.Sub(*ac.first)
+	if offset < 0 {
+		offset = 0
+	}
+	return offset
+}
+
+// arrival sets arrival offset of the order defined by its options
+func (ac *arrivalClock) arrival(ord *Order) error {
+	if ord.Opts.ArrivalOffset != nil {
+		ord.Offset = time.Duration(*ord.Opts.ArrivalOffset * float64(time.Second))
+		ord.Timed = true
+		return nil
+	}
+
+	if ord.Opts.ArrivalTime != "" {
+		ts, err := time.Parse(time.RFC3339Nano, ord.Opts.ArrivalTime)
+		if err != nil {
+			return errors.Wrap(err, "arrival time is not RFC3339")
+		}
+		ord.Offset = ac.offset(ts)
+		ord.Timed = true
+	}
+
+	return nil
+}
+
+// ListSource provides orders from the list that is already loaded.
+// Absolute arrival times are counted from the earliest one of the
+// list. Orders with arrival time are provided in the order of their
+// arrival, the rest keep their place in the list
+type ListSource struct {
+	entries []listEntry
+}
+
+// listEntry is the order of the list with its resolved arrival
+type listEntry struct {
+	ord *Order
+	// line is the number of the entry in the list
+	line int
+	err  error
+}
+
+// NewListSource creates source of the supplied orders
+func NewListSource(opts []*orders.OrderOptions) *ListSource {
+	var clock arrivalClock
+	for _, o := range opts {
+		ts, err := time.Parse(time.RFC3339Nano, o.ArrivalTime)
+		if err != nil {
+			continue
+		}
+		if clock.first == nil || ts.Before(*clock.first) {
+			first := ts
+			clock.first = &first
+		}
+	}
+
+	entries := []listEntry{}
+	timed := []int{}
+	for i, o := range opts {
+		entry := listEntry{
+			ord:  &Order{Opts: o},
+			line: i + 1,
+		}
+		entry.err = clock.arrival(entry.ord)
+		if entry.err == nil && entry.ord.Timed {
+			timed = append(timed, i)
+		}
+		entries = append(entries, entry)
+	}
+
+	// whole list is known, so timed orders are not created late
+	// behind the ones listed before them
+	sorted := []listEntry{}
+	for _, i := range timed {
+		sorted = append(sorted, entries[i])
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].ord.Offset < sorted[j].ord.Offset
+	})
+	for k, i := range timed {
+		entries[i] = sorted[k]
+	}
+
+	return &ListSource{
+		entries: entries,
+	}
+}
+
+// Next returns next order of the list
+func (ls *ListSource) Next() (*Order, error) {
+	if len(ls.entries) == 0 {
+		return nil, io.EOF
+	}
+
+	entry := ls.entries[0]
+	ls.entries = ls.entries[1:]
+	if entry.err != nil {
+		return nil, &RecordError{
+			Line: entry.line,
+			Err:  errors.Wrap(entry.err, fmt.Sprintf("order %s", entry.ord.Opts.ID)),
+		}
+	}
+
+	return entry.ord, nil
+}
+
+// Close drops the rest of the list
+func (ls *ListSource) Close() error {
+	ls.entries = nil
+	return nil
+}
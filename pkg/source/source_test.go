@@ -0,0 +1,354 @@
+package source
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bgzzz/kitchen/pkg/broker"
+	"github.com/bgzzz/kitchen/pkg/orders"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// readAll returns IDs of the orders read from the source, their
+// offsets and the lines of the bad records
+func readAll(t *testing.T, src OrderSource) ([]string, []time.Duration, []int) {
+	ids := []string{}
+	offsets := []time.Duration{}
+	badLines := []int{}
+	for {
+		ord, err := src.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var recordErr *RecordError
+			if !errors.As(err, &recordErr) {
+				t.Fatal(err)
+			}
+			badLines = append(badLines, recordErr.Line)
+			continue
+		}
+		ids = append(ids, ord.Opts.ID)
+		offsets = append(offsets, ord.Offset)
+	}
+	return ids, offsets, badLines
+}
+
+func TestListSource(t *testing.T) {
+	src := NewListSource([]*orders.OrderOptions{
+		{ID: "1"},
+		{ID: "2"},
+	})
+
+	ids, _, badLines := readAll(t, src)
+	assert.Equal(t, []string{"1", "2"}, ids, "should be equal")
+	assert.Equal(t, []int{}, badLines, "should be equal")
+
+	offset := 1.5
+	src = NewListSource([]*orders.OrderOptions{
+		{ID: "1", ArrivalTime: "2020-01-01T00:00:10Z"},
+		{ID: "2", ArrivalTime: "2020-01-01T00:00:05Z"},
+		{ID: "3", ArrivalOffset: &offset},
+		{ID: "4"},
+	})
+
+	ids, offsets, badLines := readAll(t, src)
+	assert.Equal(t, []string{"2", "3", "1", "4"}, ids,
+		"timed orders should be read in the order of arrival")
+	assert.Equal(t, []time.Duration{
+		0, 1500 * time.Millisecond, 5 * time.Second, 0,
+	}, offsets, "arrival times should be counted from the earliest one")
+	assert.Equal(t, []int{}, badLines, "should be equal")
+
+	early, late := 1.0, 2.0
+	src = NewListSource([]*orders.OrderOptions{
+		{ID: "1", ArrivalOffset: &late},
+		{ID: "2"},
+		{ID: "3", ArrivalTime: "yesterday"},
+		{ID: "4", ArrivalOffset: &early},
+		{ID: "5", ArrivalOffset: &late},
+	})
+
+	ids, offsets, badLines = readAll(t, src)
+	assert.Equal(t, []string{"4", "2", "1", "5"}, ids,
+		"orders without arrival keep their place, equal arrivals keep the list order")
+	assert.Equal(t, []time.Duration{
+		time.Second, 0, 2 * time.Second, 2 * time.Second,
+	}, offsets, "should be equal")
+	assert.Equal(t, []int{3}, badLines, "should be equal")
+}
+
+func TestQueue(t *testing.T) {
+	ctx := context.Background()
+	q := NewQueue(2)
+	assert.Nil(t, q.Push(ctx, &orders.OrderOptions{ID: "1"}), "should be nil")
+	assert.Nil(t, q.Push(ctx, &orders.OrderOptions{ID: "2"}), "should be nil")
+	q.Close()
+	assert.Equal(t, ErrQueueClosed, q.Push(ctx, &orders.OrderOptions{ID: "3"}),
+		"should be equal")
+
+	ord, err := q.Next()
+	assert.Nil(t, err, "should be nil")
+	assert.Equal(t, true, ord.Timed, "submitted orders arrive immediately")
+
+	ids, offsets, badLines := readAll(t, q)
+	assert.Equal(t, []string{"2"}, ids, "submitted orders are read after close")
+	assert.Equal(t, []time.Duration{0}, offsets, "should be equal")
+	assert.Equal(t, []int{}, badLines, "should be equal")
+}
+
+func TestQueueFull(t *testing.T) {
+	q := NewQueue(1)
+	assert.Nil(t, q.Push(context.Background(), &orders.OrderOptions{ID: "1"}),
+		"should be nil")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded,
+		q.Push(ctx, &orders.OrderOptions{ID: "2"}), "should be equal")
+
+	// close releases the push waiting for room
+	pushed := make(chan error)
+	go func() {
+		pushed <- q.Push(context.Background(), &orders.OrderOptions{ID: "3"})
+	}()
+	time.Sleep(20 * time.Millisecond)
+	q.Close()
+	assert.Equal(t, ErrQueueClosed, <-pushed, "should be equal")
+}
+
+// fakeMessage is the broker message recording its acknowledgment
+type fakeMessage struct {
+	data  string
+	acked *[]string
+}
+
+func (m *fakeMessage) Data() []byte {
+	return []byte(m.data)
+}
+
+func (m *fakeMessage) Ack() error {
+	*m.acked = append(*m.acked, m.data)
+	return nil
+}
+
+// fakeConsumer consumes the list of messages
+type fakeConsumer struct {
+	msgs   []string
+	acked  []string
+	closed bool
+}
+
+func (fc *fakeConsumer) Next() (broker.Message, error) {
+	if fc.closed || len(fc.msgs) == 0 {
+		return nil, io.EOF
+	}
+	msg := &fakeMessage{data: fc.msgs[0], acked: &fc.acked}
+	fc.msgs = fc.msgs[1:]
+	return msg, nil
+}
+
+func (fc *fakeConsumer) Close() error {
+	fc.closed = true
+	return nil
+}
+
+func TestBrokerSource(t *testing.T) {
+	consumer := &fakeConsumer{
+		msgs: []string{
+			`{"id": "1"}`,
+			`{"id": `,
+			`{"id": "2", "ts": 10}`,
+			// redelivered message
+			`{"id": "1"}`,
+			`{"id": "3", "ts": 12}`,
+		},
+	}
+	src := NewBrokerSource(consumer, nil, StreamOptions{TimestampField: "ts"})
+
+	ids := []string{}
+	offsets := []time.Duration{}
+	badLines := []int{}
+	for {
+		ord, err := src.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var recordErr *RecordError
+			if !errors.As(err, &recordErr) {
+				t.Fatal(err)
+			}
+			badLines = append(badLines, recordErr.Line)
+			continue
+		}
+		assert.Equal(t, true, ord.Timed, "consumed orders arrive immediately")
+		// message is acknowledged once order is on the rack
+		assert.Nil(t, ord.Ack(), "should be nil")
+		ids = append(ids, ord.Opts.ID)
+		offsets = append(offsets, ord.Offset)
+	}
+
+	assert.Equal(t, []string{"1", "2", "3"}, ids, "should be equal")
+	assert.Equal(t, []time.Duration{0, 0, 2 * time.Second}, offsets,
+		"should be equal")
+	assert.Equal(t, []int{2, 4}, badLines, "should be equal")
+	assert.Equal(t, []string{`{"id": "1"}`, `{"id": `, `{"id": "2", "ts": 10}`,
+		`{"id": "1"}`, `{"id": "3", "ts": 12}`}, consumer.acked,
+		"every message is acknowledged")
+
+	assert.Nil(t, src.Close(), "should be nil")
+	assert.Equal(t, true, consumer.closed, "should be equal")
+}
+
+func TestNDJSONSource(t *testing.T) {
+
+	tests := []struct {
+		input    string
+		opts     StreamOptions
+		ids      []string
+		offsets  []time.Duration
+		badLines []int
+	}{
+		// plain records, the last one is not terminated
+		{
+			input: "{\"id\": \"1\"}\n\n{\"id\": \"2\"}",
+			ids:   []string{"1", "2"},
+			offsets: []time.Duration{
+				0, 0,
+			},
+			badLines: []int{},
+		},
+
+		// bad json and duplicate id are skipped
+		{
+			input:    "{\"id\": \"1\"}\n{\"id\": \n{\"id\": \"1\"}\n{\"id\": \"2\"}\n",
+			ids:      []string{"1", "2"},
+			offsets:  []time.Duration{0, 0},
+			badLines: []int{2, 3},
+		},
+
+		// timestamps of both formats, out of order record
+		// arrives immediately
+		{
+			input: "{\"id\": \"1\", \"at\": \"2020-01-01T00:00:10Z\"}\n" +
+				"{\"id\": \"2\", \"at\": 1577836812.5}\n" +
+				"{\"id\": \"3\", \"at\": \"2020-01-01T00:00:00Z\"}\n" +
+				"{\"id\": \"4\", \"at\": true}\n",
+			opts: StreamOptions{
+				TimestampField: "at",
+			},
+			ids: []string{"1", "2", "3"},
+			offsets: []time.Duration{
+				0, 2500 * time.Millisecond, 0,
+			},
+			badLines: []int{4},
+		},
+
+		// arrival of the order options, timestamp field
+		// takes precedence
+		{
+			input: "{\"id\": \"1\", \"arrivalTime\": \"2020-01-01T00:00:01Z\"}\n" +
+				"{\"id\": \"2\", \"arrivalOffset\": 0.5}\n" +
+				"{\"id\": \"3\", \"arrivalTime\": \"2020-01-01T00:00:04Z\"}\n" +
+				"{\"id\": \"4\", \"arrivalOffset\": 0.5, \"at\": \"2020-01-01T00:00:03Z\"}\n",
+			opts: StreamOptions{
+				TimestampField: "at",
+			},
+			ids: []string{"1", "2", "3", "4"},
+			offsets: []time.Duration{
+				0, 500 * time.Millisecond, 3 * time.Second, 2 * time.Second,
+			},
+			badLines: []int{},
+		},
+
+		// validation
+		{
+			input: "{\"id\": \"1\"}\n{\"id\": \"\"}\n",
+			opts: StreamOptions{
+				Validate: func(opts *orders.OrderOptions) error {
+					if opts.ID == "" {
+						return errors.New("empty id")
+					}
+					return nil
+				},
+			},
+			ids:      []string{"1"},
+			offsets:  []time.Duration{0},
+			badLines: []int{2},
+		},
+	}
+
+	for _, test := range tests {
+		src := NewNDJSONSource(strings.NewReader(test.input), nil, test.opts)
+		ids, offsets, badLines := readAll(t, src)
+		assert.Equal(t, test.ids, ids, "should be equal")
+		assert.Equal(t, test.offsets, offsets, "should be equal")
+		assert.Equal(t, test.badLines, badLines, "should be equal")
+	}
+}
+
+func TestNDJSONSourceFollow(t *testing.T) {
+	dir, err := ioutil.TempDir("", "source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "orders.ndjson")
+	if err := ioutil.WriteFile(path, []byte("{\"id\": \"1\"}\n{\"id\""), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := OpenNDJSON(path, StreamOptions{
+		Follow:     true,
+		FollowIdle: time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return
+		}
+		defer f.Close()
+		f.WriteString(": \"2\"}\n")
+	}()
+
+	ids, _, badLines := readAll(t, src)
+	assert.Equal(t, []string{"1", "2"}, ids,
+		"appended record should be read")
+	assert.Equal(t, []int{}, badLines, "should be equal")
+
+	// closed source stops following
+	src, err = OpenNDJSON(path, StreamOptions{
+		Follow: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	readDone := make(chan bool)
+	go func() {
+		readAll(t, src)
+		readDone <- true
+	}()
+
+	time.Sleep(300 * time.Millisecond)
+	src.Close()
+	select {
+	case <-readDone:
+	case <-time.After(time.Second):
+		t.Error("closed source should stop following")
+	}
+}
@@ -1,71 +1,287 @@
 package stats
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+	"sort"
+)
 
-// Stats structure to keep common stats
-type Stats struct {
+// Order describes processed order from the stats point of view
+type Order struct {
+	Priority int
+	// Value is the order value at the moment of processing
+	Value float64
+	// Price is the amount customer pays for the order
+	Price float64
+	// Cost is the ingredient cost of the order
+	Cost float64
+}
+
+// Economics defines how money outcomes of the orders are calculated
+type Economics struct {
+	// ScaleRevenueByValue scales order price by the delivered value
+	ScaleRevenueByValue bool
+	// RefundThreshold is the delivered value below which
+	// price is refunded to the customer
+	RefundThreshold float64
+}
+
+// outcomes keeps values of processed orders
+type outcomes struct {
 	wastedValues    []float64
 	deliveredValues []float64
 	spoiled         int
-	expected        int
+	cancelled       int
+}
+
+// money keeps money outcomes of processed orders
+type money struct {
+	revenue        float64
+	refunds        float64
+	refunded       int
+	wasteCost      float64
+	ingredientCost float64
+}
+
+// Stats structure to keep common stats
+type Stats struct {
+	outcomes
+	money
+	// Economics is applied to calculate money outcomes
+	Economics Economics
+	// byPriority keeps outcomes per order priority class
+	byPriority map[int]*outcomes
+	expected   int
 }
 
 // NewStats creates new stats object
 func NewStats(expected int) *Stats {
 	return &Stats{
-		expected: expected,
+		expected:   expected,
+		byPriority: make(map[int]*outcomes),
+	}
+}
+
+// priorityClass returns outcomes of the supplied priority class
+func (st *Stats) priorityClass(priority int) *outcomes {
+	if st.byPriority == nil {
+		st.byPriority = make(map[int]*outcomes)
 	}
+	if _, ok := st.byPriority[priority]; !ok {
+		st.byPriority[priority] = &outcomes{}
+	}
+	return st.byPriority[priority]
 }
 
 // Delivered add delivered values to the stats
-func (st *Stats) Delivered(v float64) {
-	st.deliveredValues = append(st.deliveredValues, v)
+func (st *Stats) Delivered(ord Order) {
+	st.deliveredValues = append(st.deliveredValues, ord.Value)
+	class := st.priorityClass(ord.Priority)
+	class.deliveredValues = append(class.deliveredValues, ord.Value)
+
+	revenue := ord.Price
+	if st.Economics.ScaleRevenueByValue {
+		revenue = ord.Price * math.Max(0, math.Min(1, ord.Value))
+	}
+	st.revenue += revenue
+	if ord.Value < st.Economics.RefundThreshold {
+		st.refunds += revenue
+		st.refunded++
+	}
+	st.ingredientCost += ord.Cost
 }
 
 // Wasted add wasted values to the stats
-func (st *Stats) Wasted(v float64) {
-	st.wastedValues = append(st.wastedValues, v)
+func (st *Stats) Wasted(ord Order) {
+	st.wastedValues = append(st.wastedValues, ord.Value)
+	class := st.priorityClass(ord.Priority)
+	class.wastedValues = append(class.wastedValues, ord.Value)
+
+	st.wasteCost += ord.Cost
+	st.ingredientCost += ord.Cost
 }
 
 // Spoiled add spoiled cases to the stats
-func (st *Stats) Spoiled() {
+func (st *Stats) Spoiled(ord Order) {
 	st.spoiled++
+	st.priorityClass(ord.Priority).spoiled++
+
+	st.wasteCost += ord.Cost
+	st.ingredientCost += ord.Cost
 }
 
-// AvgWasted return average value of wasted orders
-func (st *Stats) AvgWasted() float64 {
-	if len(st.wastedValues) == 0 {
-		return 0
-	}
+// Cancelled add cancelled cases to the stats, cancelled order is
+// already cooked, so its ingredients are wasted
+func (st *Stats) Cancelled(ord Order) {
+	st.cancelled++
+	st.priorityClass(ord.Priority).cancelled++
 
-	var sum float64
-	for _, v := range st.wastedValues {
-		sum += v
+	st.wasteCost += ord.Cost
+	st.ingredientCost += ord.Cost
+}
+
+// add adds the other outcomes to the outcomes
+func (o *outcomes) add(other *outcomes) {
+	o.wastedValues = append(o.wastedValues, other.wastedValues...)
+	o.deliveredValues = append(o.deliveredValues, other.deliveredValues...)
+	o.spoiled += other.spoiled
+	o.cancelled += other.cancelled
+}
+
+// Merge returns stats combining the supplied ones (ex: stats of
+// several kitchens). Number of orders to process is known only in
+// case it is known for all of them
+func Merge(all ...*Stats) *Stats {
+	merged := NewStats(0)
+	known := true
+	for _, st := range all {
+		merged.outcomes.add(&st.outcomes)
+		for priority, class := range st.byPriority {
+			merged.priorityClass(priority).add(class)
+		}
+
+		merged.revenue += st.revenue
+		merged.refunds += st.refunds
+		merged.refunded += st.refunded
+		merged.wasteCost += st.wasteCost
+		merged.ingredientCost += st.ingredientCost
+
+		if st.expected <= 0 {
+			known = false
+		}
+		merged.expected += st.expected
+	}
+	if !known {
+		merged.expected = 0
 	}
+	return merged
+}
+
+// Revenue return amount earned for the delivered orders
+// before refunds
+func (st *Stats) Revenue() float64 {
+	return st.revenue
+}
+
+// Refunds return amount refunded for the low value deliveries
+func (st *Stats) Refunds() float64 {
+	return st.refunds
+}
 
-	return sum / float64(len(st.wastedValues))
+// WasteCost return ingredient cost of wasted and spoiled orders
+func (st *Stats) WasteCost() float64 {
+	return st.wasteCost
+}
+
+// Profit return revenue left after refunds and ingredient cost
+// of all processed orders
+func (st *Stats) Profit() float64 {
+	return st.revenue - st.refunds - st.ingredientCost
+}
+
+// AvgWasted return average value of wasted orders
+func (st *Stats) AvgWasted() float64 {
+	return average(st.wastedValues)
 }
 
 // AvgDelivered return average value of delivered orders
 func (st *Stats) AvgDelivered() float64 {
-	if len(st.deliveredValues) == 0 {
+	return average(st.deliveredValues)
+}
+
+func average(values []float64) float64 {
+	if len(values) == 0 {
 		return 0
 	}
 
 	var sum float64
-	for _, v := range st.deliveredValues {
+	for _, v := range values {
 		sum += v
 	}
 
-	return sum / float64(len(st.deliveredValues))
+	return sum / float64(len(values))
+}
+
+// Summary is the snapshot of the gathered stats
+type Summary struct {
+	// Expected is the number of orders to process, the number of
+	// processed ones in case it is not known upfront
+	Expected     int
+	Delivered    int
+	Wasted       int
+	Spoiled      int
+	Cancelled    int
+	AvgDelivered float64
+	AvgWasted    float64
+	Profit       float64
+}
+
+// Summary return the snapshot of the gathered stats
+func (st *Stats) Summary() Summary {
+	return Summary{
+		Expected:     st.expectedOrders(),
+		Delivered:    len(st.deliveredValues),
+		Wasted:       len(st.wastedValues),
+		Spoiled:      st.spoiled,
+		Cancelled:    st.cancelled,
+		AvgDelivered: st.AvgDelivered(),
+		AvgWasted:    st.AvgWasted(),
+		Profit:       st.Profit(),
+	}
+}
+
+// expectedOrders return number of orders to process
+func (st *Stats) expectedOrders() int {
+	// number of streamed orders is known only after they are processed
+	if st.expected <= 0 {
+		return len(st.deliveredValues) + len(st.wastedValues) + st.spoiled +
+			st.cancelled
+	}
+	return st.expected
 }
 
 // String return formatted output of the gathered stats
 func (st *Stats) String() string {
-	return fmt.Sprintf("\n\tDelivered %d/%d, avg value %f\n"+
+	expected := st.expectedOrders()
+
+	output := fmt.Sprintf("\n\tDelivered %d/%d, avg value %f\n"+
 		"\tWasted %d/%d, avg value %f\n"+
-		"\tSpoiled %d/%d", len(st.deliveredValues), st.expected,
+		"\tSpoiled %d/%d", len(st.deliveredValues), expected,
 		st.AvgDelivered(),
-		len(st.wastedValues), st.expected, st.AvgWasted(),
-		st.spoiled, st.expected)
+		len(st.wastedValues), expected, st.AvgWasted(),
+		st.spoiled, expected)
+
+	// orders are cancelled only by the kitchen service clients
+	if st.cancelled != 0 {
+		output = fmt.Sprintf("%s\n\tCancelled %d/%d", output, st.cancelled,
+			expected)
+	}
+
+	if st.revenue != 0 || st.ingredientCost != 0 {
+		output = fmt.Sprintf("%s\n\tRevenue $%.2f, refunds $%.2f (%d orders), "+
+			"waste cost $%.2f, ingredient cost $%.2f, profit $%.2f", output,
+			st.revenue, st.refunds, st.refunded, st.wasteCost,
+			st.ingredientCost, st.Profit())
+	}
+
+	// per class output makes sense only in case of several classes
+	if len(st.byPriority) < 2 {
+		return output
+	}
+
+	priorities := []int{}
+	for priority := range st.byPriority {
+		priorities = append(priorities, priority)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(priorities)))
+
+	for _, priority := range priorities {
+		class := st.byPriority[priority]
+		output = fmt.Sprintf("%s\n\tPriority %d: delivered %d, avg value %f, "+
+			"wasted %d, spoiled %d", output, priority,
+			len(class.deliveredValues), average(class.deliveredValues),
+			len(class.wastedValues), class.spoiled)
+	}
+
+	return output
 }
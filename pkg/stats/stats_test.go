@@ -0,0 +1,133 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEconomics(t *testing.T) {
+	tests := []struct {
+		economics Economics
+		delivered []Order
+		wasted    []Order
+		spoiled   []Order
+		revenue   float64
+		refunds   float64
+		wasteCost float64
+		profit    float64
+	}{
+		{
+			economics: Economics{},
+			delivered: []Order{
+				{Value: 0.5, Price: 10, Cost: 4},
+			},
+			wasted: []Order{
+				{Value: 0.9, Price: 10, Cost: 3},
+			},
+			spoiled: []Order{
+				{Value: 0, Price: 10, Cost: 2},
+			},
+			revenue:   10,
+			refunds:   0,
+			wasteCost: 5,
+			profit:    1,
+		},
+		{
+			economics: Economics{
+				ScaleRevenueByValue: true,
+				RefundThreshold:     0.3,
+			},
+			delivered: []Order{
+				{Value: 0.5, Price: 10, Cost: 4},
+				{Value: 0.2, Price: 10, Cost: 4},
+				{Value: 1.2, Price: 10, Cost: 4},
+			},
+			revenue:   17,
+			refunds:   2,
+			wasteCost: 0,
+			profit:    3,
+		},
+	}
+
+	for i, test := range tests {
+		test := test
+		t.Run(fmt.Sprintf("economics_%d", i),
+			func(t *testing.T) {
+				t.Parallel()
+				st := NewStats(len(test.delivered) + len(test.wasted) +
+					len(test.spoiled))
+				st.Economics = test.economics
+
+				for _, ord := range test.delivered {
+					st.Delivered(ord)
+				}
+				for _, ord := range test.wasted {
+					st.Wasted(ord)
+				}
+				for _, ord := range test.spoiled {
+					st.Spoiled(ord)
+				}
+
+				assert.InDelta(t, test.revenue, st.Revenue(), 1e-9, "should be equal")
+				assert.InDelta(t, test.refunds, st.Refunds(), 1e-9, "should be equal")
+				assert.InDelta(t, test.wasteCost, st.WasteCost(), 1e-9, "should be equal")
+				assert.InDelta(t, test.profit, st.Profit(), 1e-9, "should be equal")
+			})
+	}
+}
+
+func TestCancelled(t *testing.T) {
+	st := NewStats(0)
+	st.Delivered(Order{Value: 0.5, Price: 10, Cost: 4})
+	st.Cancelled(Order{Value: 0.9, Price: 10, Cost: 3})
+
+	summary := st.Summary()
+	assert.Equal(t, 2, summary.Expected, "cancelled orders are processed")
+	assert.Equal(t, 1, summary.Cancelled, "should be equal")
+	assert.Equal(t, float64(3), st.WasteCost(), "should be equal")
+	assert.Equal(t, float64(3), st.Profit(), "should be equal")
+	assert.Contains(t, st.String(), "Cancelled 1/2", "should contain")
+}
+
+func TestMerge(t *testing.T) {
+	a := NewStats(2)
+	a.Delivered(Order{Priority: 1, Value: 0.5, Price: 10, Cost: 4})
+	a.Wasted(Order{Value: 0.2, Cost: 3})
+
+	b := NewStats(3)
+	b.Delivered(Order{Value: 0.9, Price: 10, Cost: 4})
+	b.Spoiled(Order{Priority: 1, Cost: 2})
+	b.Cancelled(Order{Cost: 1})
+
+	merged := Merge(a, b)
+	assert.Equal(t, Summary{
+		Expected:     5,
+		Delivered:    2,
+		Wasted:       1,
+		Spoiled:      1,
+		Cancelled:    1,
+		AvgDelivered: 0.7,
+		AvgWasted:    0.2,
+		Profit:       6,
+	}, roundSummary(merged.Summary()), "should be equal")
+	assert.InDelta(t, 6, merged.WasteCost(), 1e-9, "should be equal")
+	assert.Equal(t, 1, merged.byPriority[1].spoiled, "should be equal")
+	assert.Equal(t, 1, len(merged.byPriority[1].deliveredValues),
+		"should be equal")
+
+	// number of orders is unknown in case one of them is streamed
+	merged = Merge(a, NewStats(0))
+	assert.Equal(t, 2, merged.Summary().Expected, "processed orders are counted")
+}
+
+// roundSummary rounds the averages of the summary, so it can be
+// compared as a whole
+func roundSummary(s Summary) Summary {
+	s.AvgDelivered = math.Round(s.AvgDelivered*1e6) / 1e6
+	s.AvgWasted = math.Round(s.AvgWasted*1e6) / 1e6
+	s.Profit = math.Round(s.Profit*1e6) / 1e6
+	return s
+}
@@ -0,0 +1,127 @@
+package stats
+
+import (
+	"math"
+)
+
+// Mean return average of the values, 0 for the empty list
+func Mean(values []float64) float64 {
+	return average(values)
+}
+
+// variance return sample variance of the values
+func variance(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	mean := average(values)
+	var sum float64
+	for _, v := range values {
+		sum += (v - mean) * (v - mean)
+	}
+	return sum / float64(len(values)-1)
+}
+
+// WelchTTest return t statistic and two-sided p-value of the Welch's
+// t-test checking whether means of two samples differ. Samples
+// need at least 2 values each, ok is false otherwise. Samples
+// without variance have p-value 1 in case their means are equal
+// and 0 otherwise
+func WelchTTest(a, b []float64) (t, p float64, ok bool) {
+	if len(a) < 2 || len(b) < 2 {
+		return 0, 0, false
+	}
+
+	va := variance(a) / float64(len(a))
+	vb := variance(b) / float64(len(b))
+	diff := average(a) - average(b)
+	if va+vb == 0 {
+		if diff == 0 {
+			return 0, 1, true
+		}
+		return math.Inf(int(math.Copysign(1, diff))), 0, true
+	}
+
+	t = diff / math.Sqrt(va+vb)
+	// Welch–Satterthwaite degrees of freedom
+	df := (va + vb) * (va + vb) /
+		(va*va/float64(len(a)-1) + vb*vb/float64(len(b)-1))
+
+	p = incompleteBeta(df/2, 0.5, df/(df+t*t))
+	return t, p, true
+}
+
+// incompleteBeta return regularized incomplete beta function
+// I_x(a, b)
+func incompleteBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+
+	la, _ := math.Lgamma(a)
+	lb, _ := math.Lgamma(b)
+	lab, _ := math.Lgamma(a + b)
+	front := math.Exp(lab - la - lb + a*math.Log(x) + b*math.Log(1-x))
+
+	// continued fraction converges quickly for x < (a+1)/(a+b+2)
+	if x < (a+1)/(a+b+2) {
+		return front * betaFraction(a, b, x) / a
+	}
+	return 1 - front*betaFraction(b, a, 1-x)/b
+}
+
+// betaFraction evaluates continued fraction of the incomplete beta
+// function by the modified Lentz's method
+func betaFraction(a, b, x float64) float64 {
+	const (
+		maxIterations = 200
+		epsilon       = 1e-14
+		tiny          = 1e-300
+	)
+
+	c := 1.0
+	d := 1 - (a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+
+	for m := 1; m <= maxIterations; m++ {
+		fm := float64(m)
+		// even step
+		num := fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+
+		// odd step
+		num = -(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+
+		if math.Abs(delta-1) < epsilon {
+			break
+		}
+	}
+	return h
+}
@@ -0,0 +1,55 @@
+package stats
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWelchTTest(t *testing.T) {
+
+	tests := []struct {
+		a  []float64
+		b  []float64
+		t  float64
+		p  float64
+		ok bool
+	}{
+		{
+			a:  []float64{1, 2, 3, 4, 5},
+			b:  []float64{2, 4, 6, 8, 10},
+			t:  -1.8974,
+			p:  0.1075,
+			ok: true,
+		},
+		{
+			a:  []float64{0.81, 0.85, 0.79, 0.88},
+			b:  []float64{0.90, 0.93, 0.91, 0.95, 0.92},
+			t:  -4.0840,
+			p:  0.0144,
+			ok: true,
+		},
+		// no variance
+		{
+			a:  []float64{1, 1},
+			b:  []float64{1, 1, 1},
+			t:  0,
+			p:  1,
+			ok: true,
+		},
+		// not enough values
+		{
+			a:  []float64{1},
+			b:  []float64{1, 2},
+			ok: false,
+		},
+	}
+
+	for _, test := range tests {
+		tt, p, ok := WelchTTest(test.a, test.b)
+		assert.Equal(t, test.ok, ok, "should be equal")
+		assert.Equal(t, test.t, math.Round(tt*10000)/10000, "should be equal")
+		assert.Equal(t, test.p, math.Round(p*10000)/10000, "should be equal")
+	}
+}
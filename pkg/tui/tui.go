@@ -0,0 +1,341 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bgzzz/kitchen/pkg/rack"
+	"github.com/bgzzz/kitchen/pkg/stats"
+	"github.com/pkg/errors"
+	"golang.org/x/term"
+)
+
+const (
+	// refreshInterval is the period of redrawing the screen
+	refreshInterval = 200 * time.Millisecond
+	// feedSize is the number of the latest rack events shown
+	feedSize = 10
+	// ordersPerShelf is the number of orders shown per shelf, the
+	// ones with the lowest value are shown first
+	ordersPerShelf = 5
+	// maxBarWidth is the max width of the shelf occupancy bar
+	maxBarWidth = 40
+)
+
+// ANSI escape sequences used to draw the screen
+const (
+	enterScreen = "\x1b[?1049h\x1b[?25l"
+	leaveScreen = "\x1b[?25h\x1b[?1049l"
+	home        = "\x1b[H"
+	clearLine   = "\x1b[K"
+	clearBelow  = "\x1b[J"
+
+	bold   = "\x1b[1m"
+	red    = "\x1b[31m"
+	green  = "\x1b[32m"
+	yellow = "\x1b[33m"
+	reset  = "\x1b[0m"
+)
+
+// keys of the UI, ctrl+c is not turned into the signal in the
+// raw mode
+const (
+	keyQuit  = 'q'
+	keyCtrlC = 3
+	keyPause = 'p'
+	keySpace = ' '
+	keyStep  = 's'
+)
+
+// UI is the live terminal view of the rack: shelves occupancy,
+// orders with their current value, the latest rack events and stats
+type UI struct {
+	in       *os.File
+	out      *os.File
+	snapshot func() *rack.Snapshot
+
+	lock sync.Mutex
+	feed []rack.Event
+	// paused holds the producer, resume is closed when UI is
+	// resumed. Screen is redrawn while paused, as orders on the rack
+	// keep decaying
+	paused bool
+	resume chan struct{}
+	// step releases one order of the paused producer
+	step chan struct{}
+
+	quit     chan struct{}
+	quitOnce sync.Once
+	stop     chan struct{}
+	stopped  chan struct{}
+	restore  func()
+}
+
+// New creates the UI reading keys from in and drawing rack
+// snapshots to out
+func New(in, out *os.File, snapshot func() *rack.Snapshot) *UI {
+	return &UI{
+		in:       in,
+		out:      out,
+		snapshot: snapshot,
+		step:     make(chan struct{}, 1),
+		quit:     make(chan struct{}),
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+}
+
+// Feed adds the rack event to the event feed, it is supposed to be
+// subscribed to the rack events
+func (ui *UI) Feed(event rack.Event) {
+	ui.lock.Lock()
+	defer ui.lock.Unlock()
+	ui.feed = append(ui.feed, event)
+	if len(ui.feed) > feedSize {
+		ui.feed = ui.feed[len(ui.feed)-feedSize:]
+	}
+}
+
+// Start switches the terminal to the raw mode and starts drawing
+// return error in case input or output is not the terminal
+func (ui *UI) Start() error {
+	if !term.IsTerminal(int(ui.in.Fd())) || !term.IsTerminal(int(ui.out.Fd())) {
+		return errors.New("terminal UI needs a terminal")
+	}
+
+	state, err := term.MakeRaw(int(ui.in.Fd()))
+	if err != nil {
+		return errors.Wrap(err, "unable to set terminal raw mode")
+	}
+	ui.restore = func() {
+		term.Restore(int(ui.in.Fd()), state)
+	}
+
+	io.WriteString(ui.out, enterScreen)
+	go ui.readKeys()
+	go ui.draw()
+	return nil
+}
+
+// Quit returns the channel closed when user asks to quit
+func (ui *UI) Quit() <-chan struct{} {
+	return ui.quit
+}
+
+// Close stops drawing and restores the terminal
+func (ui *UI) Close() {
+	if ui.restore == nil {
+		return
+	}
+	close(ui.stop)
+	<-ui.stopped
+	io.WriteString(ui.out, leaveScreen)
+	ui.restore()
+}
+
+// Wait blocks while UI is paused until the step key is pressed, it
+// is supposed to be called by the producer before every order
+func (ui *UI) Wait() {
+	for {
+		ui.lock.Lock()
+		paused, resume := ui.paused, ui.resume
+		ui.lock.Unlock()
+		if !paused {
+			return
+		}
+
+		select {
+		case <-ui.step:
+			return
+		case <-resume:
+		case <-ui.quit:
+			return
+		case <-ui.stop:
+			return
+		}
+	}
+}
+
+// readKeys waits for the keys of the UI
+func (ui *UI) readKeys() {
+	b := make([]byte, 1)
+	for {
+		if _, err := ui.in.Read(b); err != nil {
+			return
+		}
+		ui.key(b[0])
+	}
+}
+
+// key handles the pressed key
+func (ui *UI) key(key byte) {
+	switch key {
+	case keyQuit, keyCtrlC:
+		ui.quitOnce.Do(func() {
+			close(ui.quit)
+		})
+	case keyPause, keySpace:
+		ui.lock.Lock()
+		defer ui.lock.Unlock()
+		ui.paused = !ui.paused
+		if ui.paused {
+			ui.resume = make(chan struct{})
+			return
+		}
+		close(ui.resume)
+		// step that is not taken is not carried to the next pause
+		select {
+		case <-ui.step:
+		default:
+		}
+	case keyStep:
+		ui.lock.Lock()
+		defer ui.lock.Unlock()
+		if !ui.paused {
+			return
+		}
+		select {
+		case ui.step <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// draw redraws the screen every refresh interval until UI is closed
+func (ui *UI) draw() {
+	defer close(ui.stopped)
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		width, height, err := term.GetSize(int(ui.out.Fd()))
+		// size of some pseudo terminals is not set
+		if err != nil || width <= 0 || height <= 0 {
+			width, height = 80, 24
+		}
+
+		ui.lock.Lock()
+		feed := append([]rack.Event{}, ui.feed...)
+		paused := ui.paused
+		ui.lock.Unlock()
+
+		lines := render(ui.snapshot(), feed, paused, width)
+		if len(lines) > height {
+			lines = lines[:height]
+		}
+		// raw mode output needs explicit carriage return
+		io.WriteString(ui.out, home+strings.Join(lines, clearLine+"\r\n")+
+			clearLine+clearBelow)
+
+		select {
+		case <-ui.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// render returns the screen lines of the rack snapshot and the
+// latest rack events
+func render(snap *rack.Snapshot, feed []rack.Event, paused bool,
+	width int) []string {
+	keys := "p: pause  q: quit"
+	if paused {
+		keys = yellow + "producer paused" + reset + "  p: resume  s: step  q: quit"
+	}
+	lines := []string{
+		fmt.Sprintf("%skitchen%s %s    %s", bold, reset,
+			snap.Time.Format("15:04:05"), keys),
+		"",
+		bold + "Shelves" + reset,
+	}
+
+	barWidth := width - 40
+	if barWidth > maxBarWidth {
+		barWidth = maxBarWidth
+	}
+	if barWidth < 10 {
+		barWidth = 10
+	}
+
+	for _, shelf := range snap.Shelves {
+		lines = append(lines, fmt.Sprintf(" %-16s %s %d/%d",
+			truncate(shelf.Temp, 16), bar(len(shelf.Orders), shelf.Capacity, barWidth),
+			len(shelf.Orders), shelf.Capacity))
+		for i, ord := range shelf.Orders {
+			if i == ordersPerShelf {
+				lines = append(lines, fmt.Sprintf("     ... %d more",
+					len(shelf.Orders)-ordersPerShelf))
+				break
+			}
+			lines = append(lines, truncate(fmt.Sprintf("     %s%.2f%s %s %s",
+				valueColor(ord.Value), ord.Value, reset, ord.Name, ord.ID),
+				width+len(valueColor(ord.Value))+len(reset)))
+		}
+	}
+
+	lines = append(lines, "", bold+"Stats"+reset, " "+Summary(snap.Stats),
+		"", bold+"Events"+reset)
+	for i := len(feed) - 1; i >= 0; i-- {
+		event := feed[i]
+		lines = append(lines, truncate(fmt.Sprintf(" %s %-12s %s%.2f%s %s",
+			event.Time.Format("15:04:05"), event.Type,
+			valueColor(event.Value), event.Value, reset, event.OrderID),
+			width+len(valueColor(event.Value))+len(reset)))
+	}
+
+	return lines
+}
+
+// Summary returns one line of the stats shown by the UI
+func Summary(st stats.Summary) string {
+	return fmt.Sprintf("delivered %d/%d (avg %.2f)  wasted %d (avg %.2f)  "+
+		"spoiled %d  profit $%.2f", st.Delivered, st.Expected,
+		st.AvgDelivered, st.Wasted, st.AvgWasted, st.Spoiled, st.Profit)
+}
+
+// bar returns occupancy bar of the shelf
+func bar(occupied, capacity, width int) string {
+	filled := 0
+	if capacity > 0 {
+		filled = occupied * width / capacity
+	}
+	if filled > width {
+		filled = width
+	}
+
+	color := green
+	if filled*3 >= width*2 {
+		color = yellow
+	}
+	if occupied >= capacity {
+		color = red
+	}
+
+	return "[" + color + strings.Repeat("#", filled) + reset +
+		strings.Repeat(".", width-filled) + "]"
+}
+
+// valueColor returns color of the order value: green for fresh
+// orders, yellow for the half decayed, red for the ones about to spoil
+func valueColor(value float64) string {
+	switch {
+	case value >= 0.66:
+		return green
+	case value >= 0.33:
+		return yellow
+	}
+	return red
+}
+
+// truncate cuts the string to the supplied number of bytes
+func truncate(s string, n int) string {
+	if n < 0 || len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
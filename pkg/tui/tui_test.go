@@ -0,0 +1,138 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bgzzz/kitchen/pkg/rack"
+	"github.com/bgzzz/kitchen/pkg/stats"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBar(t *testing.T) {
+
+	tests := []struct {
+		occupied int
+		capacity int
+		bar      string
+	}{
+		{occupied: 0, capacity: 10, bar: "[" + green + reset + ".........." + "]"},
+		{occupied: 5, capacity: 10, bar: "[" + green + "#####" + reset + "....." + "]"},
+		{occupied: 7, capacity: 10, bar: "[" + yellow + "#######" + reset + "..." + "]"},
+		{occupied: 10, capacity: 10, bar: "[" + red + "##########" + reset + "]"},
+		// shelf shrunk below its occupancy by reload
+		{occupied: 3, capacity: 0, bar: "[" + red + reset + ".........." + "]"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.bar, bar(test.occupied, test.capacity, 10),
+			"should be equal")
+	}
+}
+
+func TestRender(t *testing.T) {
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	snap := &rack.Snapshot{
+		Time: now,
+		Shelves: []rack.ShelfSnapshot{
+			{
+				Name:     "hot shelf",
+				Temp:     "hot",
+				Capacity: 10,
+				Orders: []rack.OrderSnapshot{
+					{ID: "1", Name: "Pizza", Value: 0.2},
+					{ID: "2", Name: "Soup", Value: 0.9},
+				},
+			},
+			{
+				Name:     "overflow shelf",
+				Temp:     "any",
+				Capacity: 15,
+				Orders: []rack.OrderSnapshot{
+					{ID: "3"}, {ID: "4"}, {ID: "5"}, {ID: "6"},
+					{ID: "7"}, {ID: "8"}, {ID: "9"},
+				},
+			},
+		},
+		Stats: stats.Summary{
+			Expected:  10,
+			Delivered: 2,
+		},
+	}
+	feed := []rack.Event{
+		{Type: rack.EventPlaced, Time: now, OrderID: "1", Value: 1},
+		{Type: rack.EventWasted, Time: now, OrderID: "2", Value: 0.5},
+	}
+
+	lines := render(snap, feed, false, 80)
+	screen := strings.Join(lines, "\n")
+
+	assert.Equal(t, true, strings.Contains(lines[3], " hot "),
+		"shelf line should follow the header")
+	assert.Equal(t, true, strings.HasSuffix(lines[3], " 2/10"),
+		"should be equal")
+	assert.Equal(t, "     "+red+"0.20"+reset+" Pizza 1", lines[4],
+		"should be equal")
+	assert.Equal(t, "     "+green+"0.90"+reset+" Soup 2", lines[5],
+		"should be equal")
+	assert.Equal(t, true, strings.Contains(screen, "     ... 2 more"),
+		"orders above the limit should be counted")
+	assert.Equal(t, true, strings.Contains(screen, "delivered 2/10"),
+		"should be equal")
+	assert.Equal(t, " 12:00:00 WASTED       "+yellow+"0.50"+reset+" 2",
+		lines[len(lines)-2], "the latest event should be shown first")
+
+	lines = render(snap, feed, true, 80)
+	assert.Equal(t, true, strings.Contains(lines[0], "producer paused"),
+		"paused header should be shown")
+}
+
+// waited returns channel closed once Wait returns
+func waited(ui *UI) chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		ui.Wait()
+		close(done)
+	}()
+	return done
+}
+
+func TestPause(t *testing.T) {
+	ui := New(nil, nil, nil)
+	<-waited(ui)
+
+	ui.key(keyPause)
+	done := waited(ui)
+	select {
+	case <-done:
+		t.Fatal("paused producer should wait")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// every step releases one order
+	ui.key(keyStep)
+	<-done
+	done = waited(ui)
+	select {
+	case <-done:
+		t.Fatal("paused producer should wait for the next step")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	ui.key(keySpace)
+	<-done
+	<-waited(ui)
+
+	// step is ignored while UI runs
+	ui.key(keyStep)
+	ui.key(keyPause)
+	done = waited(ui)
+	select {
+	case <-done:
+		t.Fatal("step should not be carried to the pause")
+	case <-time.After(20 * time.Millisecond):
+	}
+	ui.key(keyQuit)
+	<-done
+}
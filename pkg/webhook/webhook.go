@@ -0,0 +1,127 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bgzzz/kitchen/pkg/sink"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// SignatureHeader is the header with HMAC-SHA256 signature of the
+	// payload: "sha256=" followed by hex encoded signature
+	SignatureHeader = "X-Kitchen-Signature"
+	// EventHeader is the header with the event name of the payload
+	EventHeader = "X-Kitchen-Event"
+
+	// defaultQueueSize is the number of events waiting to be posted,
+	// events are dropped when the queue is full
+	defaultQueueSize = 1024
+)
+
+// Events returns sorted names of the supported events, the ones of
+// the sinks except placed
+func Events() []string {
+	events := []string{}
+	for _, event := range sink.Events() {
+		if event != sink.EventPlaced {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// Options of the webhook
+type Options struct {
+	URL string
+	// Events are the names of the posted events, all supported
+	// events are posted in case it is empty
+	Events []string
+	// Secret signs the payloads, payloads are not signed in case
+	// it is empty
+	Secret  string
+	Timeout time.Duration
+	// Retries is the number of retries of the failed post
+	Retries int
+	// Backoff is the delay before the first retry, it is doubled
+	// for every next one
+	Backoff time.Duration
+	// QueueSize is the number of events waiting to be posted
+	QueueSize int
+	// DrainTimeout is the time queued events are posted for when
+	// webhook is closed, the rest are not posted
+	DrainTimeout time.Duration
+}
+
+// Webhook is the sink posting every record to the URL as the JSON
+// object, failed posts are retried by the poster
+type Webhook struct {
+	poster *sink.Poster
+	secret string
+}
+
+// New creates webhook and starts posting events. Events are queued
+// and posted one by one in order by the publisher goroutine, so rack
+// event loop is not blocked by the slow endpoint. Events are dropped
+// in case the queue is full
+func New(log *logrus.Entry, opts Options) *sink.Publisher {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = defaultQueueSize
+	}
+	if len(opts.Events) == 0 {
+		opts.Events = Events()
+	}
+
+	w := &Webhook{
+		poster: sink.NewPoster(opts.URL, opts.Timeout, opts.Retries, opts.Backoff),
+		secret: opts.Secret,
+	}
+	return sink.NewPublisher(log, "webhook "+opts.URL, w, sink.Options{
+		Events:       opts.Events,
+		BufferSize:   opts.QueueSize,
+		BatchSize:    1,
+		Policy:       sink.PolicyDrop,
+		DrainTimeout: opts.DrainTimeout,
+	})
+}
+
+// Write posts the records one by one
+func (w *Webhook) Write(ctx context.Context, records []sink.Record) error {
+	for _, rec := range records {
+		body, err := json.Marshal(rec)
+		if err != nil {
+			return errors.Wrap(err, "unable to marshal payload")
+		}
+
+		header := http.Header{}
+		header.Set(EventHeader, rec.Event)
+		if w.secret != "" {
+			header.Set(SignatureHeader, Sign([]byte(w.secret), body))
+		}
+		if err := w.poster.Post(ctx, body, header); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("order %s %s", rec.OrderID, rec.Event))
+		}
+	}
+	return nil
+}
+
+// Close releases idle connections
+func (w *Webhook) Close() error {
+	return w.poster.Close()
+}
+
+// Sign returns the signature header value of the payload: "sha256="
+// followed by hex encoded HMAC-SHA256 of the body
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,165 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bgzzz/kitchen/pkg/rack"
+	"github.com/bgzzz/kitchen/pkg/sink"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+var testLog = logrus.NewEntry(logrus.New())
+
+func TestNotify(t *testing.T) {
+	lock := sync.Mutex{}
+	payloads := []sink.Record{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		payload := sink.Record{}
+		json.Unmarshal(body, &payload)
+
+		lock.Lock()
+		defer lock.Unlock()
+		payloads = append(payloads, payload)
+		assert.Equal(t, Sign([]byte("secret"), body), r.Header.Get(SignatureHeader),
+			"should be equal")
+		assert.Equal(t, payload.Event, r.Header.Get(EventHeader), "should be equal")
+	}))
+	defer server.Close()
+
+	w := New(testLog, Options{
+		URL:    server.URL,
+		Events: []string{sink.EventDelivered, sink.EventMoved},
+		Secret: "secret",
+	})
+
+	now := time.Now()
+	w.Notify(rack.Event{Type: rack.EventPlaced, OrderID: "1", ToShelf: "hot"})
+	w.Notify(rack.Event{Type: rack.EventMoved, OrderID: "1", FromShelf: "any",
+		ToShelf: "hot", Value: 0.9, Time: now})
+	w.Notify(rack.Event{Type: rack.EventWasted, OrderID: "2"})
+	w.Notify(rack.Event{Type: rack.EventDelivered, OrderID: "1", FromShelf: "hot",
+		Value: 0.8, Time: now})
+	assert.Nil(t, w.Close(), "should be nil")
+
+	assert.Equal(t, 2, len(payloads), "should be equal")
+	assert.Equal(t, sink.Record{Event: sink.EventMoved, OrderID: "1", FromShelf: "any",
+		ToShelf: "hot", Value: 0.9, Time: payloads[0].Time}, payloads[0],
+		"should be equal")
+	assert.True(t, now.Equal(payloads[0].Time), "should be true")
+	assert.Equal(t, sink.EventDelivered, payloads[1].Event, "should be equal")
+	assert.Equal(t, "hot", payloads[1].FromShelf, "should be equal")
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		statuses []int
+		attempts int
+		failed   bool
+	}{
+		{[]int{200}, 1, false},
+		{[]int{500, 503, 200}, 3, false},
+		{[]int{429, 200}, 2, false},
+		{[]int{500, 500, 500, 500}, 3, true},
+		{[]int{400, 200}, 1, true},
+	}
+
+	for _, test := range tests {
+		lock := sync.Mutex{}
+		attempts := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lock.Lock()
+			defer lock.Unlock()
+			w.WriteHeader(test.statuses[attempts])
+			attempts++
+		}))
+
+		w := New(testLog, Options{
+			URL:     server.URL,
+			Retries: 2,
+			Backoff: time.Millisecond,
+		})
+		w.Notify(rack.Event{Type: rack.EventSpoiled, OrderID: "1"})
+		err := w.Close()
+		server.Close()
+
+		assert.Equal(t, test.attempts, attempts, "should be equal")
+		assert.Equal(t, test.failed, err != nil, "should be equal")
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+
+	w := New(testLog, Options{
+		URL:       server.URL,
+		QueueSize: 1,
+	})
+
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		w.Notify(rack.Event{Type: rack.EventWasted, OrderID: "1"})
+	}
+	assert.True(t, time.Since(start) < time.Second, "notify should not block")
+
+	close(release)
+	assert.NotNil(t, w.Close(), "events should be dropped")
+}
+
+func TestDrainTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	w := New(testLog, Options{
+		URL:          server.URL,
+		Retries:      5,
+		Backoff:      time.Second,
+		DrainTimeout: 50 * time.Millisecond,
+	})
+	for i := 0; i < 3; i++ {
+		w.Notify(rack.Event{Type: rack.EventWasted, OrderID: "1"})
+	}
+
+	start := time.Now()
+	assert.NotNil(t, w.Close(), "events should not be posted")
+	assert.True(t, time.Since(start) < time.Second, "close should not wait for retries")
+}
+
+func TestNotifyClosed(t *testing.T) {
+	posted := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posted++
+	}))
+	defer server.Close()
+
+	w := New(testLog, Options{URL: server.URL})
+	assert.Nil(t, w.Close(), "should be nil")
+
+	// rack can still emit events after the webhook is closed
+	assert.NotPanics(t, func() {
+		w.Notify(rack.Event{Type: rack.EventWasted, OrderID: "1"})
+	}, "notify should not panic")
+	assert.Nil(t, w.Close(), "should be nil")
+	assert.Equal(t, 0, posted, "should be equal")
+}
+
+func TestSign(t *testing.T) {
+	// RFC 4231 test case 2
+	assert.Equal(t,
+		"sha256=5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843",
+		Sign([]byte("Jefe"), []byte("what do ya want for nothing?")),
+		"should be equal")
+}
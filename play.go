@@ -0,0 +1,54 @@
+package main
+
+import (
+	"time"
+
+	"github.com/bgzzz/kitchen/pkg/config"
+	"github.com/bgzzz/kitchen/pkg/rack"
+	"github.com/bgzzz/kitchen/pkg/scenario"
+	shvs "github.com/bgzzz/kitchen/pkg/shelves"
+)
+
+// play applies setting and shelf capacity events of the scenario to
+// the running simulation at their time. Courier delays are applied
+// by the producer to the orders it creates
+func (sim *simulation) play(sr *rack.ShelfRack) {
+	for _, e := range sim.scenario.Timeline() {
+		time.Sleep(time.Until(sim.start.Add(e.At())))
+		sim.apply(e, sr)
+	}
+}
+
+// apply changes live config or shelves according to the event.
+// Events are checked upfront, so failure is reported and skipped
+func (sim *simulation) apply(e *scenario.Event, sr *rack.ShelfRack) {
+	cfg, shelves := sim.live.get()
+
+	if e.Setting != "" {
+		changed := *cfg
+		if err := config.Set(&changed, e.Setting, e.Value); err != nil {
+			sim.log.Errorf("scenario event is skipped: %s", err.Error())
+			return
+		}
+		sim.log.Infof("scenario: %s is set to %s", e.Setting, e.Value)
+		sim.live.set(&changed, shelves)
+		return
+	}
+
+	resized := []*shvs.Shelf{}
+	for _, shelf := range shelves {
+		if shelf.Temp == e.Shelf {
+			s := *shelf
+			s.Capacity = *e.Capacity
+			shelf = &s
+		}
+		resized = append(resized, shelf)
+	}
+	sim.log.Infof("scenario: capacity of %s shelf is set to %d",
+		e.Shelf, *e.Capacity)
+	sim.live.set(cfg, resized)
+	sr.Reconfigure(&rack.Reconfig{
+		Shelves:        resized,
+		WasteDisplaced: cfg.RackConfig.RemovedShelfPolicy == config.RemovedShelfWaste,
+	})
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/bgzzz/kitchen/pkg/config"
+	"github.com/bgzzz/kitchen/pkg/rack"
+	shvs "github.com/bgzzz/kitchen/pkg/shelves"
+)
+
+// watchInterval is the period of checking simulation files
+// for modifications
+const watchInterval = time.Second
+
+// liveConfig is the simulation config and shelves that can be
+// replaced while simulation runs
+type liveConfig struct {
+	lock    sync.RWMutex
+	cfg     *config.SimulationConfig
+	shelves []*shvs.Shelf
+}
+
+func newLiveConfig(cfg *config.SimulationConfig, shelves []*shvs.Shelf) *liveConfig {
+	return &liveConfig{
+		cfg:     cfg,
+		shelves: shelves,
+	}
+}
+
+// get returns current config and shelves
+func (lc *liveConfig) get() (*config.SimulationConfig, []*shvs.Shelf) {
+	lc.lock.RLock()
+	defer lc.lock.RUnlock()
+	return lc.cfg, lc.shelves
+}
+
+// set replaces config and shelves
+func (lc *liveConfig) set(cfg *config.SimulationConfig, shelves []*shvs.Shelf) {
+	lc.lock.Lock()
+	defer lc.lock.Unlock()
+	lc.cfg = cfg
+	lc.shelves = shelves
+}
+
+// watchConfig starts reloading simulation config and shelves on
+// SIGHUP and, in case watch is enabled, when their files are
+// modified. It returns the function stopping the watch, config is
+// not reloaded after it returns
+func (sim *simulation) watchConfig(sr *rack.ShelfRack) (stop func()) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+
+		var tick <-chan time.Time
+		if sim.watch {
+			ticker := time.NewTicker(watchInterval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+
+		modified := modTimes(sim.cfgPath, sim.live)
+		for {
+			select {
+			case <-done:
+				return
+			case <-hup:
+				sim.log.Info("SIGHUP received, reloading simulation config")
+			case <-tick:
+				current := modTimes(sim.cfgPath, sim.live)
+				if equalModTimes(modified, current) {
+					continue
+				}
+				sim.log.Info("simulation files changed, reloading simulation config")
+			}
+
+			sim.reload(sr)
+			modified = modTimes(sim.cfgPath, sim.live)
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(hup)
+			close(done)
+		})
+		<-stopped
+	}
+}
+
+// reload reads simulation config and shelves and applies them to
+// the running simulation. Invalid setup is reported and not applied
+func (sim *simulation) reload(sr *rack.ShelfRack) {
+	cfg, err := sim.load()
+	if err != nil {
+		sim.log.Errorf("config is not reloaded: %s", err.Error())
+		return
+	}
+
+	shelves, err := config.LoadShelves(cfg)
+	if err != nil {
+		sim.log.Errorf("config is not reloaded: %s", err.Error())
+		return
+	}
+
+	if problems := config.Validate(sim.cfgPath, cfg, shelves, nil); len(problems) != 0 {
+		sim.log.Errorf("config is not reloaded:\n%s", problems.Error())
+		return
+	}
+
+	sim.live.set(cfg, shelves)
+	sr.Reconfigure(&rack.Reconfig{
+		Shelves:        shelves,
+		WasteDisplaced: cfg.RackConfig.RemovedShelfPolicy == config.RemovedShelfWaste,
+	})
+}
+
+// modTimes returns modification times of the simulation config and
+// the local shelves file
+func modTimes(cfgPath string, live *liveConfig) map[string]time.Time {
+	paths := []string{cfgPath}
+	if cfg, _ := live.get(); len(cfg.Shelves) == 0 {
+		paths = append(paths, cfg.ShelvesFilePath)
+	}
+
+	times := map[string]time.Time{}
+	for _, path := range paths {
+		// links are not watched
+		if info, err := os.Stat(path); err == nil {
+			times[path] = info.ModTime()
+		}
+	}
+	return times
+}
+
+func equalModTimes(a, b map[string]time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, t := range a {
+		if !t.Equal(b[path]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/bgzzz/kitchen/pkg/broker"
+	"github.com/bgzzz/kitchen/pkg/config"
+	"github.com/bgzzz/kitchen/pkg/rack"
+	"github.com/bgzzz/kitchen/pkg/service"
+	"github.com/bgzzz/kitchen/pkg/source"
+)
+
+// serviceQueueSize is the number of submitted orders that are not
+// put on the rack yet, clients are blocked when it is exceeded
+const serviceQueueSize = 1024
+
+// serve simulates the kitchen processing orders submitted by the
+// clients of the kitchen service listening on the address
+func (sim *simulation) serve(address string) error {
+	queue := source.NewQueue(serviceQueueSize)
+	sim.service = service.New(sim.log, queue, sim.validateOrder)
+	sim.serviceAddress = address
+
+	go sim.closeOnSignal(queue)
+	return sim.run(queue, rack.UnknownOrders)
+}
+
+// consume simulates the kitchen processing orders consumed from the
+// subject of the message broker
+func (sim *simulation) consume(cfg *config.SimulationConfig) error {
+	conn, err := broker.Dial(cfg.OrdersSource.Broker.URL)
+	if err != nil {
+		return err
+	}
+	sub, err := conn.Subscribe(cfg.OrdersSource.Broker.Subject,
+		cfg.OrdersSource.Broker.Queue, cfg.OrdersSource.Broker.MaxPending)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	src := source.NewBrokerSource(sub, conn, source.StreamOptions{
+		TimestampField: cfg.OrdersSource.TimestampField,
+		Schema:         config.OrdersSchema.Items,
+		Validate:       sim.validateOrder,
+	})
+
+	// connection is closed by the producer once the last order is
+	// acknowledged
+	go sim.closeOnSignal(sub)
+	return sim.run(src, rack.UnknownOrders)
+}
+
+// closeOnSignal stops taking orders (closes the queue or the broker
+// subscription) on SIGINT or SIGTERM, the simulation is over once
+// orders on the rack are processed. The second signal terminates
+// the process
+func (sim *simulation) closeOnSignal(orders io.Closer) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	sig := <-sigs
+	signal.Stop(sigs)
+
+	sim.log.Infof("%s received, orders on the rack are processed, new ones are not taken",
+		sig)
+	orders.Close()
+}